@@ -0,0 +1,246 @@
+package homomorphicEncryption
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Permission Right of an authenticated client to use a group of server routes
+type Permission string
+
+const (
+	// PermissionParams Allows requesting CKKS and BFV params
+	PermissionParams Permission = "params"
+	// PermissionEvalKeys Allows requesting CKKS and BFV eval keys
+	PermissionEvalKeys Permission = "eval_keys"
+	// PermissionDecrypt Allows decrypting computation results
+	PermissionDecrypt Permission = "decrypt"
+)
+
+// ErrUnauthenticated Returned by Authenticator when a request carries no valid credentials
+var ErrUnauthenticated = errors.New("client is not authenticated")
+
+// AuthClient Client allowed to use the server. A client authenticates either with a bearer
+// API token, whose hash is TokenHash, or with a client certificate of CertificateCN
+type AuthClient struct {
+	Name string `json:"name"`
+	// TokenHash Hex SHA-256 hash of the API token of the client, see HashAPIToken
+	TokenHash string `json:"token_sha256,omitempty"`
+	// CertificateCN Common name of the subject of the client certificate
+	CertificateCN string       `json:"certificate_cn,omitempty"`
+	Permissions   []Permission `json:"permissions"`
+}
+
+// Allows Returns true if client has permission
+func (client *AuthClient) Allows(permission Permission) bool {
+	for _, p := range client.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthConfig Clients allowed to use the server, usually loaded from a file with LoadAuthConfig
+type AuthConfig struct {
+	Clients []AuthClient `json:"clients"`
+}
+
+// LoadAuthConfig Loads AuthConfig from a JSON file, e.g.
+//
+//	{"clients": [
+//	  {"name": "analytics", "token_sha256": "9f86d0...", "permissions": ["params", "eval_keys"]},
+//	  {"name": "reports", "certificate_cn": "reports.example.com", "permissions": ["decrypt"]}
+//	]}
+func LoadAuthConfig(path string) (AuthConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return AuthConfig{}, err
+	}
+
+	var config AuthConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return AuthConfig{}, fmt.Errorf("invalid auth config: %w", err)
+	}
+	return config, nil
+}
+
+// Authenticator Identifies the client making a request. Implementations must be safe for concurrent use
+type Authenticator interface {
+	// Authenticate Returns the client making r or an error wrapping ErrUnauthenticated
+	Authenticate(r *http.Request) (*AuthClient, error)
+}
+
+// TokenAuthenticator Authenticates clients by bearer API tokens sent in the
+// "Authorization: Bearer <token>" header. Only hashes of tokens are kept, so tokens
+// must be random, see GenerateAPIToken
+type TokenAuthenticator struct {
+	clients map[[sha256.Size]byte]*AuthClient
+}
+
+// NewTokenAuthenticator Creates a TokenAuthenticator for clients of config with a TokenHash
+func NewTokenAuthenticator(config AuthConfig) (*TokenAuthenticator, error) {
+	auth := &TokenAuthenticator{clients: make(map[[sha256.Size]byte]*AuthClient)}
+	for i := range config.Clients {
+		client := &config.Clients[i]
+		if client.TokenHash == "" {
+			continue
+		}
+
+		hash, err := hex.DecodeString(client.TokenHash)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("token hash of client '%s' must be %d hex bytes", client.Name, sha256.Size)
+		}
+
+		var key [sha256.Size]byte
+		copy(key[:], hash)
+		if _, ok := auth.clients[key]; ok {
+			return nil, fmt.Errorf("client '%s' shares its token with another client", client.Name)
+		}
+		auth.clients[key] = client
+	}
+	return auth, nil
+}
+
+// Authenticate Returns the client whose token r carries
+func (auth *TokenAuthenticator) Authenticate(r *http.Request) (*AuthClient, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, fmt.Errorf("%w: no bearer token", ErrUnauthenticated)
+	}
+
+	client, ok := auth.clients[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown token", ErrUnauthenticated)
+	}
+	return client, nil
+}
+
+// CertificateAuthenticator Authenticates clients by common names of client certificates
+// verified during the TLS handshake, see MutualTLSConfig
+type CertificateAuthenticator struct {
+	clients map[string]*AuthClient
+}
+
+// NewCertificateAuthenticator Creates a CertificateAuthenticator for clients of config with a CertificateCN
+func NewCertificateAuthenticator(config AuthConfig) (*CertificateAuthenticator, error) {
+	auth := &CertificateAuthenticator{clients: make(map[string]*AuthClient)}
+	for i := range config.Clients {
+		client := &config.Clients[i]
+		if client.CertificateCN == "" {
+			continue
+		}
+		if _, ok := auth.clients[client.CertificateCN]; ok {
+			return nil, fmt.Errorf("client '%s' shares its certificate common name with another client", client.Name)
+		}
+		auth.clients[client.CertificateCN] = client
+	}
+	return auth, nil
+}
+
+// Authenticate Returns the client whose verified certificate r was sent with
+func (auth *CertificateAuthenticator) Authenticate(r *http.Request) (*AuthClient, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, fmt.Errorf("%w: no verified client certificate", ErrUnauthenticated)
+	}
+
+	commonName := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	client, ok := auth.clients[commonName]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown client certificate '%s'", ErrUnauthenticated, commonName)
+	}
+	return client, nil
+}
+
+// Authenticators Authenticator trying all Authenticators in order, until one of them succeeds
+type Authenticators []Authenticator
+
+// Authenticate Returns the client the first succeeding Authenticator returns
+func (auths Authenticators) Authenticate(r *http.Request) (*AuthClient, error) {
+	err := ErrUnauthenticated
+	for _, auth := range auths {
+		var client *AuthClient
+		client, err = auth.Authenticate(r)
+		if err == nil {
+			return client, nil
+		}
+	}
+	return nil, err
+}
+
+// NewAuthenticator Creates an Authenticator accepting both API tokens and client certificates
+// of config clients
+func NewAuthenticator(config AuthConfig) (Authenticator, error) {
+	tokens, err := NewTokenAuthenticator(config)
+	if err != nil {
+		return nil, err
+	}
+
+	certificates, err := NewCertificateAuthenticator(config)
+	if err != nil {
+		return nil, err
+	}
+	return Authenticators{tokens, certificates}, nil
+}
+
+// GenerateAPIToken Returns a new random API token. Give the token to the client and store
+// its HashAPIToken in the auth config
+func GenerateAPIToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// HashAPIToken Returns hex SHA-256 hash of token, stored as AuthClient.TokenHash
+func HashAPIToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// MutualTLSConfig Returns tls.Config of a server verifying client certificates against CA
+// certificates in PEM file clientCAFile. Clients without certificates are still accepted,
+// so that they can authenticate with API tokens
+func MutualTLSConfig(clientCAFile string) (*tls.Config, error) {
+	pool, err := LoadCertPool(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// requirePermission returns a middleware rejecting requests of clients auth doesn't
+// authenticate or which lack permission. A nil auth accepts all requests
+func requirePermission(auth Authenticator, permission Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth == nil {
+			return
+		}
+
+		client, err := auth.Authenticate(c.Request)
+		if err != nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if !client.Allows(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("client '%s' has no %s permission", client.Name, permission)})
+			return
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package bfvMath
+
+import (
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"github.com/ldsec/lattigo/v2/bfv"
+)
+
+// BFVCiphertext Encrypted data kept unmarshalled between operations, together with
+// the ID of the key it was encrypted with and the Context evaluating it. All operations
+// of Context are available as methods of BFVCiphertext and BFVCiphertextArray, so chains
+// of operations only unmarshal their input and marshal their result once.
+// Use Context.Unmarshal and Marshal at the boundaries
+type BFVCiphertext struct {
+	Ciphertext *bfv.Ciphertext
+	KeyID      string
+
+	ctx *Context
+}
+
+// BFVCiphertextArray Array of BFVCiphertext with array operations such as Sum
+type BFVCiphertextArray []*BFVCiphertext
+
+// NewBFVCiphertext Wraps ciphertext encrypted with key keyID into a BFVCiphertext evaluated by ctx
+func (ctx *Context) NewBFVCiphertext(ciphertext *bfv.Ciphertext, keyID string) *BFVCiphertext {
+	return &BFVCiphertext{Ciphertext: ciphertext, KeyID: keyID, ctx: ctx}
+}
+
+// Unmarshal Decodes encrypted data, e.g. produced by EncryptBFV or Marshal, into a BFVCiphertext.
+// Returns envelope errors if data doesn't belong to ctx scheme, params or key
+func (ctx *Context) Unmarshal(encryptedData []byte) (*BFVCiphertext, error) {
+	paramsHash, err := ctx.paramsHash()
+	if err != nil {
+		return nil, err
+	}
+	openEnvelope := envelope.Open
+	if ctx.AcceptLegacyCiphertexts {
+		openEnvelope = envelope.OpenLegacy
+	}
+	e, err := openEnvelope(encryptedData, envelope.SchemeBFV, paramsHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.checkKeyID(e.KeyID); err != nil {
+		return nil, err
+	}
+
+	ciphertext := bfv.NewCiphertext(ctx.Params, 1)
+	err = ciphertext.UnmarshalBinary(e.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.NewBFVCiphertext(ciphertext, e.KeyID), nil
+}
+
+// UnmarshalArray Decodes every element of encryptedDataArray with Unmarshal
+func (ctx *Context) UnmarshalArray(encryptedDataArray [][]byte) (BFVCiphertextArray, error) {
+	array := make(BFVCiphertextArray, len(encryptedDataArray))
+	for i, encryptedData := range encryptedDataArray {
+		var err error
+		array[i], err = ctx.Unmarshal(encryptedData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return array, nil
+}
+
+// Marshal Encodes ct into []byte of encrypted data, which can be stored, sent to
+// other parties or decrypted by the server
+func (ct *BFVCiphertext) Marshal() ([]byte, error) {
+	paramsHash, err := ct.ctx.paramsHash()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ct.Ciphertext.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Wrap(envelope.SchemeBFV, paramsHash, ct.KeyID, data)
+}
+
+// Marshal Encodes every element of array with BFVCiphertext.Marshal
+func (array BFVCiphertextArray) Marshal() ([][]byte, error) {
+	encryptedDataArray := make([][]byte, len(array))
+	for i, ct := range array {
+		var err error
+		encryptedDataArray[i], err = ct.Marshal()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return encryptedDataArray, nil
+}
+
+// CopyNew Returns a deep copy of ct
+func (ct *BFVCiphertext) CopyNew() *BFVCiphertext {
+	return ct.derive(ct.Ciphertext.CopyNew())
+}
+
+// derive wraps ciphertext computed from ct into a BFVCiphertext with the same key and Context
+func (ct *BFVCiphertext) derive(ciphertext *bfv.Ciphertext) *BFVCiphertext {
+	return &BFVCiphertext{Ciphertext: ciphertext, KeyID: ct.KeyID, ctx: ct.ctx}
+}
+
+// checkKeys returns envelope.KeyMismatchError if any of others was encrypted with another key than ct
+func (ct *BFVCiphertext) checkKeys(others ...*BFVCiphertext) error {
+	for _, other := range others {
+		if other.KeyID != ct.KeyID {
+			return &envelope.KeyMismatchError{Expected: ct.KeyID, Actual: other.KeyID}
+		}
+	}
+	return nil
+}
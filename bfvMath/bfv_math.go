@@ -1,99 +1,254 @@
 package bfvMath
 
 import (
+	"errors"
 	"github.com/ldsec/lattigo/v2/bfv"
-	"github.com/ldsec/lattigo/v2/rlwe"
 	"log"
 )
 
 type ConstOperation func([]byte, uint64) ([]byte, error)
+type SignedConstOperation func([]byte, int64) ([]byte, error)
+type Operation1 func([]byte) ([]byte, error)
 type Operation2 func([]byte, []byte) ([]byte, error)
 
-var BfvParams bfv.Parameters
-var BfvEvaluator bfv.Evaluator
-var BfvEvalKey rlwe.EvaluationKey
+var errNoRelinearizationKey = errors.New("evaluation key has no relinearization key")
 
-// unmarshallIntoNewCiphertext returns a new bfv.Ciphertext containing
-// unmarshalled number from encryptedData
-func unmarshallIntoNewCiphertext(encryptedData []byte) (*bfv.Ciphertext, error) {
-	ciphertext := bfv.NewCiphertext(BfvParams, 1)
-	err := ciphertext.UnmarshalBinary(encryptedData)
+// MultByPositiveConst Multiplies encryptedData by uint64 multValue, producing []byte of encrypted data
+// containing a product of encryptedData and multValue when decrypted
+func (ctx *Context) MultByPositiveConst(encryptedData []byte, multValue uint64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.MultByPositiveConst(multValue)
 	if err != nil {
 		return nil, err
 	}
-	return ciphertext, nil
+
+	log.Println("BFV: MultByPositiveConst success")
+	return result.Marshal()
 }
 
-// makeZeroCiphertext Takes any encrypted data and subtracts it from itself
-// making a *bfv.Ciphertext containing 0 when decrypted
-func makeZeroCiphertext(someEncryptedData []byte) (*bfv.Ciphertext, error) {
-	ciphertext := bfv.NewCiphertext(BfvParams, 1)
-	err := ciphertext.UnmarshalBinary(someEncryptedData)
+// AddConst Adds an int64 addValue to encrypted data, producing []byte of encrypted data
+// containing a sum of encryptedData data and addValue when decrypted.
+// As all BFV arithmetic, the sum wraps around modulo the plaintext modulus
+func (ctx *Context) AddConst(encryptedData []byte, addValue int64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.AddConst(addValue)
 	if err != nil {
 		return nil, err
 	}
-	return BfvEvaluator.SubNew(ciphertext, ciphertext), nil
+
+	log.Println("BFV: AddConst success")
+	return result.Marshal()
 }
 
-// MultByPositiveConst Multiplies encryptedData by uint64 multValue, producing []byte of encrypted data
-// containing a product of encryptedData and multValue when decrypted
-func MultByPositiveConst(encryptedData []byte, multValue uint64) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+// SubtractConst Subtracts an int64 subValue from encrypted data, producing []byte of encrypted data
+// containing a difference of encryptedData data and subValue when decrypted
+func (ctx *Context) SubtractConst(encryptedData []byte, subValue int64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("BFV: MultByPositiveConst success")
-	return BfvEvaluator.MulScalarNew(ciphertext, multValue).MarshalBinary()
+	result, err := ciphertext.SubtractConst(subValue)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: SubtractConst success")
+	return result.Marshal()
+}
+
+// Negate Produces []byte of encrypted data containing encryptedData with the opposite sign when decrypted
+func (ctx *Context) Negate(encryptedData []byte) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.Negate()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: Negate success")
+	return result.Marshal()
+}
+
+// MultByConst Multiplies encryptedData by int64 multValue, which may be negative, producing
+// []byte of encrypted data containing a product of encryptedData and multValue when decrypted
+func (ctx *Context) MultByConst(encryptedData []byte, multValue int64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.MultByConst(multValue)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: MultByConst success")
+	return result.Marshal()
 }
 
 // Sum Adds encryptedData to encryptedData2, producing []byte of encrypted data
 // containing a sum of encryptedData data and encryptedData2 when decrypted
-func Sum(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+func (ctx *Context) Sum(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext2, err := ctx.Unmarshal(encryptedData2)
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertext2, err := unmarshallIntoNewCiphertext(encryptedData2)
+	result, err := ciphertext.Sum(ciphertext2)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("BFV: Sum success")
-	return BfvEvaluator.AddNew(ciphertext, ciphertext2).MarshalBinary()
+	return result.Marshal()
 }
 
 // Subtract Subtracts encryptedData2 from encryptedData, producing []byte of encrypted data
 // containing a difference of encryptedData data and encryptedData2 when decrypted
-func Subtract(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+func (ctx *Context) Subtract(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext2, err := ctx.Unmarshal(encryptedData2)
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertext2, err := unmarshallIntoNewCiphertext(encryptedData2)
+	result, err := ciphertext.Subtract(ciphertext2)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("BFV: Subtract success")
-	return BfvEvaluator.SubNew(ciphertext, ciphertext2).MarshalBinary()
+	return result.Marshal()
 }
 
 // Mult Multiplies encryptedData by encryptedData2, producing []byte of encrypted data
 // containing a product of encryptedData and encryptedData2 when decrypted
-func Mult(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+func (ctx *Context) Mult(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertext2, err := unmarshallIntoNewCiphertext(encryptedData2)
+	ciphertext2, err := ctx.Unmarshal(encryptedData2)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.Mult(ciphertext2)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("BFV: Mult success")
-	return BfvEvaluator.MulNew(ciphertext, ciphertext2).MarshalBinary()
+	return result.Marshal()
+}
+
+// MultByPositiveConst Returns a new BFVCiphertext containing a product of ct and multValue when decrypted
+func (ct *BFVCiphertext) MultByPositiveConst(multValue uint64) (*BFVCiphertext, error) {
+	return ct.derive(ct.ctx.Evaluator.MulScalarNew(ct.Ciphertext, multValue)), nil
+}
+
+// AddConst Returns a new BFVCiphertext containing a sum of ct and addValue modulo
+// the plaintext modulus when decrypted
+func (ct *BFVCiphertext) AddConst(addValue int64) (*BFVCiphertext, error) {
+	params := ct.ctx.Params
+
+	// the constant is added to every slot
+	values := make([]uint64, params.N())
+	for i := range values {
+		values[i] = ct.ctx.reduce(addValue)
+	}
+	plaintext := bfv.NewPlaintext(params)
+	bfv.NewEncoder(params).EncodeUint(values, plaintext)
+
+	return ct.derive(ct.ctx.Evaluator.AddNew(ct.Ciphertext, plaintext)), nil
+}
+
+// SubtractConst Returns a new BFVCiphertext containing a difference of ct and subValue modulo
+// the plaintext modulus when decrypted
+func (ct *BFVCiphertext) SubtractConst(subValue int64) (*BFVCiphertext, error) {
+	// -subValue overflows for math.MinInt64, its residue doesn't
+	return ct.AddConst(-int64(ct.ctx.reduce(subValue)))
+}
+
+// Negate Returns a new BFVCiphertext containing -ct when decrypted
+func (ct *BFVCiphertext) Negate() (*BFVCiphertext, error) {
+	return ct.derive(ct.ctx.Evaluator.NegNew(ct.Ciphertext)), nil
+}
+
+// MultByConst Returns a new BFVCiphertext containing a product of ct and multValue modulo
+// the plaintext modulus when decrypted. Noise grows with |multValue|, so negative constants
+// are applied as a negation followed by a multiplication by their absolute value
+func (ct *BFVCiphertext) MultByConst(multValue int64) (*BFVCiphertext, error) {
+	value := ct.ctx.reduce(multValue)
+	t := ct.ctx.Params.T()
+	if value <= t/2 {
+		return ct.MultByPositiveConst(value)
+	}
+
+	// value is congruent to -(t - value), multiplying by it directly would grow noise by ~t
+	negated, err := ct.Negate()
+	if err != nil {
+		return nil, err
+	}
+	return negated.MultByPositiveConst(t - value)
+}
+
+// Sum Returns a new BFVCiphertext containing a sum of ct and other when decrypted
+func (ct *BFVCiphertext) Sum(other *BFVCiphertext) (*BFVCiphertext, error) {
+	if err := ct.checkKeys(other); err != nil {
+		return nil, err
+	}
+	return ct.derive(ct.ctx.Evaluator.AddNew(ct.Ciphertext, other.Ciphertext)), nil
+}
+
+// Subtract Returns a new BFVCiphertext containing a difference of ct and other when decrypted
+func (ct *BFVCiphertext) Subtract(other *BFVCiphertext) (*BFVCiphertext, error) {
+	if err := ct.checkKeys(other); err != nil {
+		return nil, err
+	}
+	return ct.derive(ct.ctx.Evaluator.SubNew(ct.Ciphertext, other.Ciphertext)), nil
+}
+
+// Mult Returns a new BFVCiphertext containing a product of ct and other when decrypted
+func (ct *BFVCiphertext) Mult(other *BFVCiphertext) (*BFVCiphertext, error) {
+	if err := ct.checkKeys(other); err != nil {
+		return nil, err
+	}
+	return ct.derive(ct.ctx.Evaluator.MulNew(ct.Ciphertext, other.Ciphertext)), nil
+}
+
+// Relinearize Returns a new BFVCiphertext of degree 1 containing the same value as ct
+// of degree 2, e.g. a result of Mult. Requires the relinearization key
+func (ct *BFVCiphertext) Relinearize() (*BFVCiphertext, error) {
+	result := ct.CopyNew()
+	if ct.Ciphertext.Degree() < 2 {
+		return result, nil
+	}
+	if ct.ctx.EvalKey.Rlk == nil {
+		return nil, errNoRelinearizationKey
+	}
+	ct.ctx.Evaluator.Relinearize(result.Ciphertext, result.Ciphertext)
+	return result, nil
 }
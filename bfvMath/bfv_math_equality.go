@@ -0,0 +1,123 @@
+package bfvMath
+
+import (
+	"log"
+	"math/bits"
+)
+
+// Equality is tested with Fermat's little theorem: for the prime plaintext modulus t,
+// d^(t - 1) is 1 for any d != 0 and 0 for d = 0, so 1 - (a - b)^(t - 1) is 1 exactly where
+// a = b modulo t. The power has multiplicative depth ceil(log2(t - 1)), 16 for t = 65537,
+// which exceeds the noise budget of default parameters: equality tests return an error unless
+// Context.MaxDepth is at least 16, e.g. with bfv.PN15QP880
+
+type SetOperation func([][]byte, []byte) ([]byte, error)
+
+// IsEqual Tests encryptedData and encryptedData2 for equality, producing []byte of encrypted
+// data containing 1 where they are equal and 0 elsewhere when decrypted. Requires the
+// relinearization key and parameters of depth at least 16, otherwise returns an error, see above
+func (ctx *Context) IsEqual(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext2, err := ctx.Unmarshal(encryptedData2)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.IsEqual(ciphertext2)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: IsEqual success")
+	return result.Marshal()
+}
+
+// Contains Tests whether encryptedData is one of the elements of encryptedSet in []byte,
+// producing []byte of encrypted data containing 1 if it is and 0 otherwise when decrypted.
+// Elements of the set must be distinct, otherwise the result is the number of elements equal
+// to encryptedData. Slots are tested independently, every slot of encryptedData is looked up
+// in the same slot of elements. Has the depth of IsEqual regardless of the size of the set
+func (ctx *Context) Contains(encryptedSet [][]byte, encryptedData []byte) ([]byte, error) {
+	set, err := ctx.UnmarshalArray(encryptedSet)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := set.Contains(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: Contains success")
+	return result.Marshal()
+}
+
+// IsEqual Returns a new BFVCiphertext containing 1 where ct equals other and 0 elsewhere when decrypted
+func (ct *BFVCiphertext) IsEqual(other *BFVCiphertext) (*BFVCiphertext, error) {
+	difference, err := ct.Subtract(other)
+	if err != nil {
+		return nil, err
+	}
+	return difference.isZero()
+}
+
+// Contains Returns a new BFVCiphertext containing 1 if set contains ct and 0 otherwise when decrypted
+func (set BFVCiphertextArray) Contains(ct *BFVCiphertext) (*BFVCiphertext, error) {
+	if len(set) == 0 {
+		return nil, errEmptyArray
+	}
+
+	matches := make(BFVCiphertextArray, len(set))
+	for i, element := range set {
+		var err error
+		matches[i], err = element.IsEqual(ct)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return matches.Sum()
+}
+
+// equalsConst returns a new BFVCiphertext containing 1 where ct equals value and 0 elsewhere
+// when decrypted
+func (ct *BFVCiphertext) equalsConst(value int64) (*BFVCiphertext, error) {
+	difference, err := ct.SubtractConst(value)
+	if err != nil {
+		return nil, err
+	}
+	return difference.isZero()
+}
+
+// isZero returns a new BFVCiphertext containing 1 - ct^(t - 1), which is 1 where ct is 0
+// and 0 elsewhere when decrypted
+func (ct *BFVCiphertext) isZero() (*BFVCiphertext, error) {
+	if err := ct.ctx.checkEqualityDepth(); err != nil {
+		return nil, err
+	}
+
+	inequality, err := ct.Pow(ct.ctx.Params.T() - 1)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := inequality.Negate()
+	if err != nil {
+		return nil, err
+	}
+	return result.AddConst(1)
+}
+
+// checkEqualityDepth returns an error if ctx.Params don't support the multiplicative depth
+// of an equality test, ceil(log2(t - 1))
+func (ctx *Context) checkEqualityDepth() error {
+	return ctx.checkDepth("equality test", bits.Len64(ctx.Params.T()-2))
+}
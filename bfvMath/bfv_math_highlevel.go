@@ -2,34 +2,171 @@ package bfvMath
 
 import (
 	"errors"
-	"github.com/ldsec/lattigo/v2/bfv"
+	"fmt"
 	"log"
+	"math/bits"
 )
 
 type ArrayOperation func([][]byte) ([]byte, error)
+type ArrayConstOperation func([][]byte, int64) ([]byte, error)
+type HistogramOperation func([][]byte, []int64) ([][]byte, error)
+
+var errEmptyArray = errors.New("cannot use empty array")
 
 // ArraySum Returns the encrypted sum of all elements of passed array in []byte
-func ArraySum(encryptedDataArray [][]byte) ([]byte, error) {
-	if len(encryptedDataArray) == 0 {
-		return nil, errors.New("cannot use empty array")
+func (ctx *Context) ArraySum(encryptedDataArray [][]byte) ([]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := array.Sum()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: ArraySum success")
+	return result.Marshal()
+}
+
+// ArrayProduct Returns the encrypted product of all elements of passed array in []byte.
+// Elements are multiplied pairwise, so the product of n elements has multiplicative depth
+// ceil(log2(n)). Requires the relinearization key, and returns an error if the depth exceeds
+// Context.MaxDepth, i.e. for more than 256 elements with default parameters
+func (ctx *Context) ArrayProduct(encryptedDataArray [][]byte) ([]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
+	if err != nil {
+		return nil, err
 	}
 
-	sumCiphertext, err := makeZeroCiphertext(encryptedDataArray[0])
+	result, err := array.Product()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, encryptedData := range encryptedDataArray {
-		ciphertext := bfv.NewCiphertext(BfvParams, 1)
+	log.Println("BFV: ArrayProduct success")
+	return result.Marshal()
+}
 
-		err := ciphertext.UnmarshalBinary(encryptedData)
-		BfvEvaluator.Add(sumCiphertext, ciphertext, sumCiphertext)
+// CountIf Returns the encrypted number of elements of passed array in []byte equal to predicateValue.
+// Slots are counted independently. Elements are compared as in IsEqual, which requires parameters
+// of depth at least 16, otherwise returns an error
+func (ctx *Context) CountIf(encryptedDataArray [][]byte, predicateValue int64) ([]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
+	if err != nil {
+		return nil, err
+	}
 
+	result, err := array.CountIf(predicateValue)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: CountIf success")
+	return result.Marshal()
+}
+
+// Histogram Returns encrypted numbers of elements of passed array in []byte equal to each of buckets,
+// e.g. categories of a categorical column. Values outside of buckets aren't counted. Costs
+// len(array) * len(buckets) equality tests, see CountIf
+func (ctx *Context) Histogram(encryptedDataArray [][]byte, buckets []int64) ([][]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := array.Histogram(buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: Histogram success")
+	return result.Marshal()
+}
+
+// Sum Returns a new BFVCiphertext containing the sum of all elements of array when decrypted
+func (array BFVCiphertextArray) Sum() (*BFVCiphertext, error) {
+	if len(array) == 0 {
+		return nil, errEmptyArray
+	}
+	if err := array[0].checkKeys(array...); err != nil {
+		return nil, err
+	}
+
+	evaluator := array[0].ctx.Evaluator
+	sum := array[0].CopyNew()
+	for _, ct := range array[1:] {
+		evaluator.Add(sum.Ciphertext, ct.Ciphertext, sum.Ciphertext)
+	}
+	return sum, nil
+}
+
+// Product Returns a new BFVCiphertext containing the product of all elements of array when decrypted
+func (array BFVCiphertextArray) Product() (*BFVCiphertext, error) {
+	if len(array) == 0 {
+		return nil, errEmptyArray
+	}
+	if err := array[0].checkKeys(array...); err != nil {
+		return nil, err
+	}
+	if err := array[0].ctx.checkDepth(fmt.Sprintf("product of %d elements", len(array)), bits.Len(uint(len(array)-1))); err != nil {
+		return nil, err
+	}
+
+	factors := make([]factor, len(array))
+	for i, ct := range array {
+		factors[i] = factor{ct, 0}
+	}
+	return product(factors)
+}
+
+// CountIf Returns a new BFVCiphertext containing the number of elements of array equal to
+// predicateValue when decrypted
+func (array BFVCiphertextArray) CountIf(predicateValue int64) (*BFVCiphertext, error) {
+	if len(array) == 0 {
+		return nil, errEmptyArray
+	}
+	if err := array[0].checkKeys(array...); err != nil {
+		return nil, err
+	}
+	if err := array[0].ctx.checkEqualityDepth(); err != nil {
+		return nil, err
+	}
+
+	matches := make(BFVCiphertextArray, len(array))
+	for i, ct := range array {
+		var err error
+		matches[i], err = ct.equalsConst(predicateValue)
 		if err != nil {
 			return nil, err
 		}
 	}
+	return matches.Sum()
+}
 
-	log.Println("BFV: ArraySum success")
-	return sumCiphertext.MarshalBinary()
+// Histogram Returns a new BFVCiphertextArray containing numbers of elements of array equal to
+// each of buckets when decrypted
+func (array BFVCiphertextArray) Histogram(buckets []int64) (BFVCiphertextArray, error) {
+	if len(buckets) == 0 {
+		return nil, errors.New("histogram requires at least one bucket")
+	}
+	if len(array) == 0 {
+		return nil, errEmptyArray
+	}
+	if err := array[0].checkKeys(array...); err != nil {
+		return nil, err
+	}
+	if err := array[0].ctx.checkEqualityDepth(); err != nil {
+		return nil, err
+	}
+
+	counts := make(BFVCiphertextArray, len(buckets))
+	for i, bucket := range buckets {
+		var err error
+		counts[i], err = array.CountIf(bucket)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
 }
@@ -0,0 +1,192 @@
+package bfvMath
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/bits"
+)
+
+type PolynomialOperation func([]byte, []int64) ([]byte, error)
+
+// factor Ciphertext along with the multiplicative depth it was computed with,
+// relative to the operands of an operation
+type factor struct {
+	ct    *BFVCiphertext
+	depth int
+}
+
+// Pow Raises encryptedData to the power of n, producing []byte of encrypted data containing
+// encryptedData^n when decrypted. Uses square-and-multiply with multiplicative depth
+// ceil(log2(n)), and requires the relinearization key. Returns an error if the depth exceeds
+// Context.MaxDepth, i.e. for n > 256 with default parameters
+func (ctx *Context) Pow(encryptedData []byte, n uint64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.Pow(n)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: Pow success")
+	return result.Marshal()
+}
+
+// EvaluatePolynomial Evaluates polynomial with integer coeffs on every slot of encryptedData,
+// coeffs[i] being the coefficient of x^i, producing []byte of encrypted data containing
+// polynomial(encryptedData) when decrypted. All powers of x up to the degree are computed
+// with the lowest multiplicative depth, ceil(log2(degree)), and requires the relinearization key.
+// Returns an error if the depth exceeds Context.MaxDepth, see Pow
+func (ctx *Context) EvaluatePolynomial(encryptedData []byte, coeffs []int64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.EvaluatePolynomial(coeffs)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: EvaluatePolynomial success")
+	return result.Marshal()
+}
+
+// Pow Returns a new BFVCiphertext containing ct^n when decrypted
+func (ct *BFVCiphertext) Pow(n uint64) (*BFVCiphertext, error) {
+	if n == 0 {
+		return ct.constant(1)
+	}
+	if err := ct.ctx.checkDepth(fmt.Sprintf("x^%d", n), bits.Len64(n-1)); err != nil {
+		return nil, err
+	}
+
+	x, err := ct.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+
+	// x^(2^i) for every set bit i of n, multiplied shallowest first
+	var factors []factor
+	square := factor{x, 0}
+	for i := 0; i < bits.Len64(n); i++ {
+		if i > 0 {
+			square, err = multiply(square, square)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if n&(1<<i) != 0 {
+			factors = append(factors, square)
+		}
+	}
+	return product(factors)
+}
+
+// EvaluatePolynomial Returns a new BFVCiphertext containing polynomial with coeffs evaluated on ct
+// when decrypted
+func (ct *BFVCiphertext) EvaluatePolynomial(coeffs []int64) (*BFVCiphertext, error) {
+	if len(coeffs) == 0 {
+		return nil, errors.New("polynomial has no coefficients")
+	}
+
+	degree := len(coeffs) - 1
+	for degree > 0 && coeffs[degree] == 0 {
+		degree--
+	}
+
+	result, err := ct.constant(coeffs[0])
+	if err != nil || degree == 0 {
+		return result, err
+	}
+	if err := ct.ctx.checkDepth(fmt.Sprintf("polynomial of degree %d", degree), bits.Len(uint(degree-1))); err != nil {
+		return nil, err
+	}
+
+	x, err := ct.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+
+	// x^i = x^(2^k) * x^(i - 2^k) for the highest power of two 2^k below i,
+	// which has depth ceil(log2(i))
+	powers := make([]factor, degree+1)
+	powers[1] = factor{x, 0}
+	for i := 2; i <= degree; i++ {
+		k := 1 << (bits.Len(uint(i)) - 1)
+		if k == i {
+			k = i / 2
+		}
+		powers[i], err = multiply(powers[k], powers[i-k])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 1; i <= degree; i++ {
+		if coeffs[i] == 0 {
+			continue
+		}
+
+		term, err := powers[i].ct.MultByConst(coeffs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		result, err = result.Sum(term)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// constant returns a new BFVCiphertext containing value in every slot, encrypted as ct is
+func (ct *BFVCiphertext) constant(value int64) (*BFVCiphertext, error) {
+	zero, err := ct.MultByPositiveConst(0)
+	if err != nil {
+		return nil, err
+	}
+	return zero.AddConst(value)
+}
+
+// multiply returns the relinearized product of f1 and f2
+func multiply(f1 factor, f2 factor) (factor, error) {
+	product, err := f1.ct.Mult(f2.ct)
+	if err != nil {
+		return factor{}, err
+	}
+
+	product, err = product.Relinearize()
+	if err != nil {
+		return factor{}, err
+	}
+	return factor{product, max(f1.depth, f2.depth) + 1}, nil
+}
+
+// product multiplies factors, always multiplying the two shallowest ones, which keeps
+// the depth of the product lowest
+func product(factors []factor) (*BFVCiphertext, error) {
+	factors = append([]factor{}, factors...)
+	for len(factors) > 1 {
+		// moves the two shallowest factors to the end
+		for i := 0; i < 2; i++ {
+			last := len(factors) - 1 - i
+			for j := 0; j < last; j++ {
+				if factors[j].depth < factors[last].depth {
+					factors[j], factors[last] = factors[last], factors[j]
+				}
+			}
+		}
+
+		f, err := multiply(factors[len(factors)-1], factors[len(factors)-2])
+		if err != nil {
+			return nil, err
+		}
+		factors = append(factors[:len(factors)-2], f)
+	}
+	return factors[0].ct.CopyNew(), nil
+}
@@ -0,0 +1,187 @@
+package bfvMath
+
+import (
+	"fmt"
+	"github.com/ldsec/lattigo/v2/bfv"
+	"log"
+)
+
+type SlotOperation func([]byte, int) ([]byte, error)
+type SlotOperation2 func([]byte, []byte, int) ([]byte, error)
+type VectorOperation func([]byte, []int64) ([]byte, error)
+
+// SlotSum Returns encrypted data whose slot 0 contains the sum of the first n slots
+// of encryptedData. Uses log2(n) rotate-and-add steps and requires rotation keys
+// for powers of two below n, plus a row rotation key if n exceeds one row.
+// There is no BFV mean, since integer division can't be done homomorphically,
+// divide the decrypted sum instead
+func (ctx *Context) SlotSum(encryptedData []byte, n int) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.SlotSum(n)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: SlotSum success")
+	return result.Marshal()
+}
+
+// InnerProduct Returns encrypted data whose slot 0 contains the inner product of the
+// first n slots of encryptedData and encryptedData2
+func (ctx *Context) InnerProduct(encryptedData []byte, encryptedData2 []byte, n int) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext2, err := ctx.Unmarshal(encryptedData2)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.InnerProduct(ciphertext2, n)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: InnerProduct success")
+	return result.Marshal()
+}
+
+// MultByVector Multiplies every slot of encryptedData by the value of the same slot of
+// plaintext values. Slots beyond len(values) are multiplied by zero
+func (ctx *Context) MultByVector(encryptedData []byte, values []int64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.MultByVector(values)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("BFV: MultByVector success")
+	return result.Marshal()
+}
+
+// MultByVector Returns a new BFVCiphertext containing the slot-wise product of ct and
+// plaintext values when decrypted
+func (ct *BFVCiphertext) MultByVector(values []int64) (*BFVCiphertext, error) {
+	params := ct.ctx.Params
+	if len(values) > params.N() {
+		return nil, fmt.Errorf("cannot multiply by %d values, ciphertext has %d slots", len(values), params.N())
+	}
+
+	plaintext := bfv.NewPlaintextMul(params)
+	bfv.NewEncoder(params).EncodeIntMul(values, plaintext)
+	return ct.derive(ct.ctx.Evaluator.MulNew(ct.Ciphertext, plaintext)), nil
+}
+
+// SlotSum Returns a new BFVCiphertext whose slot 0 contains the sum of the first n slots of ct
+func (ct *BFVCiphertext) SlotSum(n int) (*BFVCiphertext, error) {
+	sum, err := ct.ctx.slotSum(ct.Ciphertext, n)
+	if err != nil {
+		return nil, err
+	}
+	return ct.derive(sum), nil
+}
+
+// InnerProduct Returns a new BFVCiphertext whose slot 0 contains the inner product of
+// the first n slots of ct and other
+func (ct *BFVCiphertext) InnerProduct(other *BFVCiphertext, n int) (*BFVCiphertext, error) {
+	product, err := ct.Mult(other)
+	if err != nil {
+		return nil, err
+	}
+
+	product, err = product.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+	return product.SlotSum(n)
+}
+
+// slotSum sums the first n slots of ciphertext into slot 0. BFV slots form two rows
+// rotated together, so sums spanning both rows add the sum of the whole first row
+// to the sum of the beginning of the second one, brought over by a row rotation
+func (ctx *Context) slotSum(ciphertext *bfv.Ciphertext, n int) (*bfv.Ciphertext, error) {
+	rowSize := ctx.Params.N() >> 1
+	if n < 1 || n > ctx.Params.N() {
+		return nil, fmt.Errorf("number of slots must be between 1 and %d", ctx.Params.N())
+	}
+	if err := ctx.checkRotationKeys(n); err != nil {
+		return nil, err
+	}
+
+	if n <= rowSize {
+		return ctx.rowSum(ciphertext, n), nil
+	}
+
+	result := ctx.rowSum(ciphertext, rowSize)
+	secondRow := ctx.Evaluator.RotateRowsNew(ctx.rowSum(ciphertext, n-rowSize))
+	ctx.Evaluator.Add(result, secondRow, result)
+	return result, nil
+}
+
+// rowSum sums the first n slots of each row into the first slot of the row, reading
+// n bit by bit: every step doubles the width of partial sums, and partial sums
+// matching set bits of n are rotated into place and accumulated
+func (ctx *Context) rowSum(ciphertext *bfv.Ciphertext, n int) *bfv.Ciphertext {
+	var result *bfv.Ciphertext
+	partial := ciphertext
+	for i, j := 0, n; j > 0; i, j = i+1, j>>1 {
+		if j&1 == 1 {
+			rotated := ctx.rotate(partial, n-(n&((2<<i)-1)))
+			if result == nil {
+				result = rotated
+			} else {
+				ctx.Evaluator.Add(result, rotated, result)
+			}
+		}
+		if j > 1 {
+			partial = ctx.Evaluator.AddNew(partial, ctx.Evaluator.RotateColumnsNew(partial, 1<<i))
+		}
+	}
+	return result
+}
+
+// rotate returns a new ciphertext with columns rotated left by k slots,
+// composing rotations by powers of two
+func (ctx *Context) rotate(ciphertext *bfv.Ciphertext, k int) *bfv.Ciphertext {
+	rotated := ciphertext.CopyNew()
+	for bit := 1; bit <= k; bit <<= 1 {
+		if k&bit != 0 {
+			ctx.Evaluator.RotateColumns(rotated, bit, rotated)
+		}
+	}
+	return rotated
+}
+
+// checkRotationKeys returns an error if EvalKey lacks a rotation key
+// needed to sum n slots
+func (ctx *Context) checkRotationKeys(n int) error {
+	rowSize := ctx.Params.N() >> 1
+
+	galEls := make([]uint64, 0)
+	for k := 1; k < min(n, rowSize); k <<= 1 {
+		galEls = append(galEls, ctx.Params.GaloisElementForColumnRotationBy(k))
+	}
+	if n > rowSize {
+		galEls = append(galEls, ctx.Params.GaloisElementForRowRotation())
+	}
+
+	for _, galEl := range galEls {
+		if ctx.EvalKey.Rtks == nil {
+			return fmt.Errorf("evaluation key has no rotation keys, cannot aggregate %d slots", n)
+		}
+		if _, ok := ctx.EvalKey.Rtks.Keys[galEl]; !ok {
+			return fmt.Errorf("evaluation key has no rotation key for galois element %d, cannot aggregate %d slots", galEl, n)
+		}
+	}
+	return nil
+}
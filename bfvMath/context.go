@@ -0,0 +1,199 @@
+package bfvMath
+
+import (
+	"fmt"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"github.com/ldsec/lattigo/v2/bfv"
+	"github.com/ldsec/lattigo/v2/rlwe"
+	"math/bits"
+)
+
+// Context Holds BFV parameters, evaluation key and an Evaluator built from them.
+// All bfvMath operations are available as methods on Context, so several
+// parameter and key sets can be used side by side in one process.
+// A Context is not safe for concurrent use, use ShallowCopy to get one per goroutine
+type Context struct {
+	Params    bfv.Parameters
+	EvalKey   rlwe.EvaluationKey
+	Evaluator bfv.Evaluator
+
+	// KeyID ID of the key EvalKey belongs to. If set, operations reject ciphertexts
+	// encrypted with other keys. If empty, any key ID is accepted, and results carry
+	// the key ID of operands
+	KeyID string
+
+	// AcceptLegacyCiphertexts If set, ciphertexts written before envelopes recorded scheme and
+	// parameters are accepted without checking them, see envelope.OpenLegacy
+	AcceptLegacyCiphertexts bool
+}
+
+// BfvParams, BfvEvaluator and BfvEvalKey back the package-level functions.
+//
+// Deprecated: create a Context with NewContext instead
+var BfvParams bfv.Parameters
+var BfvEvaluator bfv.Evaluator
+var BfvEvalKey rlwe.EvaluationKey
+
+// NewContext Creates a new Context and an Evaluator for passed params and evalKey
+func NewContext(params bfv.Parameters, evalKey rlwe.EvaluationKey) *Context {
+	return &Context{
+		Params:    params,
+		EvalKey:   evalKey,
+		Evaluator: bfv.NewEvaluator(params, evalKey),
+	}
+}
+
+// ShallowCopy Creates a copy of Context sharing params and keys, but with its own
+// Evaluator buffers, so that the copy can be used in another goroutine
+func (ctx *Context) ShallowCopy() *Context {
+	return &Context{
+		Params:    ctx.Params,
+		EvalKey:   ctx.EvalKey,
+		Evaluator: ctx.Evaluator.ShallowCopy(),
+		KeyID:     ctx.KeyID,
+
+		AcceptLegacyCiphertexts: ctx.AcceptLegacyCiphertexts,
+	}
+}
+
+// MaxDepth Returns the multiplicative depth of computations on fresh ciphertexts ctx.Params support,
+// estimated from the sizes of the ciphertext modulus, plaintext modulus and ring degree. Noise of
+// deeper computations exceeds the budget and results decrypt to garbage, so operations return an
+// error instead. It is 8 for default parameters and 18 for bfv.PN15QP880
+func (ctx *Context) MaxDepth() int {
+	logT := bits.Len64(ctx.Params.T())
+	return (ctx.Params.LogQ() - logT) / (logT + ctx.Params.LogN() + 5)
+}
+
+// checkDepth returns an error if operation needs a larger multiplicative depth than ctx.Params support
+func (ctx *Context) checkDepth(operation string, depth int) error {
+	if maxDepth := ctx.MaxDepth(); depth > maxDepth {
+		return fmt.Errorf("%s needs multiplicative depth %d, but parameters support only %d, use deeper parameters such as bfv.PN15QP880",
+			operation, depth, maxDepth)
+	}
+	return nil
+}
+
+// reduce returns value modulo the plaintext modulus, within [0, T)
+func (ctx *Context) reduce(value int64) uint64 {
+	t := int64(ctx.Params.T())
+	return uint64((value%t + t) % t)
+}
+
+// checkKeyID returns envelope.KeyMismatchError if ctx only accepts ciphertexts
+// encrypted with another key
+func (ctx *Context) checkKeyID(keyID string) error {
+	if ctx.KeyID != "" && keyID != ctx.KeyID {
+		return &envelope.KeyMismatchError{Expected: ctx.KeyID, Actual: keyID}
+	}
+	return nil
+}
+
+// paramsHash returns the fingerprint of ctx.Params written into envelopes
+func (ctx *Context) paramsHash() (uint64, error) {
+	return envelope.ParamsHash(ctx.Params)
+}
+
+// defaultContext returns a Context built from package-level variables
+func defaultContext() *Context {
+	return &Context{
+		Params:    BfvParams,
+		EvalKey:   BfvEvalKey,
+		Evaluator: BfvEvaluator,
+	}
+}
+
+// MultByPositiveConst Same as Context.MultByPositiveConst, using package-level variables
+func MultByPositiveConst(encryptedData []byte, multValue uint64) ([]byte, error) {
+	return defaultContext().MultByPositiveConst(encryptedData, multValue)
+}
+
+// AddConst Same as Context.AddConst, using package-level variables
+func AddConst(encryptedData []byte, addValue int64) ([]byte, error) {
+	return defaultContext().AddConst(encryptedData, addValue)
+}
+
+// SubtractConst Same as Context.SubtractConst, using package-level variables
+func SubtractConst(encryptedData []byte, subValue int64) ([]byte, error) {
+	return defaultContext().SubtractConst(encryptedData, subValue)
+}
+
+// Negate Same as Context.Negate, using package-level variables
+func Negate(encryptedData []byte) ([]byte, error) {
+	return defaultContext().Negate(encryptedData)
+}
+
+// MultByConst Same as Context.MultByConst, using package-level variables
+func MultByConst(encryptedData []byte, multValue int64) ([]byte, error) {
+	return defaultContext().MultByConst(encryptedData, multValue)
+}
+
+// Sum Same as Context.Sum, using package-level variables
+func Sum(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	return defaultContext().Sum(encryptedData, encryptedData2)
+}
+
+// Subtract Same as Context.Subtract, using package-level variables
+func Subtract(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	return defaultContext().Subtract(encryptedData, encryptedData2)
+}
+
+// Mult Same as Context.Mult, using package-level variables
+func Mult(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	return defaultContext().Mult(encryptedData, encryptedData2)
+}
+
+// ArraySum Same as Context.ArraySum, using package-level variables
+func ArraySum(encryptedDataArray [][]byte) ([]byte, error) {
+	return defaultContext().ArraySum(encryptedDataArray)
+}
+
+// SlotSum Same as Context.SlotSum, using package-level variables
+func SlotSum(encryptedData []byte, n int) ([]byte, error) {
+	return defaultContext().SlotSum(encryptedData, n)
+}
+
+// InnerProduct Same as Context.InnerProduct, using package-level variables
+func InnerProduct(encryptedData []byte, encryptedData2 []byte, n int) ([]byte, error) {
+	return defaultContext().InnerProduct(encryptedData, encryptedData2, n)
+}
+
+// MultByVector Same as Context.MultByVector, using package-level variables
+func MultByVector(encryptedData []byte, values []int64) ([]byte, error) {
+	return defaultContext().MultByVector(encryptedData, values)
+}
+
+// ArrayProduct Same as Context.ArrayProduct, using package-level variables
+func ArrayProduct(encryptedDataArray [][]byte) ([]byte, error) {
+	return defaultContext().ArrayProduct(encryptedDataArray)
+}
+
+// Pow Same as Context.Pow, using package-level variables
+func Pow(encryptedData []byte, n uint64) ([]byte, error) {
+	return defaultContext().Pow(encryptedData, n)
+}
+
+// EvaluatePolynomial Same as Context.EvaluatePolynomial, using package-level variables
+func EvaluatePolynomial(encryptedData []byte, coeffs []int64) ([]byte, error) {
+	return defaultContext().EvaluatePolynomial(encryptedData, coeffs)
+}
+
+// IsEqual Same as Context.IsEqual, using package-level variables
+func IsEqual(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	return defaultContext().IsEqual(encryptedData, encryptedData2)
+}
+
+// Contains Same as Context.Contains, using package-level variables
+func Contains(encryptedSet [][]byte, encryptedData []byte) ([]byte, error) {
+	return defaultContext().Contains(encryptedSet, encryptedData)
+}
+
+// CountIf Same as Context.CountIf, using package-level variables
+func CountIf(encryptedDataArray [][]byte, predicateValue int64) ([]byte, error) {
+	return defaultContext().CountIf(encryptedDataArray, predicateValue)
+}
+
+// Histogram Same as Context.Histogram, using package-level variables
+func Histogram(encryptedDataArray [][]byte, buckets []int64) ([][]byte, error) {
+	return defaultContext().Histogram(encryptedDataArray, buckets)
+}
@@ -1,34 +1,106 @@
 package homomorphicEncryption
 
 import (
+	"fmt"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
 	"github.com/ldsec/lattigo/v2/bfv"
 )
 
 // EncryptBFV Encrypts float64 data into []byte using BVF algorithm
 func EncryptBFV(data int64) ([]byte, error) {
-	encoder := bfv.NewEncoder(BfvParams)
-	encryptor := bfv.NewEncryptor(BfvParams, BfvKeys.Pk)
+	return globalContext().EncryptBFV(data)
+}
 
-	plaintext := bfv.NewPlaintext(BfvParams)
-	encoder.EncodeInt([]int64{data}, plaintext)
+// DecryptBFV Decrypts data encrypted with BVF algorithm into an int64
+func DecryptBFV(data []byte) (int64, error) {
+	return globalContext().DecryptBFV(data)
+}
 
-	ciphertext := encryptor.EncryptNew(plaintext)
+// EncryptBFVVector Encrypts up to BfvSlots() int64 values into a single ciphertext
+// using BFV algorithm, one value per slot
+func EncryptBFVVector(data []int64) ([]byte, error) {
+	return globalContext().EncryptBFVVector(data)
+}
 
-	return ciphertext.MarshalBinary()
+// DecryptBFVVector Decrypts data encrypted with BFV algorithm into an []int64
+// containing all BfvSlots() slots
+func DecryptBFVVector(data []byte) ([]int64, error) {
+	return globalContext().DecryptBFVVector(data)
 }
 
-// DecryptBFV Decrypts data encrypted with BVF algorithm into an int64
-func DecryptBFV(data []byte) (int64, error) {
-	decryptor := bfv.NewDecryptor(BfvParams, BfvKeys.Sk)
-	ciphertext := bfv.NewCiphertext(BfvParams, 1)
-	err := ciphertext.UnmarshalBinary(data)
+// BfvSlots Returns the number of values a single BFV ciphertext can hold
+func BfvSlots() int {
+	return BfvParams.N()
+}
+
+// EncryptBFV Encrypts int64 data into []byte using BVF algorithm and ctx keys
+func (ctx *Context) EncryptBFV(data int64) ([]byte, error) {
+	return ctx.EncryptBFVVector([]int64{data})
+}
+
+// DecryptBFV Decrypts data encrypted with BVF algorithm into an int64 using ctx keys
+func (ctx *Context) DecryptBFV(data []byte) (int64, error) {
+	decoded, err := ctx.DecryptBFVVector(data)
 	if err != nil {
 		return 0, err
 	}
+	return decoded[0], nil
+}
 
-	plaintext := decryptor.DecryptNew(ciphertext)
-	encoder := bfv.NewEncoder(BfvParams)
-	decoded := encoder.DecodeIntNew(plaintext)
+// BfvSlots Returns the number of values a single BFV ciphertext of ctx can hold
+func (ctx *Context) BfvSlots() int {
+	return ctx.BfvParams.N()
+}
 
-	return decoded[0], nil
+// EncryptBFVVector Encrypts up to ctx.BfvSlots() int64 values into a single ciphertext
+// using BFV algorithm and ctx keys. Unused slots are filled with zeros
+func (ctx *Context) EncryptBFVVector(data []int64) ([]byte, error) {
+	if len(data) > ctx.BfvSlots() {
+		return nil, fmt.Errorf("cannot encrypt %d values into %d slots", len(data), ctx.BfvSlots())
+	}
+
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	encoder, encryptor, _ := ctx.bfvPrimitives()
+	if encryptor == nil {
+		return nil, errNoPublicKey
+	}
+
+	plaintext := bfv.NewPlaintext(ctx.BfvParams)
+	encoder.EncodeInt(data, plaintext)
+
+	ciphertext := encryptor.EncryptNew(plaintext)
+
+	ciphertextData, err := ciphertext.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return ctx.seal(envelope.SchemeBFV, ciphertextData)
+}
+
+// DecryptBFVVector Decrypts data encrypted with BFV algorithm into an []int64
+// containing all ctx.BfvSlots() slots using ctx keys
+func (ctx *Context) DecryptBFVVector(data []byte) ([]int64, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	encoder, _, decryptor := ctx.bfvPrimitives()
+	if decryptor == nil {
+		return nil, errNoSecretKey
+	}
+
+	payload, err := ctx.open(envelope.SchemeBFV, data)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := bfv.NewCiphertext(ctx.BfvParams, 1)
+	err = ciphertext.UnmarshalBinary(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := decryptor.DecryptNew(ciphertext)
+	return encoder.DecodeIntNew(plaintext), nil
 }
@@ -0,0 +1,164 @@
+package ckksMath
+
+import (
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"github.com/ldsec/lattigo/v2/ckks"
+)
+
+// CKKSCiphertext Encrypted data kept unmarshalled between operations, together with
+// the ID of the key it was encrypted with and the Context evaluating it. All operations
+// of Context are available as methods of CKKSCiphertext and CKKSCiphertextArray, so chains
+// of operations only unmarshal their input and marshal their result once.
+// Use Context.Unmarshal and Marshal at the boundaries
+type CKKSCiphertext struct {
+	Ciphertext *ckks.Ciphertext
+	KeyID      string
+
+	ctx *Context
+}
+
+// CKKSCiphertextArray Array of CKKSCiphertext with array operations such as Sum and Variance
+type CKKSCiphertextArray []*CKKSCiphertext
+
+// NewCKKSCiphertext Wraps ciphertext encrypted with key keyID into a CKKSCiphertext evaluated by ctx
+func (ctx *Context) NewCKKSCiphertext(ciphertext *ckks.Ciphertext, keyID string) *CKKSCiphertext {
+	return &CKKSCiphertext{Ciphertext: ciphertext, KeyID: keyID, ctx: ctx}
+}
+
+// Unmarshal Decodes encrypted data, e.g. produced by EncryptCKKS or Marshal, into a CKKSCiphertext.
+// Returns envelope errors if data doesn't belong to ctx scheme, params or key
+func (ctx *Context) Unmarshal(encryptedData []byte) (*CKKSCiphertext, error) {
+	paramsHash, err := ctx.paramsHash()
+	if err != nil {
+		return nil, err
+	}
+	openEnvelope := envelope.Open
+	if ctx.AcceptLegacyCiphertexts {
+		openEnvelope = envelope.OpenLegacy
+	}
+	e, err := openEnvelope(encryptedData, envelope.SchemeCKKS, paramsHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.checkKeyID(e.KeyID); err != nil {
+		return nil, err
+	}
+
+	ciphertext := ckks.NewCiphertext(ctx.Params, 1, ctx.Params.MaxLevel(), ctx.Params.DefaultScale())
+	err = ciphertext.UnmarshalBinary(e.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.NewCKKSCiphertext(ciphertext, e.KeyID), nil
+}
+
+// UnmarshalArray Decodes every element of encryptedDataArray with Unmarshal
+func (ctx *Context) UnmarshalArray(encryptedDataArray [][]byte) (CKKSCiphertextArray, error) {
+	array := make(CKKSCiphertextArray, len(encryptedDataArray))
+	for i, encryptedData := range encryptedDataArray {
+		var err error
+		array[i], err = ctx.Unmarshal(encryptedData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return array, nil
+}
+
+// Marshal Encodes ct into []byte of encrypted data, which can be stored, sent to
+// other parties or decrypted by the server
+func (ct *CKKSCiphertext) Marshal() ([]byte, error) {
+	paramsHash, err := ct.ctx.paramsHash()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ct.Ciphertext.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Wrap(envelope.SchemeCKKS, paramsHash, ct.KeyID, data)
+}
+
+// Marshal Encodes every element of array with CKKSCiphertext.Marshal
+func (array CKKSCiphertextArray) Marshal() ([][]byte, error) {
+	encryptedDataArray := make([][]byte, len(array))
+	for i, ct := range array {
+		var err error
+		encryptedDataArray[i], err = ct.Marshal()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return encryptedDataArray, nil
+}
+
+// CopyNew Returns a deep copy of ct
+func (ct *CKKSCiphertext) CopyNew() *CKKSCiphertext {
+	return ct.derive(ct.Ciphertext.CopyNew())
+}
+
+// Level Returns the number of rescales ct can still go through. Mult, Pow2 and multiplications
+// by non-integer constants consume a level each
+func (ct *CKKSCiphertext) Level() int {
+	return ct.Ciphertext.Level()
+}
+
+// Scale Returns the scale of ct, which is kept close to the default scale of Context params
+// by rescaling after every multiplication
+func (ct *CKKSCiphertext) Scale() float64 {
+	return ct.Ciphertext.Scale
+}
+
+// derive wraps ciphertext computed from ct into a CKKSCiphertext with the same key and Context
+func (ct *CKKSCiphertext) derive(ciphertext *ckks.Ciphertext) *CKKSCiphertext {
+	return &CKKSCiphertext{Ciphertext: ciphertext, KeyID: ct.KeyID, ctx: ct.ctx}
+}
+
+// checkKeys returns envelope.KeyMismatchError if any of others was encrypted with another key than ct
+func (ct *CKKSCiphertext) checkKeys(others ...*CKKSCiphertext) error {
+	for _, other := range others {
+		if other.KeyID != ct.KeyID {
+			return &envelope.KeyMismatchError{Expected: ct.KeyID, Actual: other.KeyID}
+		}
+	}
+	return nil
+}
+
+// rescale divides ciphertext in place by moduli of the chain until its scale is back
+// close to the default scale, e.g. after a multiplication squared it
+func (ctx *Context) rescale(ciphertext *ckks.Ciphertext) error {
+	if ciphertext.Scale < 2*ctx.Params.DefaultScale() {
+		return nil
+	}
+	if ciphertext.Level() == 0 {
+		return errNoLevelsLeft
+	}
+	return ctx.Evaluator.Rescale(ciphertext, ctx.Params.DefaultScale(), ciphertext)
+}
+
+// align returns op0 and op1 brought to the same scale, so that they can be added.
+// Scales of a fresh and a rescaled ciphertext differ slightly, and adding them as is
+// loses precision. The operand at the higher level is dropped to one level above the
+// other one, multiplied by the ratio of scales and rescaled, which costs no level the
+// sum wouldn't lose anyway. Operands at the same level have no level to spare, so the
+// aligned operand is one level below the other one and the sum loses a level
+func (ctx *Context) align(op0, op1 *ckks.Ciphertext) (*ckks.Ciphertext, *ckks.Ciphertext, error) {
+	if op0.Scale == op1.Scale {
+		return op0, op1, nil
+	}
+	if op0.Level() < op1.Level() {
+		aligned1, aligned0, err := ctx.align(op1, op0)
+		return aligned0, aligned1, err
+	}
+	if op0.Level() == 0 {
+		return nil, nil, errNoLevelsLeft
+	}
+
+	aligned := ctx.Evaluator.DropLevelNew(op0, max(op0.Level()-op1.Level()-1, 0))
+	ctx.Evaluator.MultByConst(aligned, op1.Scale/op0.Scale, aligned)
+	if err := ctx.Evaluator.Rescale(aligned, ctx.Params.DefaultScale(), aligned); err != nil {
+		return nil, nil, err
+	}
+	aligned.Scale = op1.Scale
+	return aligned, op1, nil
+}
@@ -1,8 +1,8 @@
 package ckksMath
 
 import (
+	"errors"
 	"github.com/ldsec/lattigo/v2/ckks"
-	"github.com/ldsec/lattigo/v2/rlwe"
 	"log"
 )
 
@@ -10,139 +10,261 @@ type ConstOperation func([]byte, float64) ([]byte, error)
 type Operation1 func([]byte) ([]byte, error)
 type Operation2 func([]byte, []byte) ([]byte, error)
 
-var CkksParams ckks.Parameters
-var CkksEvaluator ckks.Evaluator
-var CkksEvalkey rlwe.EvaluationKey
+var errNoRelinearizationKey = errors.New("evaluation key has no relinearization key")
+var errNoLevelsLeft = errors.New("ciphertext has no levels left for rescaling, use fewer multiplications")
 
-// unmarshallIntoNewCiphertext returns a new ckks.Ciphertext containing
-// unmarshalled number from encryptedData
-func unmarshallIntoNewCiphertext(encryptedData []byte) (*ckks.Ciphertext, error) {
-	ciphertext := ckks.NewCiphertext(CkksParams, 1, CkksParams.MaxLevel(), CkksParams.DefaultScale())
-	err := ciphertext.UnmarshalBinary(encryptedData)
-	if err != nil {
-		return nil, err
-	}
-	return ciphertext, nil
-}
-
-// makeZeroCiphertext Takes any encrypted data and subtracts it from itself
-// making a *ckks.Ciphertext containing 0 when decrypted
-func makeZeroCiphertext(someEncryptedData []byte) (*ckks.Ciphertext, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(someEncryptedData)
+// AddConst Adds a float64 addValue to encrypted data, producing []byte of encrypted data
+// containing a sum of encryptedData data and addValue when decrypted
+func (ctx *Context) AddConst(encryptedData []byte, addValue float64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
 	if err != nil {
 		return nil, err
 	}
-	return CkksEvaluator.SubNew(ciphertext, ciphertext), nil
-}
 
-// AddConst Adds a float64 addValue to encrypted data, producing []byte of encrypted data
-// containing a sum of encryptedData data and addValue when decrypted
-func AddConst(encryptedData []byte, addValue float64) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+	result, err := ciphertext.AddConst(addValue)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("CKKS: AddConst success")
-	return CkksEvaluator.AddConstNew(ciphertext, addValue).MarshalBinary()
+	return result.Marshal()
 }
 
 // SubtractConst Subtracts a float64 subValue from encrypted data, producing []byte of encrypted data
 // containing a difference of encryptedData data and subValue when decrypted
-func SubtractConst(encryptedData []byte, subValue float64) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+func (ctx *Context) SubtractConst(encryptedData []byte, subValue float64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.SubtractConst(subValue)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("CKKS: SubtractConst success")
-	return CkksEvaluator.AddConstNew(ciphertext, -subValue).MarshalBinary()
+	return result.Marshal()
 }
 
 // MultByConst Multiplies encryptedData by float64 multValue, producing []byte of encrypted data
 // containing a product of encryptedData and multValue when decrypted
-func MultByConst(encryptedData []byte, multValue float64) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+func (ctx *Context) MultByConst(encryptedData []byte, multValue float64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.MultByConst(multValue)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("CKKS: MultByConst success")
-	return CkksEvaluator.MultByConstNew(ciphertext, multValue).MarshalBinary()
+	return result.Marshal()
 }
 
 // DivByConst Divides encryptedDataDividend by float64 encryptedDataDivisor, producing []byte of
 // encrypted data containing a quotient of encryptedDataDividend and encryptedDataDivisor
 // when decrypted
-func DivByConst(encryptedDataDividend []byte, encryptedDataDivisor float64) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedDataDividend)
+func (ctx *Context) DivByConst(encryptedDataDividend []byte, encryptedDataDivisor float64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedDataDividend)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.DivByConst(encryptedDataDivisor)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("CKKS: DivByConst success")
-	return CkksEvaluator.MultByConstNew(ciphertext, 1.0/encryptedDataDivisor).MarshalBinary()
+	return result.Marshal()
 }
 
 // Sum Adds encryptedData to encryptedData2, producing []byte of encrypted data
 // containing a sum of encryptedData data and encryptedData2 when decrypted
-func Sum(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+func (ctx *Context) Sum(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext2, err := ctx.Unmarshal(encryptedData2)
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertext2, err := unmarshallIntoNewCiphertext(encryptedData2)
+	result, err := ciphertext.Sum(ciphertext2)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("CKKS: Sum success")
-	return CkksEvaluator.AddNew(ciphertext, ciphertext2).MarshalBinary()
+	return result.Marshal()
 }
 
 // Subtract Subtracts encryptedData2 from encryptedData, producing []byte of encrypted data
 // containing a difference of encryptedData data and encryptedData2 when decrypted
-func Subtract(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+func (ctx *Context) Subtract(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertext2, err := unmarshallIntoNewCiphertext(encryptedData2)
+	ciphertext2, err := ctx.Unmarshal(encryptedData2)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.Subtract(ciphertext2)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("CKKS: Subtract success")
-	return CkksEvaluator.SubNew(ciphertext, ciphertext2).MarshalBinary()
+	return result.Marshal()
 }
 
 // Mult Multiplies encryptedData by encryptedData2, producing []byte of encrypted data
 // containing a product of encryptedData and encryptedData2 when decrypted
-func Mult(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+func (ctx *Context) Mult(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertext2, err := unmarshallIntoNewCiphertext(encryptedData2)
+	ciphertext2, err := ctx.Unmarshal(encryptedData2)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.Mult(ciphertext2)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("CKKS: Mult success")
-	return CkksEvaluator.MulNew(ciphertext, ciphertext2).MarshalBinary()
+	return result.Marshal()
 }
 
 // Pow2 raises encryptedData to the power of 2 by multiplying it to itself, producing []byte of
 // encrypted data containing a power of 2 of encryptedData when decrypted
-func Pow2(encryptedData []byte) ([]byte, error) {
-	ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
+func (ctx *Context) Pow2(encryptedData []byte) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.Pow2()
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("CKKS: Pow2 success")
-	return CkksEvaluator.MulNew(ciphertext, ciphertext).MarshalBinary()
+	return result.Marshal()
+}
+
+// AddConst Returns a new CKKSCiphertext containing a sum of ct and addValue when decrypted
+func (ct *CKKSCiphertext) AddConst(addValue float64) (*CKKSCiphertext, error) {
+	return ct.derive(ct.ctx.Evaluator.AddConstNew(ct.Ciphertext, addValue)), nil
+}
+
+// SubtractConst Returns a new CKKSCiphertext containing a difference of ct and subValue when decrypted
+func (ct *CKKSCiphertext) SubtractConst(subValue float64) (*CKKSCiphertext, error) {
+	return ct.derive(ct.ctx.Evaluator.AddConstNew(ct.Ciphertext, -subValue)), nil
+}
+
+// MultByConst Returns a new CKKSCiphertext containing a product of ct and multValue when decrypted.
+// Multiplying by a non-integer consumes a level
+func (ct *CKKSCiphertext) MultByConst(multValue float64) (*CKKSCiphertext, error) {
+	product := ct.ctx.Evaluator.MultByConstNew(ct.Ciphertext, multValue)
+	if err := ct.ctx.rescale(product); err != nil {
+		return nil, err
+	}
+	return ct.derive(product), nil
+}
+
+// DivByConst Returns a new CKKSCiphertext containing a quotient of ct and divisor when decrypted.
+// Consumes a level unless 1/divisor is an integer
+func (ct *CKKSCiphertext) DivByConst(divisor float64) (*CKKSCiphertext, error) {
+	return ct.MultByConst(1.0 / divisor)
+}
+
+// Sum Returns a new CKKSCiphertext containing a sum of ct and other when decrypted
+func (ct *CKKSCiphertext) Sum(other *CKKSCiphertext) (*CKKSCiphertext, error) {
+	if err := ct.checkKeys(other); err != nil {
+		return nil, err
+	}
+	op0, op1, err := ct.ctx.align(ct.Ciphertext, other.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return ct.derive(ct.ctx.Evaluator.AddNew(op0, op1)), nil
+}
+
+// Subtract Returns a new CKKSCiphertext containing a difference of ct and other when decrypted
+func (ct *CKKSCiphertext) Subtract(other *CKKSCiphertext) (*CKKSCiphertext, error) {
+	if err := ct.checkKeys(other); err != nil {
+		return nil, err
+	}
+	op0, op1, err := ct.ctx.align(ct.Ciphertext, other.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return ct.derive(ct.ctx.Evaluator.SubNew(op0, op1)), nil
+}
+
+// Mult Returns a new CKKSCiphertext containing a product of ct and other when decrypted.
+// The product is relinearized, unless Context.LazyRelinearization is set, and rescaled,
+// consuming a level. Operands of degree 2 are relinearized first
+func (ct *CKKSCiphertext) Mult(other *CKKSCiphertext) (*CKKSCiphertext, error) {
+	if err := ct.checkKeys(other); err != nil {
+		return nil, err
+	}
+
+	op0, err := ct.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+	op1, err := other.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+
+	var product *ckks.Ciphertext
+	if ct.ctx.LazyRelinearization {
+		product = ct.ctx.Evaluator.MulNew(op0.Ciphertext, op1.Ciphertext)
+	} else {
+		if ct.ctx.EvalKey.Rlk == nil {
+			return nil, errNoRelinearizationKey
+		}
+		product = ct.ctx.Evaluator.MulRelinNew(op0.Ciphertext, op1.Ciphertext)
+	}
+
+	if err := ct.ctx.rescale(product); err != nil {
+		return nil, err
+	}
+	return ct.derive(product), nil
+}
+
+// Pow2 Returns a new CKKSCiphertext containing a power of 2 of ct when decrypted,
+// relinearized and rescaled the same way as Mult
+func (ct *CKKSCiphertext) Pow2() (*CKKSCiphertext, error) {
+	return ct.Mult(ct)
+}
+
+// Relinearize Returns a new CKKSCiphertext of degree 1 containing the same value as ct
+// of degree 2, e.g. a result of Mult with Context.LazyRelinearization set.
+// Ciphertexts of degree 1 are returned as is. Requires the relinearization key
+func (ct *CKKSCiphertext) Relinearize() (*CKKSCiphertext, error) {
+	if ct.Ciphertext.Degree() < 2 {
+		return ct, nil
+	}
+	if ct.ctx.EvalKey.Rlk == nil {
+		return nil, errNoRelinearizationKey
+	}
+	result := ct.CopyNew()
+	ct.ctx.Evaluator.Relinearize(result.Ciphertext, result.Ciphertext)
+	return result, nil
 }
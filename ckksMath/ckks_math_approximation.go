@@ -0,0 +1,270 @@
+package ckksMath
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/bits"
+)
+
+type IntervalOperation func([]byte, float64, float64) ([]byte, error)
+
+// DefaultMaxApproximationError Maximum absolute error of approximations used by Sigmoid, Exp,
+// Log, Sqrt, InvSqrt and Inverse of Context, not counting the noise of CKKS itself, which
+// is around 1e-4 for values of order 1
+const DefaultMaxApproximationError = 1e-3
+
+// approximationDegrees degrees tried by Approximate, from the cheapest one. Each next degree
+// costs one more level, 63 leaves 2 of 9 levels of default parameters
+var approximationDegrees = []int{3, 7, 15, 31, 63}
+
+// approximationSamples number of points of the interval the approximation error is measured in
+const approximationSamples = 4096
+
+// Approximation Polynomial approximating a function over interval [A, B], along with
+// the maximum absolute error of the approximation over the interval
+type Approximation struct {
+	Polynomial *Polynomial
+	A          float64
+	B          float64
+	MaxError   float64
+}
+
+// Approximate Finds the polynomial of the lowest degree approximating f over [a, b] with
+// absolute error not exceeding maxError, returning an error if there is none of degree up to 63.
+// Outside of [a, b] the approximation is not valid
+func Approximate(f func(float64) float64, a float64, b float64, maxError float64) (*Approximation, error) {
+	return approximate(f, a, b, maxError, approximationDegrees[len(approximationDegrees)-1])
+}
+
+// approximate is Approximate trying degrees up to maxDegree only. If none of them reaches
+// maxError, the returned error names the error of maxDegree
+func approximate(f func(float64) float64, a float64, b float64, maxError float64, maxDegree int) (*Approximation, error) {
+	if !(a < b) || math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return nil, fmt.Errorf("invalid interval [%g, %g]", a, b)
+	}
+
+	var best *Approximation
+	for _, degree := range approximationDegrees {
+		if degree > maxDegree {
+			break
+		}
+		polynomial := ApproximatePolynomial(f, a, b, degree)
+		approximation := &Approximation{Polynomial: polynomial, A: a, B: b, MaxError: approximationError(f, polynomial, a, b)}
+		if approximation.MaxError <= maxError {
+			return approximation, nil
+		}
+		if best == nil || approximation.MaxError < best.MaxError {
+			best = approximation
+		}
+	}
+	if maxDegree < approximationDegrees[len(approximationDegrees)-1] {
+		return nil, fmt.Errorf("cannot approximate function over [%g, %g] with error below %g, only degree %d fits, reaching error %g, narrow the interval",
+			a, b, maxError, best.Polynomial.Degree(), best.MaxError)
+	}
+	return nil, fmt.Errorf("cannot approximate function over [%g, %g] with error below %g, best error is %g, narrow the interval", a, b, maxError, best.MaxError)
+}
+
+// maxApproximationDegree returns the highest degree Approximate tries that can be evaluated
+// within levels, or 0 if there is none
+func maxApproximationDegree(levels int) int {
+	maxDegree := 0
+	for _, degree := range approximationDegrees {
+		// depth of a Chebyshev polynomial of degree, see Polynomial.Depth
+		if bits.Len(uint(degree))+1 <= levels {
+			maxDegree = degree
+		}
+	}
+	return maxDegree
+}
+
+// scaled returns approximation of factor * f, where approximation approximates f
+func (approximation *Approximation) scaled(factor float64) *Approximation {
+	polynomial := *approximation.Polynomial
+	polynomial.Coeffs = make([]float64, len(approximation.Polynomial.Coeffs))
+	for i, c := range approximation.Polynomial.Coeffs {
+		polynomial.Coeffs[i] = c * factor
+	}
+	return &Approximation{Polynomial: &polynomial, A: approximation.A, B: approximation.B, MaxError: approximation.MaxError * math.Abs(factor)}
+}
+
+// approximationError returns the maximum absolute difference of f and polynomial over [a, b]
+func approximationError(f func(float64) float64, polynomial *Polynomial, a float64, b float64) float64 {
+	var maxError float64
+	for i := 0; i <= approximationSamples; i++ {
+		x := a + (b-a)*float64(i)/approximationSamples
+		maxError = math.Max(maxError, math.Abs(f(x)-polynomial.Evaluate(x)))
+	}
+	return maxError
+}
+
+// approximatedFunction Function approximated by Sigmoid, Exp, Log, Sqrt, InvSqrt and Inverse
+type approximatedFunction struct {
+	name string
+	f    func(float64) float64
+	// defined returns false if f isn't defined over all of [a, b]
+	defined func(a float64, b float64) bool
+}
+
+var (
+	sigmoidFunction = approximatedFunction{"sigmoid", func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }, nil}
+	expFunction     = approximatedFunction{"exponent", math.Exp, nil}
+	logFunction     = approximatedFunction{"logarithm", math.Log, func(a float64, b float64) bool { return a > 0 }}
+	sqrtFunction    = approximatedFunction{"square root", math.Sqrt, func(a float64, b float64) bool { return a >= 0 }}
+	invSqrtFunction = approximatedFunction{"inverse square root", func(x float64) float64 { return 1 / math.Sqrt(x) }, func(a float64, b float64) bool { return a > 0 }}
+	inverseFunction = approximatedFunction{"inverse", func(x float64) float64 { return 1 / x }, func(a float64, b float64) bool { return a > 0 || b < 0 }}
+)
+
+// approximation returns the approximation of fn over [a, b] of degree up to maxDegree, see Approximate
+func (fn approximatedFunction) approximation(a float64, b float64, maxError float64, maxDegree int) (*Approximation, error) {
+	if fn.defined != nil && !fn.defined(a, b) {
+		return nil, fmt.Errorf("%s is not defined over [%g, %g]", fn.name, a, b)
+	}
+
+	approximation, err := approximate(fn.f, a, b, maxError, maxDegree)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fn.name, err)
+	}
+	return approximation, nil
+}
+
+// SigmoidApproximation Approximation of 1 / (1 + e^-x) over [a, b]. Error over [-8, 8] is below 1e-3
+func SigmoidApproximation(a float64, b float64, maxError float64) (*Approximation, error) {
+	return sigmoidFunction.approximation(a, b, maxError, approximationDegrees[len(approximationDegrees)-1])
+}
+
+// ExpApproximation Approximation of e^x over [a, b]. Error over [-4, 4] is below 1e-3
+func ExpApproximation(a float64, b float64, maxError float64) (*Approximation, error) {
+	return expFunction.approximation(a, b, maxError, approximationDegrees[len(approximationDegrees)-1])
+}
+
+// LogApproximation Approximation of natural logarithm over [a, b], a > 0. Error over [0.1, 10] is below 1e-3
+func LogApproximation(a float64, b float64, maxError float64) (*Approximation, error) {
+	return logFunction.approximation(a, b, maxError, approximationDegrees[len(approximationDegrees)-1])
+}
+
+// SqrtApproximation Approximation of square root over [a, b], a >= 0. Error over [0.1, 100] is below 1e-3
+func SqrtApproximation(a float64, b float64, maxError float64) (*Approximation, error) {
+	return sqrtFunction.approximation(a, b, maxError, approximationDegrees[len(approximationDegrees)-1])
+}
+
+// InvSqrtApproximation Approximation of 1 / sqrt(x) over [a, b], a > 0. Error over [0.1, 10] is below 1e-3
+func InvSqrtApproximation(a float64, b float64, maxError float64) (*Approximation, error) {
+	return invSqrtFunction.approximation(a, b, maxError, approximationDegrees[len(approximationDegrees)-1])
+}
+
+// InverseApproximation Approximation of 1 / x over [a, b] not containing 0. Error over [0.1, 10] is below 1e-3.
+// Noise of encrypted x is amplified by 1 / x^2 though, so with default parameters Inverse of
+// encrypted values over [0.1, 10] is off by up to 5e-3, the most near 0.1
+func InverseApproximation(a float64, b float64, maxError float64) (*Approximation, error) {
+	return inverseFunction.approximation(a, b, maxError, approximationDegrees[len(approximationDegrees)-1])
+}
+
+// Sigmoid Computes 1 / (1 + e^-x) of encryptedData with values within [a, b]
+// and DefaultMaxApproximationError
+func (ctx *Context) Sigmoid(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return ctx.approximate(encryptedData, "Sigmoid", sigmoidFunction, a, b)
+}
+
+// Exp Computes e^x of encryptedData with values within [a, b] and DefaultMaxApproximationError
+func (ctx *Context) Exp(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return ctx.approximate(encryptedData, "Exp", expFunction, a, b)
+}
+
+// Log Computes natural logarithm of encryptedData with values within [a, b], a > 0,
+// and DefaultMaxApproximationError
+func (ctx *Context) Log(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return ctx.approximate(encryptedData, "Log", logFunction, a, b)
+}
+
+// Sqrt Computes square root of encryptedData with values within [a, b], a >= 0,
+// and DefaultMaxApproximationError
+func (ctx *Context) Sqrt(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return ctx.approximate(encryptedData, "Sqrt", sqrtFunction, a, b)
+}
+
+// InvSqrt Computes 1 / sqrt(x) of encryptedData with values within [a, b], a > 0,
+// and DefaultMaxApproximationError
+func (ctx *Context) InvSqrt(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return ctx.approximate(encryptedData, "InvSqrt", invSqrtFunction, a, b)
+}
+
+// Inverse Computes 1 / x of encryptedData with values within [a, b] not containing 0,
+// and DefaultMaxApproximationError. Values close to 0 amplify noise, see InverseApproximation
+func (ctx *Context) Inverse(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return ctx.approximate(encryptedData, "Inverse", inverseFunction, a, b)
+}
+
+// approximate evaluates the approximation of fn on encryptedData, see CKKSCiphertext.approximate
+func (ctx *Context) approximate(encryptedData []byte, name string, fn approximatedFunction, a float64, b float64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.approximate(fn, a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("CKKS: %s success", name)
+	return result.Marshal()
+}
+
+// Approximate Returns a new CKKSCiphertext containing the approximated function of ct when decrypted.
+// Values of ct must lie within the interval of approximation
+func (ct *CKKSCiphertext) Approximate(approximation *Approximation) (*CKKSCiphertext, error) {
+	return ct.EvaluatePolynomial(approximation.Polynomial)
+}
+
+// Sigmoid Returns a new CKKSCiphertext containing 1 / (1 + e^-ct) for ct within [a, b] when decrypted
+func (ct *CKKSCiphertext) Sigmoid(a float64, b float64) (*CKKSCiphertext, error) {
+	return ct.approximate(sigmoidFunction, a, b)
+}
+
+// Exp Returns a new CKKSCiphertext containing e^ct for ct within [a, b] when decrypted
+func (ct *CKKSCiphertext) Exp(a float64, b float64) (*CKKSCiphertext, error) {
+	return ct.approximate(expFunction, a, b)
+}
+
+// Log Returns a new CKKSCiphertext containing natural logarithm of ct within [a, b] when decrypted
+func (ct *CKKSCiphertext) Log(a float64, b float64) (*CKKSCiphertext, error) {
+	return ct.approximate(logFunction, a, b)
+}
+
+// Sqrt Returns a new CKKSCiphertext containing square root of ct within [a, b] when decrypted
+func (ct *CKKSCiphertext) Sqrt(a float64, b float64) (*CKKSCiphertext, error) {
+	return ct.approximate(sqrtFunction, a, b)
+}
+
+// InvSqrt Returns a new CKKSCiphertext containing 1 / sqrt(ct) for ct within [a, b] when decrypted
+func (ct *CKKSCiphertext) InvSqrt(a float64, b float64) (*CKKSCiphertext, error) {
+	return ct.approximate(invSqrtFunction, a, b)
+}
+
+// Inverse Returns a new CKKSCiphertext containing 1 / ct for ct within [a, b] when decrypted
+func (ct *CKKSCiphertext) Inverse(a float64, b float64) (*CKKSCiphertext, error) {
+	return ct.approximate(inverseFunction, a, b)
+}
+
+// approximate evaluates the approximation of fn with DefaultMaxApproximationError on ct
+func (ct *CKKSCiphertext) approximate(fn approximatedFunction, a float64, b float64) (*CKKSCiphertext, error) {
+	approximation, err := ct.approximation(fn, a, b, 0)
+	if err != nil {
+		return nil, err
+	}
+	return ct.Approximate(approximation)
+}
+
+// approximation returns the approximation of fn over [a, b] with DefaultMaxApproximationError
+// of a degree ct can evaluate, leaving levelsAfter levels for the rest of the computation.
+// If the levels left don't allow such a degree, the error names the error that degree reaches
+func (ct *CKKSCiphertext) approximation(fn approximatedFunction, a float64, b float64, levelsAfter int) (*Approximation, error) {
+	levels := ct.Level() - levelsAfter
+	maxDegree := maxApproximationDegree(levels)
+	if maxDegree == 0 {
+		return nil, fmt.Errorf("%s: approximation needs at least %d levels, but %d are left", fn.name,
+			bits.Len(uint(approximationDegrees[0]))+1, max(levels, 0))
+	}
+	return fn.approximation(a, b, DefaultMaxApproximationError, maxDegree)
+}
@@ -0,0 +1,362 @@
+package ckksMath
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/bits"
+)
+
+type BoundOperation func([]byte, float64) ([]byte, error)
+type BoundOperation2 func([]byte, []byte, float64) ([]byte, error)
+type ArrayBoundOperation func([][]byte, float64) ([]byte, error)
+
+// Sign is approximated by a composition of stages of odd polynomials of degree 7 from
+// "Efficient Homomorphic Comparison Methods with Optimal Complexity" by Cheon et al.:
+// g3 pushes values away from 0 and the last stage f3 pulls them to -1 or 1.
+// Each stage consumes 3 levels, and scaling x into [-1, 1] by the declared bound one more, so
+// the number of stages depends on levels left: fresh ciphertexts of default parameters get 2.
+// Errors of sign(x) and of max(x, 0) relative to the bound are:
+//
+//	stages | sign error for |x| >= 0.05 | sign error for |x| >= 0.2 | max error
+//	1      | 0.89                       | 0.58                      | 0.061
+//	2      | 0.54                       | 0.013                     | 0.014
+//	3      | 0.013                      | 0.013                     | 0.0051
+//
+// SignResolution and MaxMinError report the precision comparisons of a ciphertext get
+var signStageG3 = []float64{0, 4589.0 / 1024, 0, -16577.0 / 1024, 0, 25614.0 / 1024, 0, -12860.0 / 1024}
+var signStageF3 = []float64{0, 35.0 / 16, 0, -35.0 / 16, 0, 21.0 / 16, 0, -5.0 / 16}
+
+// maxSignStages maximum number of stages, more don't improve precision of the last stage
+const maxSignStages = 3
+
+// signResolutions smallest |x| for x within [-1, 1] whose sign 1, 2 or 3 stages approximate
+// with error below 0.05
+var signResolutions = []float64{0.65, 0.16, 0.04}
+
+// maxMinErrors errors of max(x, 0) relative to the bound of 1, 2 or 3 stages, see the table above
+var maxMinErrors = []float64{0.061, 0.014, 0.0051}
+
+// signStageDepth levels consumed by one stage
+const signStageDepth = 3
+
+var errNoLevelsForComparison = errors.New("not enough levels left for comparison, compare ciphertexts with at least 4 levels left")
+
+// signStages returns stages approximating outputScale * sign(x) + offset for x within [-1, 1],
+// where scaling of the output is folded into coefficients of the last stage
+func signStages(stages int, outputScale float64, offset float64) []*Polynomial {
+	polynomials := make([]*Polynomial, stages)
+	for i := range polynomials {
+		if i < stages-1 {
+			polynomials[i] = NewPolynomial(signStageG3)
+		} else {
+			polynomials[i] = NewPolynomial(signStageF3)
+		}
+	}
+
+	last := polynomials[stages-1]
+	for i := range last.Coeffs {
+		last.Coeffs[i] *= outputScale
+	}
+	last.Coeffs[0] += offset
+	return polynomials
+}
+
+// availableSignStages returns how many stages fit into levels
+func availableSignStages(levels int) int {
+	return min(maxSignStages, levels/signStageDepth)
+}
+
+// tournamentStages returns the number of rounds comparing elements normalized ciphertexts
+// with levels left takes, and how many stages every round gets
+func tournamentStages(levels int, elements int) (int, int) {
+	// every round consumes levels of sign stages and one more multiplication
+	rounds := bits.Len(uint(elements - 1))
+	return rounds, availableSignStages(levels/rounds - 1)
+}
+
+// SignResolution Returns the smallest absolute value relative to bound whose sign Sign and Compare
+// of ciphertexts with levels left approximate with error below 0.05, e.g. 0.16 for fresh ciphertexts
+// of default parameters. Values closer to 0 give values in between. Returns an error if comparison
+// doesn't fit into levels
+func SignResolution(levels int) (float64, error) {
+	// scaling by bound consumes a level
+	stages := availableSignStages(levels - 1)
+	if stages < 1 {
+		return 0, errNoLevelsForComparison
+	}
+	return signResolutions[stages-1], nil
+}
+
+// MaxMinError Returns the maximum error relative to bound of Max and Min of two ciphertexts, or
+// of ArrayMax and ArrayMin of elements ciphertexts, with levels left, e.g. 0.014 for two fresh
+// ciphertexts of default parameters. Returns an error if so many elements can't be compared
+func MaxMinError(levels int, elements int) (float64, error) {
+	if elements < 2 {
+		return 0, nil
+	}
+	// scaling by bound consumes a level
+	rounds, stages := tournamentStages(levels-1, elements)
+	if stages < 1 {
+		return 0, fmt.Errorf("comparing %d elements needs %d levels, only %d left", elements, rounds*(signStageDepth+1)+1, levels)
+	}
+	return float64(rounds) * maxMinErrors[stages-1], nil
+}
+
+// normalize returns ct / bound, scaling values not exceeding bound into [-1, 1]. Powers of
+// raw values would lose too much precision to noise
+func (ct *CKKSCiphertext) normalize(bound float64) (*CKKSCiphertext, error) {
+	if !(bound > 0) || math.IsInf(bound, 0) {
+		return nil, fmt.Errorf("bound must be positive, got %g", bound)
+	}
+	return ct.MultByConst(1 / bound)
+}
+
+// Sign Computes approximate sign of encryptedData with absolute values not exceeding bound:
+// -1, 0 or 1 when decrypted. Values close to 0 compared to bound give values in between,
+// see SignResolution
+func (ctx *Context) Sign(encryptedData []byte, bound float64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.Sign(bound)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: Sign success")
+	return result.Marshal()
+}
+
+// Compare Computes approximate comparison of encryptedData and encryptedData2 whose difference
+// doesn't exceed bound in absolute value: 1 if encryptedData is greater, 0 if it is less and
+// 0.5 if they are equal when decrypted. Small differences give values in between, see SignResolution
+func (ctx *Context) Compare(encryptedData []byte, encryptedData2 []byte, bound float64) ([]byte, error) {
+	return ctx.compare(encryptedData, encryptedData2, bound, "Compare", (*CKKSCiphertext).Compare)
+}
+
+// Max Computes approximate maximum of encryptedData and encryptedData2 whose difference
+// doesn't exceed bound in absolute value, see MaxMinError
+func (ctx *Context) Max(encryptedData []byte, encryptedData2 []byte, bound float64) ([]byte, error) {
+	return ctx.compare(encryptedData, encryptedData2, bound, "Max", (*CKKSCiphertext).Max)
+}
+
+// Min Computes approximate minimum of encryptedData and encryptedData2 whose difference
+// doesn't exceed bound in absolute value
+func (ctx *Context) Min(encryptedData []byte, encryptedData2 []byte, bound float64) ([]byte, error) {
+	return ctx.compare(encryptedData, encryptedData2, bound, "Min", (*CKKSCiphertext).Min)
+}
+
+// compare unmarshals operands of a comparison operation, performs it and marshals the result
+func (ctx *Context) compare(encryptedData []byte, encryptedData2 []byte, bound float64, name string,
+	operation func(*CKKSCiphertext, *CKKSCiphertext, float64) (*CKKSCiphertext, error)) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext2, err := ctx.Unmarshal(encryptedData2)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := operation(ciphertext, ciphertext2, bound)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("CKKS: %s success", name)
+	return result.Marshal()
+}
+
+// ArrayMax Computes approximate maximum of all elements of passed array in []byte, whose
+// differences don't exceed bound in absolute value. Pairs are compared in ceil(log2(len)) rounds,
+// which share levels of ciphertexts, so with default parameters arrays of at most 4 elements
+// are supported, longer ones return an error, and 3 or 4 elements are compared with the
+// precision of one stage, see MaxMinError
+func (ctx *Context) ArrayMax(encryptedDataArray [][]byte, bound float64) ([]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := array.Max(bound)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: ArrayMax success")
+	return result.Marshal()
+}
+
+// ArrayMin Computes approximate minimum of all elements of passed array in []byte, see ArrayMax
+func (ctx *Context) ArrayMin(encryptedDataArray [][]byte, bound float64) ([]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := array.Min(bound)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: ArrayMin success")
+	return result.Marshal()
+}
+
+// Sign Returns a new CKKSCiphertext containing approximate sign of ct with absolute values
+// not exceeding bound when decrypted
+func (ct *CKKSCiphertext) Sign(bound float64) (*CKKSCiphertext, error) {
+	normalized, err := ct.normalize(bound)
+	if err != nil {
+		return nil, err
+	}
+	return normalized.sign(availableSignStages(normalized.Level()), 1, 0)
+}
+
+// Compare Returns a new CKKSCiphertext containing 1 where ct is greater than other, 0 where
+// it is less and 0.5 where they are equal when decrypted. Difference of ct and other must
+// not exceed bound in absolute value
+func (ct *CKKSCiphertext) Compare(other *CKKSCiphertext, bound float64) (*CKKSCiphertext, error) {
+	difference, err := ct.Subtract(other)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized, err := difference.normalize(bound)
+	if err != nil {
+		return nil, err
+	}
+	return normalized.sign(availableSignStages(normalized.Level()), 0.5, 0.5)
+}
+
+// Max Returns a new CKKSCiphertext containing approximate maximum of ct and other when decrypted.
+// Difference of ct and other must not exceed bound in absolute value
+func (ct *CKKSCiphertext) Max(other *CKKSCiphertext, bound float64) (*CKKSCiphertext, error) {
+	return CKKSCiphertextArray{ct, other}.tournament(bound, 1)
+}
+
+// Min Returns a new CKKSCiphertext containing approximate minimum of ct and other when decrypted.
+// Difference of ct and other must not exceed bound in absolute value
+func (ct *CKKSCiphertext) Min(other *CKKSCiphertext, bound float64) (*CKKSCiphertext, error) {
+	return CKKSCiphertextArray{ct, other}.tournament(bound, -1)
+}
+
+// Max Returns a new CKKSCiphertext containing approximate maximum of all elements of array
+// when decrypted, see Context.ArrayMax
+func (array CKKSCiphertextArray) Max(bound float64) (*CKKSCiphertext, error) {
+	return array.tournament(bound, 1)
+}
+
+// Min Returns a new CKKSCiphertext containing approximate minimum of all elements of array
+// when decrypted, see Context.ArrayMax
+func (array CKKSCiphertextArray) Min(bound float64) (*CKKSCiphertext, error) {
+	return array.tournament(bound, -1)
+}
+
+// sign evaluates outputScale * sign(ct) + offset for ct within [-1, 1] with passed number of stages
+func (ct *CKKSCiphertext) sign(stages int, outputScale float64, offset float64) (*CKKSCiphertext, error) {
+	if stages < 1 {
+		return nil, errNoLevelsForComparison
+	}
+
+	result := ct
+	for _, polynomial := range signStages(stages, outputScale, offset) {
+		var err error
+		result, err = result.EvaluatePolynomial(polynomial)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// maxMin returns outputScale * max(ct, other) for direction 1 and outputScale * min(ct, other)
+// for direction -1 of normalized ct and other, computed as
+// outputScale * ((ct + other) / 2 + direction * (ct - other) * sign(ct - other) / 2)
+func (ct *CKKSCiphertext) maxMin(other *CKKSCiphertext, stages int, direction float64, outputScale float64) (*CKKSCiphertext, error) {
+	difference, err := ct.Subtract(other)
+	if err != nil {
+		return nil, err
+	}
+
+	sign, err := difference.sign(stages, direction*outputScale/2, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	deviation, err := difference.Mult(sign)
+	if err != nil {
+		return nil, err
+	}
+
+	sum, err := ct.Sum(other)
+	if err != nil {
+		return nil, err
+	}
+
+	mean, err := sum.MultByConst(outputScale / 2)
+	if err != nil {
+		return nil, err
+	}
+	return mean.Sum(deviation)
+}
+
+// tournament compares pairs of array elements in rounds until one remains. Elements are
+// normalized once, levels left are split evenly between rounds, and the last round scales
+// its result back by bound
+func (array CKKSCiphertextArray) tournament(bound float64, direction float64) (*CKKSCiphertext, error) {
+	if len(array) == 0 {
+		return nil, errEmptyArray
+	}
+	if err := array[0].checkKeys(array...); err != nil {
+		return nil, err
+	}
+
+	normalized := make(CKKSCiphertextArray, len(array))
+	levelsLeft, level := math.MaxInt, math.MaxInt
+	for i, ct := range array {
+		levelsLeft = min(levelsLeft, ct.Level())
+		var err error
+		normalized[i], err = ct.normalize(bound)
+		if err != nil {
+			return nil, err
+		}
+		level = min(level, normalized[i].Level())
+	}
+
+	if len(array) == 1 {
+		return array[0].CopyNew(), nil
+	}
+
+	rounds, stages := tournamentStages(level, len(array))
+	if stages < 1 {
+		return nil, fmt.Errorf("comparing %d elements needs %d levels, only %d left", len(array), rounds*(signStageDepth+1)+levelsLeft-level, levelsLeft)
+	}
+
+	array = normalized
+	for len(array) > 1 {
+		outputScale := 1.0
+		if len(array) == 2 {
+			outputScale = bound
+		}
+
+		next := make(CKKSCiphertextArray, 0, (len(array)+1)/2)
+		for i := 0; i+1 < len(array); i += 2 {
+			result, err := array[i].maxMin(array[i+1], stages, direction, outputScale)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, result)
+		}
+		if len(array)%2 == 1 {
+			next = append(next, array[len(array)-1])
+		}
+		array = next
+	}
+	return array[0], nil
+}
@@ -2,225 +2,445 @@ package ckksMath
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"math"
 )
 
 type ArrayOperation func([][]byte) ([]byte, error)
 type ArrayOperation2 func([][]byte, [][]byte) ([]byte, error)
 type Operation3 func([]byte, []byte, []byte) ([]byte, error)
 type ArrayOperationWithParamReturningArray func([][]byte, int) ([][]byte, error)
+type ArrayIntervalOperation func([][]byte, float64, float64) ([]byte, error)
+type ArrayIntervalOperation2 func([][]byte, [][]byte, float64, float64) ([]byte, error)
 
-// ArraySum Returns the encrypted sum of all elements of passed array in []byte
-func ArraySum(encryptedDataArray [][]byte) ([]byte, error) {
-	if len(encryptedDataArray) == 0 {
-		return nil, errors.New("cannot use empty array")
-	}
+var errEmptyArray = errors.New("cannot use empty array")
 
-	sumCiphertext, err := makeZeroCiphertext(encryptedDataArray[0])
+// ArraySum Returns the encrypted sum of all elements of passed array in []byte
+func (ctx *Context) ArraySum(encryptedDataArray [][]byte) ([]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, encryptedData := range encryptedDataArray {
-		ciphertext, err := unmarshallIntoNewCiphertext(encryptedData)
-		if err != nil {
-			return nil, err
-		}
-
-		CkksEvaluator.Add(sumCiphertext, ciphertext, sumCiphertext)
+	result, err := array.Sum()
+	if err != nil {
+		return nil, err
 	}
 
 	log.Println("CKKS: ArraySum success")
-	return sumCiphertext.MarshalBinary()
+	return result.Marshal()
 }
 
 // ArrayMean Calculates the encrypted mean of all elements of passed array in []byte
-func ArrayMean(encryptedDataArray [][]byte) ([]byte, error) {
-	sum, err := ArraySum(encryptedDataArray)
+func (ctx *Context) ArrayMean(encryptedDataArray [][]byte) ([]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertext, err := unmarshallIntoNewCiphertext(sum)
+	result, err := array.Mean()
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("CKKS: ArrayMean success")
-	return CkksEvaluator.MultByConstNew(ciphertext, 1.0/float64(len(encryptedDataArray))).MarshalBinary()
+	return result.Marshal()
 }
 
-// MovingAverage Returns an array, containing len(encryptedDataArray) - windowSize elements,
+// MovingAverage Returns an array, containing len(encryptedDataArray) - windowSize + 1 elements,
 // each representing a calculated mean of numbers within a shifting window of size windowSize
-func MovingAverage(encryptedDataArray [][]byte, windowSize int) ([][]byte, error) {
-	movingArrayLen := len(encryptedDataArray) - windowSize + 1
-	r := make([][]byte, movingArrayLen)
-	for i := 0; i < movingArrayLen; i++ {
-		var err error
-		r[i], err = ArrayMean(encryptedDataArray[i : i+windowSize])
-		if err != nil {
-			return nil, err
-		}
+func (ctx *Context) MovingAverage(encryptedDataArray [][]byte, windowSize int) ([][]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := array.MovingAverage(windowSize)
+	if err != nil {
+		return nil, err
 	}
 
 	log.Println("CKKS: MovingAverage success")
-	return r, nil
+	return result.Marshal()
 }
 
 // Variance Calculates variance of a passed array in []bytes
-func Variance(encryptedDataArray [][]byte) ([]byte, error) { //дисперсия
-	if len(encryptedDataArray) == 0 {
-		return nil, errors.New("cannot use empty array")
+func (ctx *Context) Variance(encryptedDataArray [][]byte) ([]byte, error) { //дисперсия
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
+	if err != nil {
+		return nil, err
 	}
 
-	mean, err := ArrayMean(encryptedDataArray)
+	result, err := array.Variance()
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertextSum, err := makeZeroCiphertext(encryptedDataArray[0])
+	log.Println("CKKS: Variance success")
+	return result.Marshal()
+}
+
+// Covariance Calculates covariance coefficient between two encrypted arrays in []byte
+func (ctx *Context) Covariance(encryptedDataArray1 [][]byte, encryptedDataArray2 [][]byte) ([]byte, error) {
+	array1, err := ctx.UnmarshalArray(encryptedDataArray1)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, encryptedData := range encryptedDataArray {
-		sub, err := Subtract(encryptedData, mean)
-		if err != nil {
-			return nil, err
-		}
+	array2, err := ctx.UnmarshalArray(encryptedDataArray2)
+	if err != nil {
+		return nil, err
+	}
 
-		pow, err := Pow2(sub)
-		if err != nil {
-			return nil, err
-		}
+	result, err := array1.Covariance(array2)
+	if err != nil {
+		return nil, err
+	}
 
-		ciphertextPow, err := unmarshallIntoNewCiphertext(pow)
-		if err != nil {
-			return nil, err
-		}
+	log.Println("CKKS: Covariance success")
+	return result.Marshal()
+}
 
-		CkksEvaluator.Relinearize(ciphertextPow, ciphertextPow)
+// StdDev Calculates standard deviation of a passed array in []byte.
+// Variance of the array must lie within [a, b], a >= 0
+func (ctx *Context) StdDev(encryptedDataArray [][]byte, a float64, b float64) ([]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
+	if err != nil {
+		return nil, err
+	}
 
-		CkksEvaluator.Add(ciphertextSum, ciphertextPow, ciphertextSum)
+	result, err := array.StdDev(a, b)
+	if err != nil {
+		return nil, err
 	}
 
-	sumSquaredDiff, err := ciphertextSum.MarshalBinary()
-	result, err := DivByConst(sumSquaredDiff, float64(len(encryptedDataArray)))
+	log.Println("CKKS: StdDev success")
+	return result.Marshal()
+}
+
+// PearsonCorrelation Calculates Pearson correlation coefficient between two encrypted arrays in []byte.
+// Variances of both arrays must lie within [a, b], a > 0
+func (ctx *Context) PearsonCorrelation(encryptedDataArray1 [][]byte, encryptedDataArray2 [][]byte, a float64, b float64) ([]byte, error) {
+	array1, err := ctx.UnmarshalArray(encryptedDataArray1)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("CKKS: Variance success")
-	return result, nil
+	array2, err := ctx.UnmarshalArray(encryptedDataArray2)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := array1.PearsonCorrelation(array2, a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: PearsonCorrelation success")
+	return result.Marshal()
 }
 
-// Covariance Calculates covariance coefficient between two encrypted arrays in []byte
-func Covariance(encryptedDataArray1 [][]byte, encryptedDataArray2 [][]byte) ([]byte, error) {
-	if len(encryptedDataArray1) != len(encryptedDataArray2) {
-		return nil, errors.New("arrays must be of the same length")
+// ZScoreNormalize Returns an array of z-scores (x - mean) / stdDev of every element of passed
+// array in []byte. Variance of the array must lie within [a, b], a > 0
+func (ctx *Context) ZScoreNormalize(encryptedDataArray [][]byte, a float64, b float64) ([][]byte, error) {
+	array, err := ctx.UnmarshalArray(encryptedDataArray)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := array.ZScoreNormalize(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: ZScoreNormalize success")
+	return result.Marshal()
+}
+
+// ArithmeticProgressionElementN Calculates the N element of arithmetic progression in []byte.
+// Requires the first element of progression and the difference between two members of progression
+func (ctx *Context) ArithmeticProgressionElementN(firstMember []byte, dif []byte, n []byte) ([]byte, error) {
+	array, err := ctx.UnmarshalArray([][]byte{firstMember, dif, n})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := array[0].ArithmeticProgressionElementN(array[1], array[2])
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: ArithmeticProgressionElementN success")
+	return result.Marshal()
+}
+
+// ArithmeticProgressionSum Calculates sum of arithmetic progression in []byte.
+// Requires the first element of progression, the difference between two members of progression
+// and number of elements in progression
+func (ctx *Context) ArithmeticProgressionSum(firstMember []byte, dif []byte, numberOfMembers []byte) ([]byte, error) {
+	array, err := ctx.UnmarshalArray([][]byte{firstMember, dif, numberOfMembers})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := array[0].ArithmeticProgressionSum(array[1], array[2])
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: ArithmeticProgressionSum success")
+	return result.Marshal()
+}
+
+// Sum Returns a new CKKSCiphertext containing the sum of all elements of array when decrypted
+func (array CKKSCiphertextArray) Sum() (*CKKSCiphertext, error) {
+	if len(array) == 0 {
+		return nil, errEmptyArray
 	}
-	if len(encryptedDataArray1) == 0 || len(encryptedDataArray2) == 0 {
-		return nil, errors.New("cannot use empty array")
+	if err := array[0].checkKeys(array...); err != nil {
+		return nil, err
 	}
 
-	mean1, err := ArrayMean(encryptedDataArray1)
+	sum := array[0]
+	for _, ct := range array[1:] {
+		var err error
+		sum, err = sum.Sum(ct)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sum.CopyNew(), nil
+}
+
+// Mean Returns a new CKKSCiphertext containing the mean of all elements of array when decrypted
+func (array CKKSCiphertextArray) Mean() (*CKKSCiphertext, error) {
+	sum, err := array.Sum()
 	if err != nil {
 		return nil, err
 	}
+	return sum.DivByConst(float64(len(array)))
+}
+
+// MovingAverage Returns len(array) - windowSize + 1 means of elements within a shifting window
+// of size windowSize
+func (array CKKSCiphertextArray) MovingAverage(windowSize int) (CKKSCiphertextArray, error) {
+	if windowSize < 1 || windowSize > len(array) {
+		return nil, errors.New("window size must be between 1 and array length")
+	}
+
+	result := make(CKKSCiphertextArray, len(array)-windowSize+1)
+	for i := range result {
+		var err error
+		result[i], err = array[i : i+windowSize].Mean()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Variance Returns a new CKKSCiphertext containing the variance of array when decrypted
+func (array CKKSCiphertextArray) Variance() (*CKKSCiphertext, error) {
+	return array.Covariance(array)
+}
+
+// Covariance Returns a new CKKSCiphertext containing the covariance of array and array2 when decrypted
+func (array CKKSCiphertextArray) Covariance(array2 CKKSCiphertextArray) (*CKKSCiphertext, error) {
+	if len(array) != len(array2) {
+		return nil, errors.New("arrays must be of the same length")
+	}
 
-	mean2, err := ArrayMean(encryptedDataArray2)
+	deviations1, err := array.deviations()
 	if err != nil {
 		return nil, err
 	}
 
-	ciphertextSum, err := makeZeroCiphertext(encryptedDataArray1[0])
+	deviations2, err := array2.deviations()
 	if err != nil {
 		return nil, err
 	}
+	return deviations1.covariance(deviations2)
+}
 
-	for i := 0; i < len(encryptedDataArray1); i++ {
-		sub1, err := Subtract(encryptedDataArray1[i], mean1)
+// covariance returns the covariance of arrays whose deviations are passed, see deviations
+func (deviations CKKSCiphertextArray) covariance(deviations2 CKKSCiphertextArray) (*CKKSCiphertext, error) {
+	products := make(CKKSCiphertextArray, len(deviations))
+	for i := range deviations {
+		var err error
+		products[i], err = deviations[i].Mult(deviations2[i])
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		sub2, err := Subtract(encryptedDataArray2[i], mean2)
+	// with lazy relinearization products are summed first and relinearized once
+	sum, err := products.Sum()
+	if err != nil {
+		return nil, err
+	}
+	return sum.Relinearize()
+}
+
+// deviations returns (x - mean) / sqrt(n) for every x of array, so that covariance is the sum
+// of products of deviations. Computed as (n * x - sum) * n^-1.5, which consumes one level,
+// where subtracting the mean would consume one for the mean and one for the final division by n
+func (array CKKSCiphertextArray) deviations() (CKKSCiphertextArray, error) {
+	return array.scaledDeviations(1)
+}
+
+// scaledDeviations returns factor times deviations of array within the same level, see deviations
+func (array CKKSCiphertextArray) scaledDeviations(factor float64) (CKKSCiphertextArray, error) {
+	sum, err := array.Sum()
+	if err != nil {
+		return nil, err
+	}
+
+	n := float64(len(array))
+	deviations := make(CKKSCiphertextArray, len(array))
+	for i, ct := range array {
+		scaled, err := ct.MultByConst(n)
 		if err != nil {
 			return nil, err
 		}
 
-		mult, err := Mult(sub1, sub2)
+		deviation, err := scaled.Subtract(sum)
 		if err != nil {
 			return nil, err
 		}
 
-		ciphertextMult, err := unmarshallIntoNewCiphertext(mult)
+		deviations[i], err = deviation.MultByConst(factor * math.Pow(n, -1.5))
 		if err != nil {
 			return nil, err
 		}
+	}
+	return deviations, nil
+}
 
-		CkksEvaluator.Relinearize(ciphertextMult, ciphertextMult)
+// StdDev Returns a new CKKSCiphertext containing the standard deviation of array when decrypted.
+// Variance of array must lie within [a, b], a >= 0, see SqrtApproximation
+func (array CKKSCiphertextArray) StdDev(a float64, b float64) (*CKKSCiphertext, error) {
+	variance, err := array.Variance()
+	if err != nil {
+		return nil, err
+	}
+	return variance.Sqrt(a, b)
+}
 
-		CkksEvaluator.Add(ciphertextSum, ciphertextMult, ciphertextSum)
+// PearsonCorrelation Returns a new CKKSCiphertext containing the Pearson correlation coefficient
+// of array and array2 when decrypted. Variances of both arrays must lie within [a, b], a > 0.
+// With default parameters the inverse square root of variances is limited to degree 15, which keeps
+// the error below DefaultMaxApproximationError for intervals up to about [1, 20]. Wider intervals
+// return an error naming the degree that fits
+func (array CKKSCiphertextArray) PearsonCorrelation(array2 CKKSCiphertextArray, a float64, b float64) (*CKKSCiphertext, error) {
+	approximation, err := InvSqrtApproximation(a, b, DefaultMaxApproximationError)
+	if err != nil {
+		return nil, err
 	}
 
-	sum, err := ciphertextSum.MarshalBinary()
-	result, err := DivByConst(sum, float64(len(encryptedDataArray1)))
+	covariance, err := array.Covariance(array2)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("CKKS: Covariance success")
-	return result, nil
+	invStdDev1, err := array.invStdDev(approximation, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	invStdDev2, err := array2.invStdDev(approximation, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	invStdDevProduct, err := invStdDev1.Mult(invStdDev2)
+	if err != nil {
+		return nil, err
+	}
+	return covariance.Mult(invStdDevProduct)
 }
 
-// ArithmeticProgressionElementN Calculates the N element of arithmetic progression in []byte.
-// Requires the first element of progression and the difference between two members of progression
-func ArithmeticProgressionElementN(firstMember []byte, dif []byte, n []byte) ([]byte, error) {
-	dec, err := SubtractConst(n, 1)
+// ZScoreNormalize Returns a new CKKSCiphertextArray containing (x - mean) / stdDev for every x
+// of array when decrypted. Variance of array must lie within [a, b], a > 0. With default parameters
+// the inverse square root of variance is limited to degree 31, e.g. [1, 100], and wider intervals
+// return an error naming the degree that fits, see InvSqrtApproximation
+func (array CKKSCiphertextArray) ZScoreNormalize(a float64, b float64) (CKKSCiphertextArray, error) {
+	approximation, err := InvSqrtApproximation(a, b, DefaultMaxApproximationError)
 	if err != nil {
 		return nil, err
 	}
 
-	mult, err := Mult(dif, dec)
+	deviations, err := array.deviations()
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("CKKS: ArithmeticProgressionElementN success")
-	return Sum(firstMember, mult)
+	// deviations are divided by sqrt(n), which is multiplied back into coefficients
+	// of the approximation without consuming a level
+	invStdDev, err := array.invStdDev(approximation.scaled(math.Sqrt(float64(len(array)))), 1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(CKKSCiphertextArray, len(array))
+	for i, deviation := range deviations {
+		result[i], err = deviation.Mult(invStdDev)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
-// ArithmeticProgressionSum Calculates sum of arithmetic progression in []byte.
-// Requires the first element of progression, the difference between two members of progression
-// and number of elements in progression
-func ArithmeticProgressionSum(firstMember []byte, dif []byte, numberOfMembers []byte) ([]byte, error) {
-	elementN, err := ArithmeticProgressionElementN(firstMember, dif, numberOfMembers)
+// invStdDev returns 1 / stdDev of array, evaluating approximation of inverse square root on its variance.
+// Returns an error if the approximation doesn't leave levelsAfter levels for the rest of the computation
+func (array CKKSCiphertextArray) invStdDev(approximation *Approximation, levelsAfter int) (*CKKSCiphertext, error) {
+	variance, err := array.Variance()
 	if err != nil {
 		return nil, err
 	}
 
-	sum, err := Sum(firstMember, elementN)
+	levels := variance.Level() - levelsAfter
+	if approximation.Polynomial.Depth() > levels {
+		return nil, fmt.Errorf("inverse square root of variance over [%g, %g] needs degree %d, but only degree %d fits, narrow the interval",
+			approximation.A, approximation.B, approximation.Polynomial.Degree(), maxApproximationDegree(levels))
+	}
+	return variance.Approximate(approximation)
+}
+
+// ArithmeticProgressionElementN Returns a new CKKSCiphertext containing the n element of
+// arithmetic progression with first member ct and difference dif when decrypted
+func (ct *CKKSCiphertext) ArithmeticProgressionElementN(dif *CKKSCiphertext, n *CKKSCiphertext) (*CKKSCiphertext, error) {
+	dec, err := n.SubtractConst(1)
 	if err != nil {
 		return nil, err
 	}
 
-	sumCiphertext, err := unmarshallIntoNewCiphertext(sum)
+	mult, err := dif.Mult(dec)
 	if err != nil {
 		return nil, err
 	}
-	CkksEvaluator.Relinearize(sumCiphertext, sumCiphertext)
 
-	sum, err = sumCiphertext.MarshalBinary()
+	return ct.Sum(mult)
+}
+
+// ArithmeticProgressionSum Returns a new CKKSCiphertext containing the sum of numberOfMembers
+// members of arithmetic progression with first member ct and difference dif when decrypted
+func (ct *CKKSCiphertext) ArithmeticProgressionSum(dif *CKKSCiphertext, numberOfMembers *CKKSCiphertext) (*CKKSCiphertext, error) {
+	elementN, err := ct.ArithmeticProgressionElementN(dif, numberOfMembers)
 	if err != nil {
 		return nil, err
 	}
 
-	mult, err := Mult(numberOfMembers, sum)
+	sum, err := ct.Sum(elementN)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("CKKS: ArithmeticProgressionSum success")
-	return DivByConst(mult, 2.0)
+	mult, err := numberOfMembers.Mult(sum)
+	if err != nil {
+		return nil, err
+	}
+
+	return mult.DivByConst(2.0)
 }
@@ -0,0 +1,219 @@
+package ckksMath
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// Matrices are multiplied by the diagonal method of Halevi and Shoup: for a d x d matrix M and
+// vector v, M * v is the sum of products of diagonals diag[i][j] = M[j][(j + i) mod d] and
+// v rotated by i slots. Rectangular matrices are padded with zeros to d = max(rows, columns).
+// Vectors take the first slots of a ciphertext, and the slots after them must be zero, as in
+// freshly encrypted vectors and results of MatVec. Encrypted matrices are arrays of encrypted
+// diagonals, see MatrixDiagonals, and encrypted matrices MatMul multiplies by are arrays
+// of encrypted columns
+
+type MatrixOperation func([][]float64, []byte) ([]byte, error)
+type MatrixOperation2 func([][]float64, [][]byte) ([][]byte, error)
+
+// MatrixDiagonals Returns diagonals of matrix padded with zeros to a square matrix, which
+// Context.MatVecEncrypted and Context.MatMulEncrypted take encrypted
+func MatrixDiagonals(matrix [][]float64) ([][]float64, error) {
+	if len(matrix) == 0 || len(matrix[0]) == 0 {
+		return nil, errors.New("matrix must not be empty")
+	}
+	columns := len(matrix[0])
+	for _, row := range matrix {
+		if len(row) != columns {
+			return nil, errors.New("all rows of matrix must be of the same length")
+		}
+	}
+
+	d := max(len(matrix), columns)
+	diagonals := make([][]float64, d)
+	for i := range diagonals {
+		diagonals[i] = make([]float64, d)
+		for j := range diagonals[i] {
+			if k := (j + i) % d; j < len(matrix) && k < columns {
+				diagonals[i][j] = matrix[j][k]
+			}
+		}
+	}
+	return diagonals, nil
+}
+
+// MatVec Computes encrypted product of plaintext matrix and encryptedVector, which holds
+// len(matrix[0]) values, consuming one level. Requires rotation keys for all slots
+func (ctx *Context) MatVec(matrix [][]float64, encryptedVector []byte) ([]byte, error) {
+	vector, err := ctx.Unmarshal(encryptedVector)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := vector.MatVec(matrix)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: MatVec success")
+	return result.Marshal()
+}
+
+// MatVecEncrypted Computes encrypted product of encrypted matrix, given by its encrypted diagonals,
+// and encryptedVector, consuming one level. Requires rotation keys for all slots
+func (ctx *Context) MatVecEncrypted(encryptedMatrix [][]byte, encryptedVector []byte) ([]byte, error) {
+	diagonals, err := ctx.UnmarshalArray(encryptedMatrix)
+	if err != nil {
+		return nil, err
+	}
+
+	vector, err := ctx.Unmarshal(encryptedVector)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := vector.MatVecEncrypted(diagonals)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: MatVecEncrypted success")
+	return result.Marshal()
+}
+
+// MatMul Computes encrypted product of plaintext matrix and encrypted matrix given by its
+// encrypted columns, returning encrypted columns of the product
+func (ctx *Context) MatMul(matrix [][]float64, encryptedColumns [][]byte) ([][]byte, error) {
+	columns, err := ctx.UnmarshalArray(encryptedColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := columns.MatMul(matrix)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: MatMul success")
+	return result.Marshal()
+}
+
+// MatMulEncrypted Computes encrypted product of encrypted matrix given by its encrypted diagonals
+// and encrypted matrix given by its encrypted columns, returning encrypted columns of the product
+func (ctx *Context) MatMulEncrypted(encryptedMatrix [][]byte, encryptedColumns [][]byte) ([][]byte, error) {
+	diagonals, err := ctx.UnmarshalArray(encryptedMatrix)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := ctx.UnmarshalArray(encryptedColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := columns.MatMulEncrypted(diagonals)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: MatMulEncrypted success")
+	return result.Marshal()
+}
+
+// MatVec Returns a new CKKSCiphertext containing the product of plaintext matrix and ct when decrypted
+func (ct *CKKSCiphertext) MatVec(matrix [][]float64) (*CKKSCiphertext, error) {
+	diagonals, err := MatrixDiagonals(matrix)
+	if err != nil {
+		return nil, err
+	}
+
+	return ct.matVec(len(diagonals), func(i int, rotated *CKKSCiphertext) (*CKKSCiphertext, error) {
+		return rotated.MultByVector(diagonals[i])
+	})
+}
+
+// MatVecEncrypted Returns a new CKKSCiphertext containing the product of encrypted matrix given by
+// its diagonals and ct when decrypted
+func (ct *CKKSCiphertext) MatVecEncrypted(diagonals CKKSCiphertextArray) (*CKKSCiphertext, error) {
+	if len(diagonals) == 0 {
+		return nil, errEmptyArray
+	}
+
+	return ct.matVec(len(diagonals), func(i int, rotated *CKKSCiphertext) (*CKKSCiphertext, error) {
+		return rotated.Mult(diagonals[i])
+	})
+}
+
+// MatMul Returns a new CKKSCiphertextArray containing columns of the product of plaintext matrix and
+// the matrix of columns when decrypted
+func (columns CKKSCiphertextArray) MatMul(matrix [][]float64) (CKKSCiphertextArray, error) {
+	return columns.matMul(func(column *CKKSCiphertext) (*CKKSCiphertext, error) {
+		return column.MatVec(matrix)
+	})
+}
+
+// MatMulEncrypted Returns a new CKKSCiphertextArray containing columns of the product of encrypted
+// matrix given by its diagonals and the matrix of columns when decrypted
+func (columns CKKSCiphertextArray) MatMulEncrypted(diagonals CKKSCiphertextArray) (CKKSCiphertextArray, error) {
+	return columns.matMul(func(column *CKKSCiphertext) (*CKKSCiphertext, error) {
+		return column.MatVecEncrypted(diagonals)
+	})
+}
+
+// matMul multiplies a matrix by every column with matVec
+func (columns CKKSCiphertextArray) matMul(matVec func(*CKKSCiphertext) (*CKKSCiphertext, error)) (CKKSCiphertextArray, error) {
+	if len(columns) == 0 {
+		return nil, errEmptyArray
+	}
+
+	result := make(CKKSCiphertextArray, len(columns))
+	for i, column := range columns {
+		var err error
+		result[i], err = matVec(column)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// matVec sums products of d diagonals, computed by multiply, and ct rotated by index of a diagonal
+func (ct *CKKSCiphertext) matVec(d int, multiply func(int, *CKKSCiphertext) (*CKKSCiphertext, error)) (*CKKSCiphertext, error) {
+	slots := ct.ctx.Params.Slots()
+	if 2*d > slots {
+		return nil, fmt.Errorf("matrix dimension must not exceed %d", slots/2)
+	}
+	if err := ct.ctx.checkRotationKeys(slots); err != nil {
+		return nil, err
+	}
+
+	// rotations require a relinearized ciphertext
+	vector, err := ct.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+
+	// the vector is copied right after itself, so that rotations by less than d wrap around it
+	copied := ct.ctx.rotate(vector.Ciphertext, slots-d)
+	rotated := ct.derive(ct.ctx.Evaluator.AddNew(vector.Ciphertext, copied))
+
+	products := make(CKKSCiphertextArray, d)
+	for i := range products {
+		if i > 0 {
+			rotated = ct.derive(ct.ctx.Evaluator.RotateNew(rotated.Ciphertext, 1))
+		}
+
+		products[i], err = multiply(i, rotated)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// with lazy relinearization products are summed first and relinearized once
+	sum, err := products.Sum()
+	if err != nil {
+		return nil, err
+	}
+	return sum.Relinearize()
+}
@@ -0,0 +1,571 @@
+package ckksMath
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+)
+
+// LinearModel Plaintext linear regression model predicting Intercept + sum of Weights[i] * x[i]
+type LinearModel struct {
+	Weights   []float64
+	Intercept float64
+}
+
+// LinearRegression Fits simple linear regression y = slope * x + intercept over encrypted arrays
+// x and y in []byte, returning encrypted slope and intercept in this order. Variance of x must
+// lie within [a, b], a > 0. The inverse of variance is approximated as in Inverse, intervals
+// spanning up to a decade, e.g. [1, 10], leave the model one level for PredictLinearEncrypted
+func (ctx *Context) LinearRegression(encryptedX [][]byte, encryptedY [][]byte, a float64, b float64) ([][]byte, error) {
+	x, err := ctx.UnmarshalArray(encryptedX)
+	if err != nil {
+		return nil, err
+	}
+
+	y, err := ctx.UnmarshalArray(encryptedY)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := x.LinearRegression(y, a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: LinearRegression success")
+	return result.Marshal()
+}
+
+// LinearRegressionStatistics Computes statistics multiple linear regression of encryptedTarget on
+// encryptedFeatures is fitted from, without any approximation: for every feature its covariances
+// with all features followed by its covariance with the target, then means of all features and the
+// mean of the target, (k + 1)^2 values for k features. Fitting is not encrypted: solving the normal
+// equations needs the inverse of a matrix, which is too deep for CKKS without bootstrapping, so
+// the key owner decrypts these values and solves them in plaintext with SolveLinearRegression.
+// This reveals all covariances and means to the key owner, only use it where the key owner may
+// learn them, otherwise fit the model encrypted with LinearRegressionGradientDescent
+func (ctx *Context) LinearRegressionStatistics(encryptedFeatures [][][]byte, encryptedTarget [][]byte) ([][]byte, error) {
+	features := make([]CKKSCiphertextArray, len(encryptedFeatures))
+	for i, encryptedFeature := range encryptedFeatures {
+		var err error
+		features[i], err = ctx.UnmarshalArray(encryptedFeature)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	target, err := ctx.UnmarshalArray(encryptedTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := target.LinearRegressionStatistics(features)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: LinearRegressionStatistics success")
+	return result.Marshal()
+}
+
+// LinearRegressionGradientDescent Fits multiple linear regression of encryptedTarget on
+// encryptedFeatures by a fixed number of iterations of gradient descent over encrypted data,
+// returning encrypted weights of all features followed by the intercept, as PredictLinearEncrypted
+// takes them. Starting from zero weights, every iteration updates weights w of centered features to
+// w - rate * (C * w - c), C being covariances of features and c their covariances with the target.
+// It converges for rate below 2 / the largest eigenvalue of C, e.g. 1 / the sum of variances, and
+// the fewer the more correlated features are. Unlike SolveLinearRegression the result is only exact
+// in the limit, and each iteration consumes a level: with default parameters at most 7 iterations
+// fit, leaving the model one level for PredictLinearEncrypted
+func (ctx *Context) LinearRegressionGradientDescent(encryptedFeatures [][][]byte, encryptedTarget [][]byte, rate float64, iterations int) ([][]byte, error) {
+	features := make([]CKKSCiphertextArray, len(encryptedFeatures))
+	for i, encryptedFeature := range encryptedFeatures {
+		var err error
+		features[i], err = ctx.UnmarshalArray(encryptedFeature)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	target, err := ctx.UnmarshalArray(encryptedTarget)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := target.LinearRegressionGradientDescent(features, rate, iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: LinearRegressionGradientDescent success")
+	return result.Marshal()
+}
+
+// PredictLinear Computes encrypted prediction of model for encryptedFeatures in []byte,
+// one ciphertext per feature
+func (ctx *Context) PredictLinear(encryptedFeatures [][]byte, model *LinearModel) ([]byte, error) {
+	features, err := ctx.UnmarshalArray(encryptedFeatures)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := features.PredictLinear(model)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: PredictLinear success")
+	return result.Marshal()
+}
+
+// PredictLinearEncrypted Computes encrypted prediction of encrypted model for encryptedFeatures
+// in []byte, one ciphertext per feature. encryptedModel holds weights of all features followed by
+// the intercept, as returned by LinearRegression
+func (ctx *Context) PredictLinearEncrypted(encryptedFeatures [][]byte, encryptedModel [][]byte) ([]byte, error) {
+	features, err := ctx.UnmarshalArray(encryptedFeatures)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := ctx.UnmarshalArray(encryptedModel)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := features.PredictLinearEncrypted(model)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: PredictLinearEncrypted success")
+	return result.Marshal()
+}
+
+// PredictLogistic Computes encrypted probability 1 / (1 + e^-z) of logistic regression model
+// with plaintext weights, z being the logit model.Intercept + sum of model.Weights[i] * x[i] of the
+// feature vector packed into the first len(model.Weights) slots of encryptedFeatures. Further
+// vectors may follow back to back, slot i * len(model.Weights) of the result then holds
+// the probability of vector i. Logits must lie within [a, b], see Sigmoid, and summing slots
+// requires rotation keys for powers of two below len(model.Weights)
+func (ctx *Context) PredictLogistic(encryptedFeatures []byte, model *LinearModel, a float64, b float64) ([]byte, error) {
+	features, err := ctx.Unmarshal(encryptedFeatures)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := features.PredictLogistic(model, a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: PredictLogistic success")
+	return result.Marshal()
+}
+
+// SolveLinearRegression Fits multiple linear regression in plaintext from decrypted statistics
+// returned by LinearRegressionStatistics, solving its normal equations. Returns an error if
+// features are linearly dependent
+func SolveLinearRegression(statistics []float64) (*LinearModel, error) {
+	k := int(math.Round(math.Sqrt(float64(len(statistics))))) - 1
+	if k < 1 || (k+1)*(k+1) != len(statistics) {
+		return nil, fmt.Errorf("linear regression statistics must contain (k + 1)^2 values for k features, got %d", len(statistics))
+	}
+
+	// augmented matrix of normal equations covariances * weights = target covariances
+	rows := make([][]float64, k)
+	var maxCovariance float64
+	for i := range rows {
+		rows[i] = append([]float64{}, statistics[i*(k+1):(i+1)*(k+1)]...)
+		maxCovariance = math.Max(maxCovariance, math.Abs(rows[i][i]))
+	}
+
+	// Gaussian elimination with partial pivoting
+	for column := 0; column < k; column++ {
+		pivot := column
+		for i := column + 1; i < k; i++ {
+			if math.Abs(rows[i][column]) > math.Abs(rows[pivot][column]) {
+				pivot = i
+			}
+		}
+		if !(math.Abs(rows[pivot][column]) > 1e-9*maxCovariance) {
+			return nil, errors.New("features are linearly dependent, remove redundant features")
+		}
+		rows[column], rows[pivot] = rows[pivot], rows[column]
+
+		for i := column + 1; i < k; i++ {
+			factor := rows[i][column] / rows[column][column]
+			for j := column; j <= k; j++ {
+				rows[i][j] -= factor * rows[column][j]
+			}
+		}
+	}
+
+	means := statistics[k*(k+1):]
+	model := &LinearModel{Weights: make([]float64, k), Intercept: means[k]}
+	for i := k - 1; i >= 0; i-- {
+		value := rows[i][k]
+		for j := i + 1; j < k; j++ {
+			value -= rows[i][j] * model.Weights[j]
+		}
+		model.Weights[i] = value / rows[i][i]
+		model.Intercept -= model.Weights[i] * means[i]
+	}
+	return model, nil
+}
+
+// LinearRegression Returns a new CKKSCiphertextArray containing slope and intercept of simple
+// linear regression of target on array when decrypted. Variance of array must lie within [a, b], a > 0
+func (array CKKSCiphertextArray) LinearRegression(target CKKSCiphertextArray, a float64, b float64) (CKKSCiphertextArray, error) {
+	if len(array) != len(target) {
+		return nil, errors.New("arrays must be of the same length")
+	}
+
+	deviations, err := array.deviations()
+	if err != nil {
+		return nil, err
+	}
+
+	targetDeviations, err := target.deviations()
+	if err != nil {
+		return nil, err
+	}
+
+	variance, err := deviations.covariance(deviations)
+	if err != nil {
+		return nil, err
+	}
+
+	covariance, err := deviations.covariance(targetDeviations)
+	if err != nil {
+		return nil, err
+	}
+
+	// one level is left for multiplying by covariance
+	approximation, err := variance.approximation(inverseFunction, a, b, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	invVariance, err := variance.Approximate(approximation)
+	if err != nil {
+		return nil, err
+	}
+
+	slope, err := covariance.Mult(invVariance)
+	if err != nil {
+		return nil, err
+	}
+
+	mean, err := array.Mean()
+	if err != nil {
+		return nil, err
+	}
+
+	targetMean, err := target.Mean()
+	if err != nil {
+		return nil, err
+	}
+
+	// mean * covariance is computed while the inverse of variance is evaluated,
+	// so intercept takes no more levels than slope
+	shift, err := mean.Mult(covariance)
+	if err != nil {
+		return nil, err
+	}
+
+	shift, err = shift.Mult(invVariance)
+	if err != nil {
+		return nil, err
+	}
+
+	intercept, err := targetMean.Subtract(shift)
+	if err != nil {
+		return nil, err
+	}
+	return CKKSCiphertextArray{slope, intercept}, nil
+}
+
+// LinearRegressionStatistics Returns a new CKKSCiphertextArray containing statistics of linear
+// regression of target on features when decrypted, see Context.LinearRegressionStatistics
+func (target CKKSCiphertextArray) LinearRegressionStatistics(features []CKKSCiphertextArray) (CKKSCiphertextArray, error) {
+	if err := target.checkFeatures(features); err != nil {
+		return nil, err
+	}
+
+	arrays := append(append([]CKKSCiphertextArray{}, features...), target)
+	deviations := make([]CKKSCiphertextArray, len(arrays))
+	for i, array := range arrays {
+		var err error
+		deviations[i], err = array.deviations()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	k := len(features)
+	result := make(CKKSCiphertextArray, (k+1)*(k+1))
+	for i := 0; i < k; i++ {
+		for j := 0; j <= k; j++ {
+			// covariance matrix is symmetric
+			if j < i {
+				result[i*(k+1)+j] = result[j*(k+1)+i]
+				continue
+			}
+
+			var err error
+			result[i*(k+1)+j], err = deviations[i].covariance(deviations[j])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i, array := range arrays {
+		var err error
+		result[k*(k+1)+i], err = array.Mean()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// LinearRegressionGradientDescent Returns a new CKKSCiphertextArray containing weights of all
+// features followed by the intercept of multiple linear regression of target on features, fitted
+// by iterations iterations of gradient descent with learning rate rate, when decrypted,
+// see Context.LinearRegressionGradientDescent
+func (target CKKSCiphertextArray) LinearRegressionGradientDescent(features []CKKSCiphertextArray, rate float64, iterations int) (CKKSCiphertextArray, error) {
+	if err := target.checkFeatures(features); err != nil {
+		return nil, err
+	}
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		return nil, errors.New("learning rate must be positive")
+	}
+	if iterations < 1 {
+		return nil, errors.New("gradient descent requires at least one iteration")
+	}
+
+	k := len(features)
+	deviations := make([]CKKSCiphertextArray, k+1)
+	// deviations of features multiplied by rate, so that rate * C and rate * c cost no level
+	rateDeviations := make([]CKKSCiphertextArray, k)
+	for i, array := range append(append([]CKKSCiphertextArray{}, features...), target) {
+		var err error
+		deviations[i], err = array.deviations()
+		if err != nil {
+			return nil, err
+		}
+		if i < k {
+			rateDeviations[i], err = array.scaledDeviations(rate)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// row i holds rate * C[i][j] for every feature j followed by rate * c[i]
+	covariances := make([]CKKSCiphertextArray, k)
+	for i := range covariances {
+		covariances[i] = make(CKKSCiphertextArray, k+1)
+		for j := range covariances[i] {
+			// rate * C is symmetric
+			if j < i {
+				covariances[i][j] = covariances[j][i]
+				continue
+			}
+
+			var err error
+			covariances[i][j], err = rateDeviations[i].covariance(deviations[j])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// every iteration after the first one and the intercept consume a level each
+	levels := covariances[0][k].Level()
+	if iterations > levels {
+		return nil, fmt.Errorf("gradient descent of %d iterations needs %d levels, but %d are left, run at most %d iterations",
+			iterations, iterations, levels, levels)
+	}
+
+	// the first iteration from zero weights yields rate * c
+	weights := make(CKKSCiphertextArray, k)
+	for i := range weights {
+		weights[i] = covariances[i][k]
+	}
+
+	for iteration := 1; iteration < iterations; iteration++ {
+		next := make(CKKSCiphertextArray, k)
+		for i := range next {
+			products := make(CKKSCiphertextArray, k)
+			for j := range products {
+				var err error
+				products[j], err = covariances[i][j].Mult(weights[j])
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			// with lazy relinearization products are summed first and relinearized once
+			gradient, err := products.Sum()
+			if err != nil {
+				return nil, err
+			}
+
+			gradient, err = gradient.Relinearize()
+			if err != nil {
+				return nil, err
+			}
+
+			// rate * (C * w - c) is subtracted as rate * c - rate * C * w
+			step, err := covariances[i][k].Subtract(gradient)
+			if err != nil {
+				return nil, err
+			}
+
+			next[i], err = weights[i].Sum(step)
+			if err != nil {
+				return nil, err
+			}
+		}
+		weights = next
+	}
+
+	intercept, err := target.intercept(features, weights)
+	if err != nil {
+		return nil, err
+	}
+	return append(weights, intercept), nil
+}
+
+// intercept returns mean of target - sum of weights[i] * mean of features[i]
+func (target CKKSCiphertextArray) intercept(features []CKKSCiphertextArray, weights CKKSCiphertextArray) (*CKKSCiphertext, error) {
+	products := make(CKKSCiphertextArray, len(features))
+	for i, feature := range features {
+		mean, err := feature.Mean()
+		if err != nil {
+			return nil, err
+		}
+
+		products[i], err = mean.Mult(weights[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shift, err := products.Sum()
+	if err != nil {
+		return nil, err
+	}
+
+	shift, err = shift.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+
+	targetMean, err := target.Mean()
+	if err != nil {
+		return nil, err
+	}
+	return targetMean.Subtract(shift)
+}
+
+// checkFeatures returns an error unless there is at least one feature and all features
+// are of the length of target
+func (target CKKSCiphertextArray) checkFeatures(features []CKKSCiphertextArray) error {
+	if len(features) == 0 {
+		return errors.New("linear regression requires at least one feature")
+	}
+	for _, feature := range features {
+		if len(feature) != len(target) {
+			return errors.New("features and target must be of the same length")
+		}
+	}
+	return nil
+}
+
+// PredictLinear Returns a new CKKSCiphertext containing prediction of model for features when
+// decrypted, features holding one ciphertext per feature
+func (features CKKSCiphertextArray) PredictLinear(model *LinearModel) (*CKKSCiphertext, error) {
+	if len(features) == 0 || len(model.Weights) != len(features) {
+		return nil, fmt.Errorf("model of %d weights can't predict from %d features", len(model.Weights), len(features))
+	}
+
+	terms := make(CKKSCiphertextArray, len(features))
+	for i, feature := range features {
+		var err error
+		terms[i], err = feature.MultByConst(model.Weights[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sum, err := terms.Sum()
+	if err != nil {
+		return nil, err
+	}
+	return sum.AddConst(model.Intercept)
+}
+
+// PredictLinearEncrypted Returns a new CKKSCiphertext containing prediction of encrypted model for
+// features when decrypted. model holds weights of all features followed by the intercept
+func (features CKKSCiphertextArray) PredictLinearEncrypted(model CKKSCiphertextArray) (*CKKSCiphertext, error) {
+	if len(features) == 0 || len(model) != len(features)+1 {
+		return nil, fmt.Errorf("model of %d weights and intercept can't predict from %d features", len(model)-1, len(features))
+	}
+
+	terms := make(CKKSCiphertextArray, len(features))
+	for i, feature := range features {
+		var err error
+		terms[i], err = feature.Mult(model[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// with lazy relinearization terms are summed first and relinearized once
+	sum, err := terms.Sum()
+	if err != nil {
+		return nil, err
+	}
+
+	sum, err = sum.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+	return sum.Sum(model[len(features)])
+}
+
+// PredictLogistic Returns a new CKKSCiphertext containing probabilities of logistic regression model
+// for feature vectors packed into ct when decrypted, see Context.PredictLogistic
+func (ct *CKKSCiphertext) PredictLogistic(model *LinearModel, a float64, b float64) (*CKKSCiphertext, error) {
+	n := len(model.Weights)
+	slots := ct.ctx.Params.Slots()
+	if n == 0 || n > slots {
+		return nil, fmt.Errorf("number of weights must be between 1 and %d", slots)
+	}
+
+	// weights are repeated for every vector packed into ct
+	weights := make([]float64, slots-slots%n)
+	for i := range weights {
+		weights[i] = model.Weights[i%n]
+	}
+
+	products, err := ct.MultByVector(weights)
+	if err != nil {
+		return nil, err
+	}
+
+	logits, err := products.SlotSum(n)
+	if err != nil {
+		return nil, err
+	}
+
+	logits, err = logits.AddConst(model.Intercept)
+	if err != nil {
+		return nil, err
+	}
+	return logits.Sigmoid(a, b)
+}
@@ -0,0 +1,196 @@
+package ckksMath
+
+import (
+	"fmt"
+	"github.com/ldsec/lattigo/v2/ckks"
+	"log"
+)
+
+type SlotOperation func([]byte, int) ([]byte, error)
+type SlotOperation2 func([]byte, []byte, int) ([]byte, error)
+type VectorOperation func([]byte, []float64) ([]byte, error)
+
+// SlotSum Returns encrypted data whose slot 0 contains the sum of the first n slots
+// of encryptedData. Uses log2(n) rotate-and-add steps and requires rotation keys
+// for powers of two below n
+func (ctx *Context) SlotSum(encryptedData []byte, n int) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.SlotSum(n)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: SlotSum success")
+	return result.Marshal()
+}
+
+// SlotMean Returns encrypted data whose slot 0 contains the mean of the first n slots
+// of encryptedData
+func (ctx *Context) SlotMean(encryptedData []byte, n int) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.SlotMean(n)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: SlotMean success")
+	return result.Marshal()
+}
+
+// InnerProduct Returns encrypted data whose slot 0 contains the inner product of the
+// first n slots of encryptedData and encryptedData2
+func (ctx *Context) InnerProduct(encryptedData []byte, encryptedData2 []byte, n int) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext2, err := ctx.Unmarshal(encryptedData2)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.InnerProduct(ciphertext2, n)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: InnerProduct success")
+	return result.Marshal()
+}
+
+// MultByVector Multiplies every slot of encryptedData by the value of the same slot of
+// plaintext values. Slots beyond len(values) are multiplied by zero
+func (ctx *Context) MultByVector(encryptedData []byte, values []float64) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.MultByVector(values)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: MultByVector success")
+	return result.Marshal()
+}
+
+// MultByVector Returns a new CKKSCiphertext containing the slot-wise product of ct and
+// plaintext values when decrypted
+func (ct *CKKSCiphertext) MultByVector(values []float64) (*CKKSCiphertext, error) {
+	params := ct.ctx.Params
+	if len(values) > params.Slots() {
+		return nil, fmt.Errorf("cannot multiply by %d values, ciphertext has %d slots", len(values), params.Slots())
+	}
+
+	// values are encoded at the scale of the modulus rescaling drops, so the product
+	// keeps the scale of ct, as with MultByConst
+	level := ct.Level()
+	plaintext := ckks.NewPlaintext(params, level, float64(params.RingQ().Modulus[level]))
+	ckks.NewEncoder(params).Encode(values, plaintext, params.LogSlots())
+
+	product := ct.ctx.Evaluator.MulNew(ct.Ciphertext, plaintext)
+	if err := ct.ctx.rescale(product); err != nil {
+		return nil, err
+	}
+	return ct.derive(product), nil
+}
+
+// SlotSum Returns a new CKKSCiphertext whose slot 0 contains the sum of the first n slots of ct
+func (ct *CKKSCiphertext) SlotSum(n int) (*CKKSCiphertext, error) {
+	sum, err := ct.ctx.slotSum(ct.Ciphertext, n)
+	if err != nil {
+		return nil, err
+	}
+	return ct.derive(sum), nil
+}
+
+// SlotMean Returns a new CKKSCiphertext whose slot 0 contains the mean of the first n slots of ct
+func (ct *CKKSCiphertext) SlotMean(n int) (*CKKSCiphertext, error) {
+	sum, err := ct.SlotSum(n)
+	if err != nil {
+		return nil, err
+	}
+	return sum.DivByConst(float64(n))
+}
+
+// InnerProduct Returns a new CKKSCiphertext whose slot 0 contains the inner product of
+// the first n slots of ct and other
+func (ct *CKKSCiphertext) InnerProduct(other *CKKSCiphertext, n int) (*CKKSCiphertext, error) {
+	product, err := ct.Mult(other)
+	if err != nil {
+		return nil, err
+	}
+
+	// rotations require a relinearized ciphertext
+	product, err = product.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+	return product.SlotSum(n)
+}
+
+// slotSum sums the first n slots of ciphertext into slot 0, reading n bit by bit:
+// every step doubles the width of partial sums, and partial sums matching set bits
+// of n are rotated into place and accumulated
+func (ctx *Context) slotSum(ciphertext *ckks.Ciphertext, n int) (*ckks.Ciphertext, error) {
+	if n < 1 || n > ctx.Params.Slots() {
+		return nil, fmt.Errorf("number of slots must be between 1 and %d", ctx.Params.Slots())
+	}
+	if err := ctx.checkRotationKeys(n); err != nil {
+		return nil, err
+	}
+
+	var result *ckks.Ciphertext
+	partial := ciphertext
+	for i, j := 0, n; j > 0; i, j = i+1, j>>1 {
+		if j&1 == 1 {
+			rotated := ctx.rotate(partial, n-(n&((2<<i)-1)))
+			if result == nil {
+				result = rotated
+			} else {
+				ctx.Evaluator.Add(result, rotated, result)
+			}
+		}
+		if j > 1 {
+			partial = ctx.Evaluator.AddNew(partial, ctx.Evaluator.RotateNew(partial, 1<<i))
+		}
+	}
+	return result, nil
+}
+
+// rotate returns a new ciphertext rotated left by k slots, composing rotations
+// by powers of two
+func (ctx *Context) rotate(ciphertext *ckks.Ciphertext, k int) *ckks.Ciphertext {
+	rotated := ciphertext.CopyNew()
+	for bit := 1; bit <= k; bit <<= 1 {
+		if k&bit != 0 {
+			ctx.Evaluator.Rotate(rotated, bit, rotated)
+		}
+	}
+	return rotated
+}
+
+// checkRotationKeys returns an error if EvalKey lacks a rotation key
+// needed to sum n slots
+func (ctx *Context) checkRotationKeys(n int) error {
+	for k := 1; k < n; k <<= 1 {
+		galEl := ctx.Params.GaloisElementForColumnRotationBy(k)
+		if ctx.EvalKey.Rtks == nil {
+			return fmt.Errorf("evaluation key has no rotation keys, cannot aggregate %d slots", n)
+		}
+		if _, ok := ctx.EvalKey.Rtks.Keys[galEl]; !ok {
+			return fmt.Errorf("evaluation key has no rotation key for step %d, cannot aggregate %d slots", k, n)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package ckksMath
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ldsec/lattigo/v2/ckks"
+	"log"
+	"math"
+	"math/bits"
+)
+
+type PolynomialOperation func([]byte, *Polynomial) ([]byte, error)
+
+// PolynomialBasis Basis coefficients of a Polynomial are given in
+type PolynomialBasis int
+
+const (
+	// MonomialBasis Coefficients of 1, x, x^2, ...
+	MonomialBasis PolynomialBasis = iota
+	// ChebyshevBasis Coefficients of Chebyshev polynomials T0, T1, T2, ... over interval [A, B]
+	ChebyshevBasis
+)
+
+// Polynomial Real polynomial which can be evaluated on encrypted data with EvaluatePolynomial.
+// Coeffs[i] is the coefficient of x^i in MonomialBasis, or of Ti((2x - A - B) / (B - A))
+// in ChebyshevBasis
+type Polynomial struct {
+	Coeffs []float64
+	Basis  PolynomialBasis
+	A      float64
+	B      float64
+}
+
+// NewPolynomial Creates a Polynomial in MonomialBasis, coeffs[i] being the coefficient of x^i
+func NewPolynomial(coeffs []float64) *Polynomial {
+	return &Polynomial{Coeffs: append([]float64{}, coeffs...), Basis: MonomialBasis}
+}
+
+// NewChebyshevPolynomial Creates a Polynomial in ChebyshevBasis over interval [a, b]
+func NewChebyshevPolynomial(coeffs []float64, a float64, b float64) *Polynomial {
+	return &Polynomial{Coeffs: append([]float64{}, coeffs...), Basis: ChebyshevBasis, A: a, B: b}
+}
+
+// ApproximatePolynomial Interpolates f in Chebyshev nodes of interval [a, b], creating a Polynomial
+// of passed degree in ChebyshevBasis. Outside of [a, b] the approximation quickly diverges from f
+func ApproximatePolynomial(f func(float64) float64, a float64, b float64, degree int) *Polynomial {
+	approximation := ckks.Approximate(f, a, b, degree)
+	coeffs := make([]float64, len(approximation.Coeffs))
+	for i, c := range approximation.Coeffs {
+		coeffs[i] = real(c)
+	}
+	return NewChebyshevPolynomial(coeffs, a, b)
+}
+
+// Degree Returns the degree of polynomial, ignoring zero leading coefficients
+func (polynomial *Polynomial) Degree() int {
+	degree := len(polynomial.Coeffs) - 1
+	for degree > 0 && polynomial.Coeffs[degree] == 0 {
+		degree--
+	}
+	return degree
+}
+
+// Depth Returns the number of levels EvaluatePolynomial consumes at most: ceil(log2(degree + 1)),
+// plus one for the change of variable of ChebyshevBasis
+func (polynomial *Polynomial) Depth() int {
+	degree := polynomial.Degree()
+	if degree < 1 {
+		return 0
+	}
+	depth := bits.Len(uint(degree))
+	if polynomial.Basis == ChebyshevBasis {
+		depth++
+	}
+	return depth
+}
+
+// Evaluate Returns the value of polynomial at x computed in plain
+func (polynomial *Polynomial) Evaluate(x float64) float64 {
+	coeffs := polynomial.Coeffs[:polynomial.Degree()+1]
+	if polynomial.Basis == ChebyshevBasis {
+		// Clenshaw's recurrence
+		t := (2*x - polynomial.A - polynomial.B) / (polynomial.B - polynomial.A)
+		var b1, b2 float64
+		for i := len(coeffs) - 1; i > 0; i-- {
+			b1, b2 = 2*t*b1-b2+coeffs[i], b1
+		}
+		return t*b1 - b2 + coeffs[0]
+	}
+
+	var result float64
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		result = result*x + coeffs[i]
+	}
+	return result
+}
+
+// validate returns an error if polynomial can't be evaluated
+func (polynomial *Polynomial) validate() error {
+	if polynomial == nil || len(polynomial.Coeffs) == 0 {
+		return errors.New("polynomial has no coefficients")
+	}
+	for _, c := range polynomial.Coeffs {
+		if math.IsNaN(c) || math.IsInf(c, 0) {
+			return errors.New("polynomial coefficients must be finite")
+		}
+	}
+	switch polynomial.Basis {
+	case MonomialBasis:
+	case ChebyshevBasis:
+		if !(polynomial.A < polynomial.B) {
+			return fmt.Errorf("invalid Chebyshev interval [%g, %g]", polynomial.A, polynomial.B)
+		}
+	default:
+		return fmt.Errorf("unknown polynomial basis %d", polynomial.Basis)
+	}
+	return nil
+}
+
+// EvaluatePolynomial Evaluates polynomial on every slot of encryptedData, producing []byte of
+// encrypted data containing polynomial(encryptedData) when decrypted. Uses baby-step giant-step
+// evaluation consuming at most polynomial.Depth() levels, and requires the relinearization key
+func (ctx *Context) EvaluatePolynomial(encryptedData []byte, polynomial *Polynomial) ([]byte, error) {
+	ciphertext, err := ctx.Unmarshal(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := ciphertext.EvaluatePolynomial(polynomial)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("CKKS: EvaluatePolynomial success")
+	return result.Marshal()
+}
+
+// EvaluatePolynomial Returns a new CKKSCiphertext containing polynomial(ct) when decrypted
+func (ct *CKKSCiphertext) EvaluatePolynomial(polynomial *Polynomial) (*CKKSCiphertext, error) {
+	if err := polynomial.validate(); err != nil {
+		return nil, err
+	}
+
+	degree := polynomial.Degree()
+	if degree == 0 {
+		zero, err := ct.MultByConst(0)
+		if err != nil {
+			return nil, err
+		}
+		return zero.AddConst(polynomial.Coeffs[0])
+	}
+
+	if ct.ctx.EvalKey.Rlk == nil {
+		return nil, errNoRelinearizationKey
+	}
+	if ct.Level() < polynomial.Depth() {
+		return nil, fmt.Errorf("polynomial of depth %d can't be evaluated on ciphertext with %d levels left", polynomial.Depth(), ct.Level())
+	}
+
+	x, err := ct.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+
+	pol := &ckks.Polynomial{
+		Coeffs: make([]complex128, degree+1),
+		MaxDeg: degree,
+		Lead:   true,
+		Basis:  ckks.StandardBasis,
+	}
+	for i := range pol.Coeffs {
+		pol.Coeffs[i] = complex(polynomial.Coeffs[i], 0)
+	}
+
+	if polynomial.Basis == ChebyshevBasis {
+		// maps [A, B] onto [-1, 1], where Chebyshev polynomials are defined
+		a, b := polynomial.A, polynomial.B
+		pol.A, pol.B = a, b
+		pol.Basis = ckks.ChebyshevBasis
+
+		x, err = x.MultByConst(2 / (b - a))
+		if err != nil {
+			return nil, err
+		}
+		x, err = x.AddConst((-a - b) / (b - a))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := ct.ctx.Evaluator.EvaluatePoly(x.Ciphertext, pol, ct.ctx.Params.DefaultScale())
+	if err != nil {
+		return nil, err
+	}
+	return ct.derive(result), nil
+}
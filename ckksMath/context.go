@@ -0,0 +1,309 @@
+package ckksMath
+
+import (
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/rlwe"
+)
+
+// Context Holds CKKS parameters, evaluation key and an Evaluator built from them.
+// All ckksMath operations are available as methods on Context, so several
+// parameter and key sets can be used side by side in one process.
+// A Context is not safe for concurrent use, use ShallowCopy to get one per goroutine
+type Context struct {
+	Params    ckks.Parameters
+	EvalKey   rlwe.EvaluationKey
+	Evaluator ckks.Evaluator
+
+	// KeyID ID of the key EvalKey belongs to. If set, operations reject ciphertexts
+	// encrypted with other keys. If empty, any key ID is accepted, and results carry
+	// the key ID of operands
+	KeyID string
+
+	// AcceptLegacyCiphertexts If set, ciphertexts written before envelopes recorded scheme and
+	// parameters are accepted without checking them, see envelope.OpenLegacy
+	AcceptLegacyCiphertexts bool
+
+	// LazyRelinearization If set, Mult and Pow2 return products of degree 2, so that
+	// several products can be summed and relinearized once with Relinearize.
+	// Products are rescaled either way
+	LazyRelinearization bool
+}
+
+// CkksParams, CkksEvaluator and CkksEvalkey back the package-level functions.
+//
+// Deprecated: create a Context with NewContext instead
+var CkksParams ckks.Parameters
+var CkksEvaluator ckks.Evaluator
+var CkksEvalkey rlwe.EvaluationKey
+
+// NewContext Creates a new Context and an Evaluator for passed params and evalKey
+func NewContext(params ckks.Parameters, evalKey rlwe.EvaluationKey) *Context {
+	return &Context{
+		Params:    params,
+		EvalKey:   evalKey,
+		Evaluator: ckks.NewEvaluator(params, evalKey),
+	}
+}
+
+// ShallowCopy Creates a copy of Context sharing params and keys, but with its own
+// Evaluator buffers, so that the copy can be used in another goroutine
+func (ctx *Context) ShallowCopy() *Context {
+	return &Context{
+		Params:    ctx.Params,
+		EvalKey:   ctx.EvalKey,
+		Evaluator: ctx.Evaluator.ShallowCopy(),
+		KeyID:     ctx.KeyID,
+
+		LazyRelinearization:     ctx.LazyRelinearization,
+		AcceptLegacyCiphertexts: ctx.AcceptLegacyCiphertexts,
+	}
+}
+
+// checkKeyID returns envelope.KeyMismatchError if ctx only accepts ciphertexts
+// encrypted with another key
+func (ctx *Context) checkKeyID(keyID string) error {
+	if ctx.KeyID != "" && keyID != ctx.KeyID {
+		return &envelope.KeyMismatchError{Expected: ctx.KeyID, Actual: keyID}
+	}
+	return nil
+}
+
+// paramsHash returns the fingerprint of ctx.Params written into envelopes
+func (ctx *Context) paramsHash() (uint64, error) {
+	return envelope.ParamsHash(ctx.Params)
+}
+
+// defaultContext returns a Context built from package-level variables
+func defaultContext() *Context {
+	return &Context{
+		Params:    CkksParams,
+		EvalKey:   CkksEvalkey,
+		Evaluator: CkksEvaluator,
+	}
+}
+
+// AddConst Same as Context.AddConst, using package-level variables
+func AddConst(encryptedData []byte, addValue float64) ([]byte, error) {
+	return defaultContext().AddConst(encryptedData, addValue)
+}
+
+// SubtractConst Same as Context.SubtractConst, using package-level variables
+func SubtractConst(encryptedData []byte, subValue float64) ([]byte, error) {
+	return defaultContext().SubtractConst(encryptedData, subValue)
+}
+
+// MultByConst Same as Context.MultByConst, using package-level variables
+func MultByConst(encryptedData []byte, multValue float64) ([]byte, error) {
+	return defaultContext().MultByConst(encryptedData, multValue)
+}
+
+// DivByConst Same as Context.DivByConst, using package-level variables
+func DivByConst(encryptedDataDividend []byte, encryptedDataDivisor float64) ([]byte, error) {
+	return defaultContext().DivByConst(encryptedDataDividend, encryptedDataDivisor)
+}
+
+// Sum Same as Context.Sum, using package-level variables
+func Sum(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	return defaultContext().Sum(encryptedData, encryptedData2)
+}
+
+// Subtract Same as Context.Subtract, using package-level variables
+func Subtract(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	return defaultContext().Subtract(encryptedData, encryptedData2)
+}
+
+// Mult Same as Context.Mult, using package-level variables
+func Mult(encryptedData []byte, encryptedData2 []byte) ([]byte, error) {
+	return defaultContext().Mult(encryptedData, encryptedData2)
+}
+
+// Pow2 Same as Context.Pow2, using package-level variables
+func Pow2(encryptedData []byte) ([]byte, error) {
+	return defaultContext().Pow2(encryptedData)
+}
+
+// ArraySum Same as Context.ArraySum, using package-level variables
+func ArraySum(encryptedDataArray [][]byte) ([]byte, error) {
+	return defaultContext().ArraySum(encryptedDataArray)
+}
+
+// ArrayMean Same as Context.ArrayMean, using package-level variables
+func ArrayMean(encryptedDataArray [][]byte) ([]byte, error) {
+	return defaultContext().ArrayMean(encryptedDataArray)
+}
+
+// MovingAverage Same as Context.MovingAverage, using package-level variables
+func MovingAverage(encryptedDataArray [][]byte, windowSize int) ([][]byte, error) {
+	return defaultContext().MovingAverage(encryptedDataArray, windowSize)
+}
+
+// Variance Same as Context.Variance, using package-level variables
+func Variance(encryptedDataArray [][]byte) ([]byte, error) {
+	return defaultContext().Variance(encryptedDataArray)
+}
+
+// Covariance Same as Context.Covariance, using package-level variables
+func Covariance(encryptedDataArray1 [][]byte, encryptedDataArray2 [][]byte) ([]byte, error) {
+	return defaultContext().Covariance(encryptedDataArray1, encryptedDataArray2)
+}
+
+// ArithmeticProgressionElementN Same as Context.ArithmeticProgressionElementN, using package-level variables
+func ArithmeticProgressionElementN(firstMember []byte, dif []byte, n []byte) ([]byte, error) {
+	return defaultContext().ArithmeticProgressionElementN(firstMember, dif, n)
+}
+
+// ArithmeticProgressionSum Same as Context.ArithmeticProgressionSum, using package-level variables
+func ArithmeticProgressionSum(firstMember []byte, dif []byte, numberOfMembers []byte) ([]byte, error) {
+	return defaultContext().ArithmeticProgressionSum(firstMember, dif, numberOfMembers)
+}
+
+// SlotSum Same as Context.SlotSum, using package-level variables
+func SlotSum(encryptedData []byte, n int) ([]byte, error) {
+	return defaultContext().SlotSum(encryptedData, n)
+}
+
+// SlotMean Same as Context.SlotMean, using package-level variables
+func SlotMean(encryptedData []byte, n int) ([]byte, error) {
+	return defaultContext().SlotMean(encryptedData, n)
+}
+
+// InnerProduct Same as Context.InnerProduct, using package-level variables
+func InnerProduct(encryptedData []byte, encryptedData2 []byte, n int) ([]byte, error) {
+	return defaultContext().InnerProduct(encryptedData, encryptedData2, n)
+}
+
+// EvaluatePolynomial Same as Context.EvaluatePolynomial, using package-level variables
+func EvaluatePolynomial(encryptedData []byte, polynomial *Polynomial) ([]byte, error) {
+	return defaultContext().EvaluatePolynomial(encryptedData, polynomial)
+}
+
+// Sigmoid Same as Context.Sigmoid, using package-level variables
+func Sigmoid(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return defaultContext().Sigmoid(encryptedData, a, b)
+}
+
+// Exp Same as Context.Exp, using package-level variables
+func Exp(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return defaultContext().Exp(encryptedData, a, b)
+}
+
+// Log Same as Context.Log, using package-level variables
+func Log(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return defaultContext().Log(encryptedData, a, b)
+}
+
+// Sqrt Same as Context.Sqrt, using package-level variables
+func Sqrt(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return defaultContext().Sqrt(encryptedData, a, b)
+}
+
+// InvSqrt Same as Context.InvSqrt, using package-level variables
+func InvSqrt(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return defaultContext().InvSqrt(encryptedData, a, b)
+}
+
+// Inverse Same as Context.Inverse, using package-level variables
+func Inverse(encryptedData []byte, a float64, b float64) ([]byte, error) {
+	return defaultContext().Inverse(encryptedData, a, b)
+}
+
+// StdDev Same as Context.StdDev, using package-level variables
+func StdDev(encryptedDataArray [][]byte, a float64, b float64) ([]byte, error) {
+	return defaultContext().StdDev(encryptedDataArray, a, b)
+}
+
+// PearsonCorrelation Same as Context.PearsonCorrelation, using package-level variables
+func PearsonCorrelation(encryptedDataArray1 [][]byte, encryptedDataArray2 [][]byte, a float64, b float64) ([]byte, error) {
+	return defaultContext().PearsonCorrelation(encryptedDataArray1, encryptedDataArray2, a, b)
+}
+
+// ZScoreNormalize Same as Context.ZScoreNormalize, using package-level variables
+func ZScoreNormalize(encryptedDataArray [][]byte, a float64, b float64) ([][]byte, error) {
+	return defaultContext().ZScoreNormalize(encryptedDataArray, a, b)
+}
+
+// Sign Same as Context.Sign, using package-level variables
+func Sign(encryptedData []byte, bound float64) ([]byte, error) {
+	return defaultContext().Sign(encryptedData, bound)
+}
+
+// Compare Same as Context.Compare, using package-level variables
+func Compare(encryptedData []byte, encryptedData2 []byte, bound float64) ([]byte, error) {
+	return defaultContext().Compare(encryptedData, encryptedData2, bound)
+}
+
+// Max Same as Context.Max, using package-level variables
+func Max(encryptedData []byte, encryptedData2 []byte, bound float64) ([]byte, error) {
+	return defaultContext().Max(encryptedData, encryptedData2, bound)
+}
+
+// Min Same as Context.Min, using package-level variables
+func Min(encryptedData []byte, encryptedData2 []byte, bound float64) ([]byte, error) {
+	return defaultContext().Min(encryptedData, encryptedData2, bound)
+}
+
+// ArrayMax Same as Context.ArrayMax, using package-level variables
+func ArrayMax(encryptedDataArray [][]byte, bound float64) ([]byte, error) {
+	return defaultContext().ArrayMax(encryptedDataArray, bound)
+}
+
+// ArrayMin Same as Context.ArrayMin, using package-level variables
+func ArrayMin(encryptedDataArray [][]byte, bound float64) ([]byte, error) {
+	return defaultContext().ArrayMin(encryptedDataArray, bound)
+}
+
+// LinearRegression Same as Context.LinearRegression, using package-level variables
+func LinearRegression(encryptedX [][]byte, encryptedY [][]byte, a float64, b float64) ([][]byte, error) {
+	return defaultContext().LinearRegression(encryptedX, encryptedY, a, b)
+}
+
+// LinearRegressionStatistics Same as Context.LinearRegressionStatistics, using package-level variables
+func LinearRegressionStatistics(encryptedFeatures [][][]byte, encryptedTarget [][]byte) ([][]byte, error) {
+	return defaultContext().LinearRegressionStatistics(encryptedFeatures, encryptedTarget)
+}
+
+// LinearRegressionGradientDescent Same as Context.LinearRegressionGradientDescent, using package-level variables
+func LinearRegressionGradientDescent(encryptedFeatures [][][]byte, encryptedTarget [][]byte, rate float64, iterations int) ([][]byte, error) {
+	return defaultContext().LinearRegressionGradientDescent(encryptedFeatures, encryptedTarget, rate, iterations)
+}
+
+// PredictLinear Same as Context.PredictLinear, using package-level variables
+func PredictLinear(encryptedFeatures [][]byte, model *LinearModel) ([]byte, error) {
+	return defaultContext().PredictLinear(encryptedFeatures, model)
+}
+
+// PredictLinearEncrypted Same as Context.PredictLinearEncrypted, using package-level variables
+func PredictLinearEncrypted(encryptedFeatures [][]byte, encryptedModel [][]byte) ([]byte, error) {
+	return defaultContext().PredictLinearEncrypted(encryptedFeatures, encryptedModel)
+}
+
+// MultByVector Same as Context.MultByVector, using package-level variables
+func MultByVector(encryptedData []byte, values []float64) ([]byte, error) {
+	return defaultContext().MultByVector(encryptedData, values)
+}
+
+// PredictLogistic Same as Context.PredictLogistic, using package-level variables
+func PredictLogistic(encryptedFeatures []byte, model *LinearModel, a float64, b float64) ([]byte, error) {
+	return defaultContext().PredictLogistic(encryptedFeatures, model, a, b)
+}
+
+// MatVec Same as Context.MatVec, using package-level variables
+func MatVec(matrix [][]float64, encryptedVector []byte) ([]byte, error) {
+	return defaultContext().MatVec(matrix, encryptedVector)
+}
+
+// MatVecEncrypted Same as Context.MatVecEncrypted, using package-level variables
+func MatVecEncrypted(encryptedMatrix [][]byte, encryptedVector []byte) ([]byte, error) {
+	return defaultContext().MatVecEncrypted(encryptedMatrix, encryptedVector)
+}
+
+// MatMul Same as Context.MatMul, using package-level variables
+func MatMul(matrix [][]float64, encryptedColumns [][]byte) ([][]byte, error) {
+	return defaultContext().MatMul(matrix, encryptedColumns)
+}
+
+// MatMulEncrypted Same as Context.MatMulEncrypted, using package-level variables
+func MatMulEncrypted(encryptedMatrix [][]byte, encryptedColumns [][]byte) ([][]byte, error) {
+	return defaultContext().MatMulEncrypted(encryptedMatrix, encryptedColumns)
+}
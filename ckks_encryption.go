@@ -1,33 +1,155 @@
 package homomorphicEncryption
 
 import (
+	"fmt"
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
 	"github.com/ldsec/lattigo/v2/ckks"
 )
 
 // EncryptCKKS Encrypts float64 data into []byte using CKKS algorithm
 func EncryptCKKS(data float64) ([]byte, error) {
-	encoder := ckks.NewEncoder(CkksParams)
-	encryptor := ckks.NewEncryptor(CkksParams, CkksKeys.Pk)
-
-	plaintext := ckks.NewPlaintext(CkksParams, CkksParams.MaxLevel(), CkksParams.DefaultScale())
-	encoder.Encode([]float64{data}, plaintext, CkksParams.LogSlots())
-
-	ciphertext := encryptor.EncryptNew(plaintext)
-	return ciphertext.MarshalBinary()
+	return globalContext().EncryptCKKS(data)
 }
 
 // DecryptCKKS Decrypts data encrypted with CKKS algorithm into a float64
 func DecryptCKKS(data []byte) (float64, error) {
-	decryptor := ckks.NewDecryptor(CkksParams, CkksKeys.Sk)
-	ciphertext := ckks.NewCiphertext(CkksParams, 1, CkksParams.MaxLevel(), CkksParams.DefaultScale())
-	err := ciphertext.UnmarshalBinary(data)
+	return globalContext().DecryptCKKS(data)
+}
+
+// EncryptCKKSVector Encrypts up to CkksSlots() float64 values into a single ciphertext
+// using CKKS algorithm, one value per slot
+func EncryptCKKSVector(data []float64) ([]byte, error) {
+	return globalContext().EncryptCKKSVector(data)
+}
+
+// DecryptCKKSVector Decrypts data encrypted with CKKS algorithm into a []float64
+// containing all CkksSlots() slots
+func DecryptCKKSVector(data []byte) ([]float64, error) {
+	return globalContext().DecryptCKKSVector(data)
+}
+
+// FitLinearRegression Same as Context.FitLinearRegression, using package-level variables
+func FitLinearRegression(statistics [][]byte) (*ckksMath.LinearModel, error) {
+	return globalContext().FitLinearRegression(statistics)
+}
+
+// EncryptCKKSMatrix Encrypts diagonals of matrix, see ckksMath.MatrixDiagonals
+func EncryptCKKSMatrix(matrix [][]float64) ([][]byte, error) {
+	return globalContext().EncryptCKKSMatrix(matrix)
+}
+
+// CkksSlots Returns the number of values a single CKKS ciphertext can hold
+func CkksSlots() int {
+	return CkksParams.Slots()
+}
+
+// EncryptCKKS Encrypts float64 data into []byte using CKKS algorithm and ctx keys
+func (ctx *Context) EncryptCKKS(data float64) ([]byte, error) {
+	return ctx.EncryptCKKSVector([]float64{data})
+}
+
+// DecryptCKKS Decrypts data encrypted with CKKS algorithm into a float64 using ctx keys
+func (ctx *Context) DecryptCKKS(data []byte) (float64, error) {
+	decoded, err := ctx.DecryptCKKSVector(data)
 	if err != nil {
 		return 0, err
 	}
+	return decoded[0], nil
+}
+
+// CkksSlots Returns the number of values a single CKKS ciphertext of ctx can hold
+func (ctx *Context) CkksSlots() int {
+	return ctx.CkksParams.Slots()
+}
+
+// EncryptCKKSVector Encrypts up to ctx.CkksSlots() float64 values into a single ciphertext
+// using CKKS algorithm and ctx keys. Unused slots are filled with zeros
+func (ctx *Context) EncryptCKKSVector(data []float64) ([]byte, error) {
+	if len(data) > ctx.CkksSlots() {
+		return nil, fmt.Errorf("cannot encrypt %d values into %d slots", len(data), ctx.CkksSlots())
+	}
+
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	encoder, encryptor, _ := ctx.ckksPrimitives()
+	if encryptor == nil {
+		return nil, errNoPublicKey
+	}
+
+	plaintext := ckks.NewPlaintext(ctx.CkksParams, ctx.CkksParams.MaxLevel(), ctx.CkksParams.DefaultScale())
+	encoder.Encode(data, plaintext, ctx.CkksParams.LogSlots())
+
+	ciphertext := encryptor.EncryptNew(plaintext)
+	ciphertextData, err := ciphertext.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return ctx.seal(envelope.SchemeCKKS, ciphertextData)
+}
+
+// DecryptCKKSVector Decrypts data encrypted with CKKS algorithm into a []float64
+// containing all ctx.CkksSlots() slots using ctx keys
+func (ctx *Context) DecryptCKKSVector(data []byte) ([]float64, error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	encoder, _, decryptor := ctx.ckksPrimitives()
+	if decryptor == nil {
+		return nil, errNoSecretKey
+	}
+
+	payload, err := ctx.open(envelope.SchemeCKKS, data)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := ckks.NewCiphertext(ctx.CkksParams, 1, ctx.CkksParams.MaxLevel(), ctx.CkksParams.DefaultScale())
+	err = ciphertext.UnmarshalBinary(payload)
+	if err != nil {
+		return nil, err
+	}
 
 	plaintext := decryptor.DecryptNew(ciphertext)
-	encoder := ckks.NewEncoder(CkksParams)
-	decoded := encoder.Decode(plaintext, CkksParams.LogSlots())
+	decoded := encoder.Decode(plaintext, ctx.CkksParams.LogSlots())
+
+	result := make([]float64, len(decoded))
+	for i, value := range decoded {
+		result[i] = real(value)
+	}
+	return result, nil
+}
+
+// EncryptCKKSMatrix Encrypts diagonals of matrix using ctx keys, one ciphertext per diagonal,
+// for ckksMath.MatVecEncrypted and ckksMath.MatMulEncrypted
+func (ctx *Context) EncryptCKKSMatrix(matrix [][]float64) ([][]byte, error) {
+	diagonals, err := ckksMath.MatrixDiagonals(matrix)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := make([][]byte, len(diagonals))
+	for i, diagonal := range diagonals {
+		encrypted[i], err = ctx.EncryptCKKSVector(diagonal)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return encrypted, nil
+}
 
-	return real(decoded[0]), nil
+// FitLinearRegression Decrypts statistics returned by ckksMath.LinearRegressionStatistics using
+// ctx keys and fits the linear regression model from them in plaintext, see ckksMath.SolveLinearRegression.
+// The decrypted covariances and means of features and target are revealed to the caller along with the model
+func (ctx *Context) FitLinearRegression(statistics [][]byte) (*ckksMath.LinearModel, error) {
+	decrypted := make([]float64, len(statistics))
+	for i, data := range statistics {
+		var err error
+		decrypted[i], err = ctx.DecryptCKKS(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ckksMath.SolveLinearRegression(decrypted)
 }
@@ -0,0 +1,348 @@
+package homomorphicEncryption
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ldsec/lattigo/v2/bfv"
+	"github.com/ldsec/lattigo/v2/ckks"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrNoDecryptedResult Returned when a decryption response has no decrypted result
+var ErrNoDecryptedResult = errors.New("server response has no decrypted result")
+
+// ServerError Error response of a server, carrying its HTTP status and error message.
+// Errors with http.StatusUnauthorized match ErrUnauthenticated with errors.Is
+type ServerError struct {
+	StatusCode int
+	Status     string
+	// Message The error field of the response, empty if the response had none
+	Message string
+}
+
+func (err *ServerError) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("server responded with %s", err.Status)
+	}
+	return fmt.Sprintf("server responded with %s: %s", err.Status, err.Message)
+}
+
+// Is Returns true for ErrUnauthenticated if the server rejected the credentials of the client
+func (err *ServerError) Is(target error) bool {
+	return target == ErrUnauthenticated && err.StatusCode == http.StatusUnauthorized
+}
+
+// Client Client of a server created by NewServer, mounted at a base URL. Unlike the package-level
+// client helpers, a Client doesn't share its configuration with the rest of the process.
+// Safe for concurrent use
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	apiToken   string
+	retries    int
+	backoff    time.Duration
+
+	rootCAs      *x509.CertPool
+	certificates []tls.Certificate
+	timeout      time.Duration
+}
+
+// ClientOption Configures a Client created by NewClient
+type ClientOption func(*Client)
+
+// WithRootCAs Makes the Client trust server certificates signed by CAs of pool instead of the system CAs
+func WithRootCAs(pool *x509.CertPool) ClientOption {
+	return func(client *Client) {
+		client.rootCAs = pool
+	}
+}
+
+// WithClientCertificate Makes the Client present certificate to servers requiring mutual TLS
+func WithClientCertificate(certificate tls.Certificate) ClientOption {
+	return func(client *Client) {
+		client.certificates = []tls.Certificate{certificate}
+	}
+}
+
+// WithAPIToken Makes the Client authenticate with bearer API token
+func WithAPIToken(token string) ClientOption {
+	return func(client *Client) {
+		client.apiToken = token
+	}
+}
+
+// WithRequestTimeout Limits every attempt of a request to timeout, including reading the response.
+// Defaults to 1 minute
+func WithRequestTimeout(timeout time.Duration) ClientOption {
+	return func(client *Client) {
+		client.timeout = timeout
+	}
+}
+
+// WithRetries Makes the Client retry failed requests up to retries times, waiting backoff before
+// the first retry and twice as long before every next one. Only failed dials,
+// http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable and
+// http.StatusGatewayTimeout responses, and for GET requests also timeouts and reset connections
+// are retried. Other errors, such as failed certificate verification or TLS alerts of a server
+// rejecting the client certificate, are returned right away
+func WithRetries(retries int, backoff time.Duration) ClientOption {
+	return func(client *Client) {
+		client.retries = retries
+		client.backoff = backoff
+	}
+}
+
+// WithHTTPClient Makes the Client send requests with httpClient, ignoring WithRootCAs,
+// WithClientCertificate and WithRequestTimeout
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(client *Client) {
+		client.httpClient = httpClient
+	}
+}
+
+// NewClient Creates a Client of the server at baseURL, including the route prefix of the server,
+// e.g. "https://gateway.example.com/he/v1"
+func NewClient(baseURL string, options ...ClientOption) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("base URL '%s' must be absolute", baseURL)
+	}
+
+	client := &Client{baseURL: parsed, timeout: time.Minute}
+	for _, option := range options {
+		option(client)
+	}
+
+	if client.httpClient == nil {
+		client.httpClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					RootCAs:      client.rootCAs,
+					Certificates: client.certificates,
+					MinVersion:   tls.VersionTLS12,
+				},
+			},
+			Timeout: client.timeout,
+		}
+	}
+	return client, nil
+}
+
+// LoadCertPool Loads CA certificates from PEM file caFile, e.g. for WithRootCAs
+func LoadCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// CkksParams Retrieves CKKS parameters from the server
+func (client *Client) CkksParams(ctx context.Context) (ckks.Parameters, error) {
+	body, err := client.do(ctx, http.MethodGet, "get_ckks_params", nil)
+	if err != nil {
+		return ckks.Parameters{}, err
+	}
+	return decodeCkksParams(body)
+}
+
+// BfvParams Retrieves BFV parameters from the server
+func (client *Client) BfvParams(ctx context.Context) (bfv.Parameters, error) {
+	body, err := client.do(ctx, http.MethodGet, "get_bfv_params", nil)
+	if err != nil {
+		return bfv.Parameters{}, err
+	}
+	return decodeBfvParams(body)
+}
+
+// CkksEvalKeys Retrieves CKKS eval keys from the server
+func (client *Client) CkksEvalKeys(ctx context.Context) (EvalKeys, error) {
+	body, err := client.do(ctx, http.MethodGet, "get_ckks_eval_keys", nil)
+	if err != nil {
+		return EvalKeys{}, err
+	}
+	return decodeCkksEvalKeys(body)
+}
+
+// BfvEvalKeys Retrieves BFV eval keys from the server
+func (client *Client) BfvEvalKeys(ctx context.Context) (EvalKeys, error) {
+	body, err := client.do(ctx, http.MethodGet, "get_bfv_eval_keys", nil)
+	if err != nil {
+		return EvalKeys{}, err
+	}
+	return decodeBfvEvalKeys(body)
+}
+
+// DecryptCkks Sends a CKKS computation result to the server and returns the decrypted result
+func (client *Client) DecryptCkks(ctx context.Context, encryptedResult []byte) (float64, error) {
+	return client.DecryptVerifiableCkks(ctx, encryptedResult, nil)
+}
+
+// DecryptBfv Sends a BFV computation result to the server and returns the decrypted result
+func (client *Client) DecryptBfv(ctx context.Context, encryptedResult []byte) (int64, error) {
+	return client.DecryptVerifiableBfv(ctx, encryptedResult, nil)
+}
+
+// DecryptVerifiableCkks Sends a CKKS computation result along with the transcript of its computation
+// to a server with a DecryptionPolicy and returns the decrypted result
+func (client *Client) DecryptVerifiableCkks(ctx context.Context, encryptedResult []byte, transcript *ComputationTranscript) (float64, error) {
+	body, err := client.decrypt(ctx, "decrypt_computations_ckks", decryptRequest{encryptedResult, transcript})
+	if err != nil {
+		return 0.0, err
+	}
+	return decodeDecryptedResult[float64](body)
+}
+
+// DecryptVerifiableBfv Sends a BFV computation result along with the transcript of its computation
+// to a server with a DecryptionPolicy and returns the decrypted result
+func (client *Client) DecryptVerifiableBfv(ctx context.Context, encryptedResult []byte, transcript *ComputationTranscript) (int64, error) {
+	body, err := client.decrypt(ctx, "decrypt_computations_bfv", decryptRequest{encryptedResult, transcript})
+	if err != nil {
+		return 0, err
+	}
+	return decodeDecryptedResult[int64](body)
+}
+
+// DecryptBatchCkks Sends several CKKS computation results to the server in a single request and
+// returns decrypted results along with per-result errors, which are nil for decrypted results
+// and *ServerError for the others. The error is only returned if the whole request failed
+func (client *Client) DecryptBatchCkks(ctx context.Context, encryptedResults [][]byte) ([]float64, []error, error) {
+	return client.DecryptVerifiableBatchCkks(ctx, encryptedResults, nil)
+}
+
+// DecryptBatchBfv Sends several BFV computation results to the server in a single request,
+// see DecryptBatchCkks
+func (client *Client) DecryptBatchBfv(ctx context.Context, encryptedResults [][]byte) ([]int64, []error, error) {
+	return client.DecryptVerifiableBatchBfv(ctx, encryptedResults, nil)
+}
+
+// DecryptVerifiableBatchCkks Same as DecryptBatchCkks, sending transcripts[i] along with
+// encryptedResults[i] to a server with a DecryptionPolicy
+func (client *Client) DecryptVerifiableBatchCkks(ctx context.Context, encryptedResults [][]byte, transcripts []*ComputationTranscript) ([]float64, []error, error) {
+	return decryptBatch[float64](ctx, client, "decrypt_computations_ckks/batch", encryptedResults, transcripts)
+}
+
+// DecryptVerifiableBatchBfv Same as DecryptBatchBfv, sending transcripts[i] along with
+// encryptedResults[i] to a server with a DecryptionPolicy
+func (client *Client) DecryptVerifiableBatchBfv(ctx context.Context, encryptedResults [][]byte, transcripts []*ComputationTranscript) ([]int64, []error, error) {
+	return decryptBatch[int64](ctx, client, "decrypt_computations_bfv/batch", encryptedResults, transcripts)
+}
+
+// decryptBatch posts encryptedResults with transcripts to route of the server of client
+// and decodes the response
+func decryptBatch[T int64 | float64](ctx context.Context, client *Client, route string, encryptedResults [][]byte, transcripts []*ComputationTranscript) ([]T, []error, error) {
+	req, err := newBatchDecryptRequest(encryptedResults, transcripts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, err := client.do(ctx, http.MethodPost, route, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodeBatchDecryptedResults[T](body, len(encryptedResults))
+}
+
+// decrypt posts req to route, returning the response body
+func (client *Client) decrypt(ctx context.Context, route string, req decryptRequest) ([]byte, error) {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	return client.do(ctx, http.MethodPost, route, data)
+}
+
+// do sends a request to route of the server, retrying it as configured by WithRetries,
+// and returns the response body
+func (client *Client) do(ctx context.Context, method string, route string, body []byte) ([]byte, error) {
+	// package-level client helpers pass full route URLs, which are sent as they are
+	target := client.baseURL.String()
+	if route != "" {
+		target = client.baseURL.JoinPath(route).String()
+	}
+
+	backoff := client.backoff
+	for attempt := 0; ; attempt++ {
+		data, err := doRequest(ctx, client.httpClient, client.apiToken, method, target, body)
+		if err == nil || attempt >= client.retries || !retryable(ctx, method, err) {
+			return data, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// retryable returns true if a method request failing with err may succeed when sent again.
+// Failed dials are retried for every request, as nothing was sent. Timeouts and connections
+// reset or closed before the response was read are only retried for requests safe to repeat
+func retryable(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var serverErr *ServerError
+	if errors.As(err, &serverErr) {
+		switch serverErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	// certificates failing verification and TLS alerts of the server, e.g. rejecting the
+	// client certificate, fail the same way on the next attempt
+	var verificationErr *tls.CertificateVerificationError
+	var alertErr tls.AlertError
+	var opErr *net.OpError
+	if errors.As(err, &verificationErr) || errors.As(err, &alertErr) {
+		return false
+	}
+	if errors.As(err, &opErr) {
+		switch opErr.Op {
+		case "dial":
+			return true
+		case "remote error":
+			return false
+		}
+	}
+
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,357 @@
+package homomorphicEncryption
+
+import (
+	"errors"
+	"github.com/SamBridgess/homomorphicEncryption/bfvMath"
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"github.com/ldsec/lattigo/v2/bfv"
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/rlwe"
+	"sync"
+	"sync/atomic"
+)
+
+// Context Owns CKKS and BFV parameters, keys, encoders, encryptors, decryptors and
+// evaluators. Unlike SetupServer and SetupClient, which write into package-level
+// variables, any number of Contexts with different keys can be used in one process.
+// Params and keys must not be changed directly after the Context is created,
+// use Context methods such as LoadOrGenerateKeys instead
+type Context struct {
+	CkksParams ckks.Parameters
+	BfvParams  bfv.Parameters
+
+	CkksKeys KeyPair
+	BfvKeys  KeyPair
+
+	EvalKeysCkks EvalKeys
+	EvalKeysBfv  EvalKeys
+
+	// RotationSlots Number of leading slots slot-wise aggregates can span, see ContextConfig
+	RotationSlots int
+
+	// KeyID ID of the key version of ctx, written into every ciphertext ctx encrypts.
+	// Ciphertexts encrypted with other key versions are rejected. Empty for Contexts
+	// created without a KeyRing
+	KeyID string
+
+	// AcceptLegacyCiphertexts If set, ciphertexts written before envelopes recorded scheme and
+	// parameters are decrypted and evaluated without checking them, see ContextConfig
+	AcceptLegacyCiphertexts bool
+
+	// Ckks and Bfv provide all ckksMath and bfvMath operations
+	Ckks *ckksMath.Context
+	Bfv  *bfvMath.Context
+
+	mu            sync.Mutex
+	ckksEncoder   ckks.Encoder
+	ckksEncryptor ckks.Encryptor
+	ckksDecryptor ckks.Decryptor
+	bfvEncoder    bfv.Encoder
+	bfvEncryptor  bfv.Encryptor
+	bfvDecryptor  bfv.Decryptor
+}
+
+// ContextConfig Settings of a server side Context
+type ContextConfig struct {
+	CkksParams ckks.Parameters
+	BfvParams  bfv.Parameters
+
+	// RotationSlots Number of leading slots slot-wise aggregates (SlotSum, InnerProduct etc.)
+	// can span. Rotation keys are generated for every power of two below it. With default
+	// parameters each key takes about 15 MB, and all of them are sent to clients with the eval
+	// keys, so keep it as low as the longest aggregated vector. Zero and 1 generate no rotation
+	// keys, RotationSlotsAll generates keys for all slots, e.g. for matrix operations
+	RotationSlots int
+
+	// AcceptLegacyCiphertexts If set, ciphertexts written before envelopes recorded scheme and
+	// parameters, e.g. bare lattigo ciphertexts stored by earlier releases, are accepted without
+	// checking them. Keep it off unless such data has to be read, e.g. to re-encrypt it
+	AcceptLegacyCiphertexts bool
+}
+
+// RotationSlotsAll Value of ContextConfig.RotationSlots generating rotation keys for all slots
+const RotationSlotsAll = -1
+
+// DefaultContextConfig Returns ContextConfig with default parameters and no rotation keys
+func DefaultContextConfig() (ContextConfig, error) {
+	ckksParams, bfvParams, err := DefaultParams()
+	if err != nil {
+		return ContextConfig{}, err
+	}
+	return ContextConfig{CkksParams: ckksParams, BfvParams: bfvParams}, nil
+}
+
+// DefaultParams Returns CKKS and BFV parameters used by SetupServer and NewServerContext
+func DefaultParams() (ckks.Parameters, bfv.Parameters, error) {
+	ckksParams, err := ckks.NewParametersFromLiteral(ckks.PN14QP438)
+	if err != nil {
+		return ckks.Parameters{}, bfv.Parameters{}, err
+	}
+	bfvParams, err := bfv.NewParametersFromLiteral(bfv.PN14QP438)
+	if err != nil {
+		return ckks.Parameters{}, bfv.Parameters{}, err
+	}
+	return ckksParams, bfvParams, nil
+}
+
+// NewServerContext Creates a server side Context with default parameters. Keys are
+// loaded from files or generated and saved to files if such locations don't exist
+func NewServerContext(ckksKeysFileLocation string, bfvKeysFileLocation string) (*Context, error) {
+	ckksParams, bfvParams, err := DefaultParams()
+	if err != nil {
+		return nil, err
+	}
+	return NewServerContextWithParams(ckksParams, bfvParams, ckksKeysFileLocation, bfvKeysFileLocation)
+}
+
+// NewServerContextWithParams Same as NewServerContext, but with custom parameters,
+// e.g. deeper ones for long chains of multiplications
+func NewServerContextWithParams(ckksParams ckks.Parameters, bfvParams bfv.Parameters, ckksKeysFileLocation string, bfvKeysFileLocation string) (*Context, error) {
+	config := ContextConfig{
+		CkksParams: ckksParams,
+		BfvParams:  bfvParams,
+	}
+	return NewServerContextWithConfig(config, ckksKeysFileLocation, bfvKeysFileLocation)
+}
+
+// NewServerContextWithConfig Same as NewServerContext, but with all settings taken from config
+func NewServerContextWithConfig(config ContextConfig, ckksKeysFileLocation string, bfvKeysFileLocation string) (*Context, error) {
+	ctx := &Context{
+		CkksParams:              config.CkksParams,
+		BfvParams:               config.BfvParams,
+		RotationSlots:           config.RotationSlots,
+		AcceptLegacyCiphertexts: config.AcceptLegacyCiphertexts,
+	}
+	if err := ctx.LoadOrGenerateKeys(ckksKeysFileLocation, CKKS); err != nil {
+		return nil, err
+	}
+	if err := ctx.LoadOrGenerateKeys(bfvKeysFileLocation, BFV); err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// NewServerContextWithKeyStore Same as NewServerContextWithConfig, but keys are
+// loaded from store or generated and saved to store if it doesn't have them
+func NewServerContextWithKeyStore(config ContextConfig, store KeyStore) (*Context, error) {
+	return newServerContextFromStore(config, store, "", true)
+}
+
+// newServerContextFromStore creates a server side Context for key version keyID,
+// loading its keys from store. Missing keys are generated if generate is set
+func newServerContextFromStore(config ContextConfig, store KeyStore, keyID string, generate bool) (*Context, error) {
+	ctx := &Context{
+		CkksParams:              config.CkksParams,
+		BfvParams:               config.BfvParams,
+		RotationSlots:           config.RotationSlots,
+		AcceptLegacyCiphertexts: config.AcceptLegacyCiphertexts,
+		KeyID:                   keyID,
+	}
+	for _, method := range []Method{CKKS, BFV} {
+		var err error
+		if generate {
+			err = ctx.LoadOrGenerateKeysFromStore(store, method)
+		} else {
+			err = ctx.loadKeysFromStore(store, method)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ctx, nil
+}
+
+// NewClientContext Creates a client side Context from parameters and evaluation keys
+// retrieved from server. Such Context can perform computations, but can't encrypt
+// or decrypt data
+func NewClientContext(ckksParams ckks.Parameters, bfvParams bfv.Parameters, ckksEvalKey rlwe.EvaluationKey, bfvEvalKey rlwe.EvaluationKey) *Context {
+	ctx := &Context{
+		CkksParams:   ckksParams,
+		BfvParams:    bfvParams,
+		EvalKeysCkks: EvalKeys{EvalKey1: ckksEvalKey},
+		EvalKeysBfv:  EvalKeys{EvalKey1: bfvEvalKey},
+	}
+	ctx.resetCkks()
+	ctx.resetBfv()
+	return ctx
+}
+
+// globalCache Context built from package-level variables, created once on first use
+type globalCache struct {
+	once sync.Once
+	ctx  *Context
+}
+
+// globalState current globalCache, replaced whenever package-level variables change
+var globalState atomic.Pointer[globalCache]
+
+// globalContext returns the Context built from package-level variables. It is built once
+// and reused, until key loading or direct assignment changes the variables
+func globalContext() *Context {
+	for {
+		cache := globalState.Load()
+		if cache == nil {
+			globalState.CompareAndSwap(nil, &globalCache{})
+			continue
+		}
+
+		cache.once.Do(func() {
+			cache.ctx = newGlobalContext()
+		})
+		if cache.ctx.matchesGlobals() {
+			return cache.ctx
+		}
+		globalState.CompareAndSwap(cache, &globalCache{})
+	}
+}
+
+// newGlobalContext returns a new Context built from package-level variables, for
+// package-level functions changing keys, which mustn't change the shared Context
+func newGlobalContext() *Context {
+	return &Context{
+		CkksParams:   CkksParams,
+		BfvParams:    BfvParams,
+		CkksKeys:     CkksKeys,
+		BfvKeys:      BfvKeys,
+		EvalKeysCkks: EvalKeysCkks,
+		EvalKeysBfv:  EvalKeysBfv,
+	}
+}
+
+// invalidateGlobalContext makes the next globalContext call rebuild the Context
+func invalidateGlobalContext() {
+	globalState.Store(&globalCache{})
+}
+
+// matchesGlobals returns true if ctx was built from the current package-level variables
+func (ctx *Context) matchesGlobals() bool {
+	return ctx.CkksKeys == CkksKeys && ctx.BfvKeys == BfvKeys &&
+		ctx.EvalKeysCkks.EvalKey1 == EvalKeysCkks.EvalKey1 && ctx.EvalKeysBfv.EvalKey1 == EvalKeysBfv.EvalKey1 &&
+		ctx.CkksParams.Equals(CkksParams) && ctx.BfvParams.Equals(BfvParams)
+}
+
+// setGlobals copies params and keys of ctx into package-level variables
+func (ctx *Context) setGlobals() {
+	CkksParams = ctx.CkksParams
+	BfvParams = ctx.BfvParams
+	CkksKeys = ctx.CkksKeys
+	BfvKeys = ctx.BfvKeys
+	EvalKeysCkks = ctx.EvalKeysCkks
+	EvalKeysBfv = ctx.EvalKeysBfv
+	invalidateGlobalContext()
+}
+
+// resetCkks recreates CKKS encoder, encryptor, decryptor and evaluator after
+// params or keys have changed
+func (ctx *Context) resetCkks() {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	ctx.ckksEncoder = ckks.NewEncoder(ctx.CkksParams)
+	ctx.ckksEncryptor = nil
+	if ctx.CkksKeys.Pk != nil {
+		ctx.ckksEncryptor = ckks.NewEncryptor(ctx.CkksParams, ctx.CkksKeys.Pk)
+	}
+	ctx.ckksDecryptor = nil
+	if ctx.CkksKeys.Sk != nil {
+		ctx.ckksDecryptor = ckks.NewDecryptor(ctx.CkksParams, ctx.CkksKeys.Sk)
+	}
+	ctx.Ckks = ckksMath.NewContext(ctx.CkksParams, ctx.EvalKeysCkks.EvalKey1)
+	ctx.Ckks.KeyID = ctx.KeyID
+	ctx.Ckks.AcceptLegacyCiphertexts = ctx.AcceptLegacyCiphertexts
+}
+
+// resetBfv recreates BFV encoder, encryptor, decryptor and evaluator after
+// params or keys have changed
+func (ctx *Context) resetBfv() {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+
+	ctx.bfvEncoder = bfv.NewEncoder(ctx.BfvParams)
+	ctx.bfvEncryptor = nil
+	if ctx.BfvKeys.Pk != nil {
+		ctx.bfvEncryptor = bfv.NewEncryptor(ctx.BfvParams, ctx.BfvKeys.Pk)
+	}
+	ctx.bfvDecryptor = nil
+	if ctx.BfvKeys.Sk != nil {
+		ctx.bfvDecryptor = bfv.NewDecryptor(ctx.BfvParams, ctx.BfvKeys.Sk)
+	}
+	ctx.Bfv = bfvMath.NewContext(ctx.BfvParams, ctx.EvalKeysBfv.EvalKey1)
+	ctx.Bfv.KeyID = ctx.KeyID
+	ctx.Bfv.AcceptLegacyCiphertexts = ctx.AcceptLegacyCiphertexts
+}
+
+// ckksPrimitives returns CKKS encoder, encryptor and decryptor, creating them on first
+// use for Contexts that were not built with a constructor. Must be called with ctx.mu held
+func (ctx *Context) ckksPrimitives() (ckks.Encoder, ckks.Encryptor, ckks.Decryptor) {
+	if ctx.ckksEncoder == nil {
+		ctx.ckksEncoder = ckks.NewEncoder(ctx.CkksParams)
+		if ctx.CkksKeys.Pk != nil {
+			ctx.ckksEncryptor = ckks.NewEncryptor(ctx.CkksParams, ctx.CkksKeys.Pk)
+		}
+		if ctx.CkksKeys.Sk != nil {
+			ctx.ckksDecryptor = ckks.NewDecryptor(ctx.CkksParams, ctx.CkksKeys.Sk)
+		}
+	}
+	return ctx.ckksEncoder, ctx.ckksEncryptor, ctx.ckksDecryptor
+}
+
+// bfvPrimitives returns BFV encoder, encryptor and decryptor, creating them on first
+// use for Contexts that were not built with a constructor. Must be called with ctx.mu held
+func (ctx *Context) bfvPrimitives() (bfv.Encoder, bfv.Encryptor, bfv.Decryptor) {
+	if ctx.bfvEncoder == nil {
+		ctx.bfvEncoder = bfv.NewEncoder(ctx.BfvParams)
+		if ctx.BfvKeys.Pk != nil {
+			ctx.bfvEncryptor = bfv.NewEncryptor(ctx.BfvParams, ctx.BfvKeys.Pk)
+		}
+		if ctx.BfvKeys.Sk != nil {
+			ctx.bfvDecryptor = bfv.NewDecryptor(ctx.BfvParams, ctx.BfvKeys.Sk)
+		}
+	}
+	return ctx.bfvEncoder, ctx.bfvEncryptor, ctx.bfvDecryptor
+}
+
+// seal wraps ciphertext bytes returned by lattigo MarshalBinary into an envelope
+// with scheme, params of the scheme and ctx.KeyID
+func (ctx *Context) seal(scheme envelope.Scheme, data []byte) ([]byte, error) {
+	paramsHash, err := ctx.paramsHash(scheme)
+	if err != nil {
+		return nil, err
+	}
+	return envelope.Wrap(scheme, paramsHash, ctx.KeyID, data)
+}
+
+// open returns lattigo ciphertext bytes from an envelope, if it was sealed
+// with scheme, params of the scheme and ctx.KeyID
+func (ctx *Context) open(scheme envelope.Scheme, data []byte) ([]byte, error) {
+	paramsHash, err := ctx.paramsHash(scheme)
+	if err != nil {
+		return nil, err
+	}
+	openEnvelope := envelope.Open
+	if ctx.AcceptLegacyCiphertexts {
+		openEnvelope = envelope.OpenLegacy
+	}
+	e, err := openEnvelope(data, scheme, paramsHash)
+	if err != nil {
+		return nil, err
+	}
+	if e.KeyID != ctx.KeyID {
+		return nil, &envelope.KeyMismatchError{Expected: ctx.KeyID, Actual: e.KeyID}
+	}
+	return e.Payload, nil
+}
+
+// paramsHash returns the fingerprint of ctx params of scheme
+func (ctx *Context) paramsHash(scheme envelope.Scheme) (uint64, error) {
+	if scheme == envelope.SchemeCKKS {
+		return envelope.ParamsHash(ctx.CkksParams)
+	}
+	return envelope.ParamsHash(ctx.BfvParams)
+}
+
+var (
+	errNoPublicKey = errors.New("public key is not set")
+	errNoSecretKey = errors.New("secret key is not set")
+)
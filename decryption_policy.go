@@ -0,0 +1,141 @@
+package homomorphicEncryption
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/SamBridgess/homomorphicEncryption/bfvMath"
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"sync"
+)
+
+// Without a policy the decryption endpoints decrypt any ciphertext, so a client could send
+// a stored row and read it in plaintext. A DecryptionPolicy only decrypts results of approved
+// computations over registered inputs. Clients hold no keys the server could trust to sign
+// their computations, so instead a result comes with a ComputationTranscript naming
+// the computation and its inputs, and the server verifies it by recomputing the result:
+// homomorphic operations are deterministic, so an honest transcript reproduces the result
+// byte for byte.
+//
+// A policy limits which results can be decrypted, it doesn't make them anonymous: e.g.
+// sums over two sets of inputs differing in a single row reveal that row. Choose approved
+// computations and their minimum numbers of inputs accordingly
+
+// ErrNotApproved Returned when a DecryptionPolicy refuses to decrypt a result
+var ErrNotApproved = errors.New("decryption not approved by policy")
+
+// ComputationTranscript Describes how a result sent for decryption was computed: the name
+// of an approved computation and its input ciphertexts in the order they were passed
+type ComputationTranscript struct {
+	Operation string   `json:"operation"`
+	Inputs    [][]byte `json:"inputs"`
+}
+
+// ApprovedComputation Computation whose results a DecryptionPolicy decrypts
+type ApprovedComputation struct {
+	// MinInputs Minimum number of inputs, which must be distinct and registered
+	MinInputs int
+	// Compute Computes the result from inputs with keys of ctx, exactly as clients do
+	Compute func(ctx *Context, inputs [][]byte) ([]byte, error)
+}
+
+// DecryptionPolicy Decides which results the decryption endpoints decrypt, see WithDecryptionPolicy.
+// Safe for concurrent use
+type DecryptionPolicy struct {
+	mu           sync.Mutex
+	computations map[string]ApprovedComputation
+	inputs       map[[sha256.Size]byte]struct{}
+}
+
+// NewDecryptionPolicy Creates a DecryptionPolicy without approved computations and registered
+// inputs, which refuses to decrypt anything
+func NewDecryptionPolicy() *DecryptionPolicy {
+	return &DecryptionPolicy{
+		computations: make(map[string]ApprovedComputation),
+		inputs:       make(map[[sha256.Size]byte]struct{}),
+	}
+}
+
+// Approve Approves decryption of results of computation named operation
+func (policy *DecryptionPolicy) Approve(operation string, computation ApprovedComputation) {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+
+	policy.computations[operation] = computation
+}
+
+// ApproveCkksAggregate Approves decryption of results of a CKKS aggregate over at least
+// minInputs inputs, e.g. ApproveCkksAggregate("ArrayMean", 10, (*ckksMath.Context).ArrayMean)
+func (policy *DecryptionPolicy) ApproveCkksAggregate(operation string, minInputs int, aggregate func(*ckksMath.Context, [][]byte) ([]byte, error)) {
+	policy.Approve(operation, ApprovedComputation{
+		MinInputs: minInputs,
+		Compute: func(ctx *Context, inputs [][]byte) ([]byte, error) {
+			return aggregate(ctx.Ckks, inputs)
+		},
+	})
+}
+
+// ApproveBfvAggregate Approves decryption of results of a BFV aggregate over at least
+// minInputs inputs, e.g. ApproveBfvAggregate("ArraySum", 10, (*bfvMath.Context).ArraySum)
+func (policy *DecryptionPolicy) ApproveBfvAggregate(operation string, minInputs int, aggregate func(*bfvMath.Context, [][]byte) ([]byte, error)) {
+	policy.Approve(operation, ApprovedComputation{
+		MinInputs: minInputs,
+		Compute: func(ctx *Context, inputs [][]byte) ([]byte, error) {
+			return aggregate(ctx.Bfv, inputs)
+		},
+	})
+}
+
+// RegisterInputs Registers encrypted data, e.g. stored rows, as inputs approved computations
+// may use. Ciphertexts computed by clients can't be registered, otherwise a client could
+// pad a computation with derived ciphertexts, e.g. zeros, to reach its minimum number of inputs
+func (policy *DecryptionPolicy) RegisterInputs(encryptedData ...[]byte) {
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+
+	for _, data := range encryptedData {
+		policy.inputs[sha256.Sum256(data)] = struct{}{}
+	}
+}
+
+// Authorize Returns nil if encryptedResult is the result of an approved computation over
+// registered inputs described by transcript, and an error wrapping ErrNotApproved otherwise.
+// The result is recomputed with keys of ctx
+func (policy *DecryptionPolicy) Authorize(ctx *Context, encryptedResult []byte, transcript *ComputationTranscript) error {
+	if transcript == nil {
+		return fmt.Errorf("%w: result has no computation transcript", ErrNotApproved)
+	}
+
+	// computations share evaluators of ctx, which aren't safe for concurrent use
+	policy.mu.Lock()
+	defer policy.mu.Unlock()
+
+	computation, ok := policy.computations[transcript.Operation]
+	if !ok {
+		return fmt.Errorf("%w: unknown operation '%s'", ErrNotApproved, transcript.Operation)
+	}
+	if len(transcript.Inputs) < computation.MinInputs {
+		return fmt.Errorf("%w: %s requires at least %d inputs, got %d", ErrNotApproved, transcript.Operation, computation.MinInputs, len(transcript.Inputs))
+	}
+
+	seen := make(map[[sha256.Size]byte]struct{}, len(transcript.Inputs))
+	for i, input := range transcript.Inputs {
+		digest := sha256.Sum256(input)
+		if _, ok := policy.inputs[digest]; !ok {
+			return fmt.Errorf("%w: input %d is not registered", ErrNotApproved, i)
+		}
+		if _, ok := seen[digest]; ok {
+			return fmt.Errorf("%w: input %d is repeated", ErrNotApproved, i)
+		}
+		seen[digest] = struct{}{}
+	}
+
+	expected, err := computation.Compute(ctx, transcript.Inputs)
+	if err != nil {
+		return fmt.Errorf("%w: recomputing %s failed: %v", ErrNotApproved, transcript.Operation, err)
+	}
+	if sha256.Sum256(expected) != sha256.Sum256(encryptedResult) {
+		return fmt.Errorf("%w: result doesn't match its transcript", ErrNotApproved)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Magic Bytes every enveloped ciphertext starts with
+var Magic = []byte{'H', 'E', 'C', 'T'}
+
+// Version Current version of the envelope format
+const Version = 2
+
+// MaxKeyIDLength Maximum length of a key ID in bytes
+const MaxKeyIDLength = 255
+
+// Scheme Encryption scheme a ciphertext belongs to
+type Scheme byte
+
+const (
+	// SchemeUnknown Scheme of ciphertexts written before schemes were recorded
+	SchemeUnknown Scheme = iota
+	SchemeCKKS
+	SchemeBFV
+)
+
+// Envelope Ciphertext together with the scheme, parameters and key it was produced with.
+// Version 2 is encoded as Magic, version byte, scheme byte, 8 bytes of parameters hash
+// in big endian, key ID length byte, key ID and lattigo MarshalBinary bytes of the
+// ciphertext. Version 1 lacks scheme and parameters hash
+type Envelope struct {
+	Scheme     Scheme
+	ParamsHash uint64
+	KeyID      string
+	Payload    []byte
+}
+
+// ErrMalformed Returned when data starts with Magic, but can't be decoded
+var ErrMalformed = errors.New("malformed ciphertext envelope")
+
+// ErrLegacy Returned by Open for data written before scheme and parameters were recorded,
+// i.e. bare lattigo ciphertexts and version 1 envelopes, which only OpenLegacy accepts
+var ErrLegacy = errors.New("ciphertext has no recorded scheme and parameters")
+
+// VersionError Returned for envelopes of an unsupported format version
+type VersionError struct {
+	Version byte
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("unsupported ciphertext envelope version %d", e.Version)
+}
+
+// SchemeMismatchError Returned when a ciphertext of one scheme is passed where
+// a ciphertext of another scheme is expected, e.g. BFV data to a CKKS function
+type SchemeMismatchError struct {
+	Expected Scheme
+	Actual   Scheme
+}
+
+func (e *SchemeMismatchError) Error() string {
+	return fmt.Sprintf("expected %s ciphertext, got %s ciphertext", e.Expected, e.Actual)
+}
+
+// ParamsMismatchError Returned when a ciphertext was produced with other parameters than expected
+type ParamsMismatchError struct {
+	Expected uint64
+	Actual   uint64
+}
+
+func (e *ParamsMismatchError) Error() string {
+	return fmt.Sprintf("ciphertext parameters hash %016x doesn't match expected parameters hash %016x", e.Actual, e.Expected)
+}
+
+// KeyMismatchError Returned when a ciphertext was encrypted with another key than expected,
+// or when ciphertexts encrypted with different keys are combined
+type KeyMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (e *KeyMismatchError) Error() string {
+	return fmt.Sprintf("ciphertext key ID '%s' doesn't match expected key ID '%s'", e.Actual, e.Expected)
+}
+
+// ParamsHash Returns a fingerprint of encryption parameters, e.g. ckks.Parameters
+// or bfv.Parameters: the first 8 bytes of SHA-256 of their binary form
+func ParamsHash(params encoding.BinaryMarshaler) (uint64, error) {
+	data, err := params.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	sum := sha256.Sum256(data)
+	return binary.BigEndian.Uint64(sum[:8]), nil
+}
+
+// Wrap Encodes payload into an Envelope
+func Wrap(scheme Scheme, paramsHash uint64, keyID string, payload []byte) ([]byte, error) {
+	return Envelope{Scheme: scheme, ParamsHash: paramsHash, KeyID: keyID, Payload: payload}.Marshal()
+}
+
+// Marshal Encodes Envelope into bytes of the current format version
+func (e Envelope) Marshal() ([]byte, error) {
+	if len(e.KeyID) > MaxKeyIDLength {
+		return nil, fmt.Errorf("key ID is longer than %d bytes", MaxKeyIDLength)
+	}
+
+	data := make([]byte, 0, len(Magic)+11+len(e.KeyID)+len(e.Payload))
+	data = append(data, Magic...)
+	data = append(data, Version, byte(e.Scheme))
+	data = binary.BigEndian.AppendUint64(data, e.ParamsHash)
+	data = append(data, byte(len(e.KeyID)))
+	data = append(data, e.KeyID...)
+	data = append(data, e.Payload...)
+	return data, nil
+}
+
+// Unmarshal Decodes data encoded with Marshal. Data without Magic is treated as
+// a bare lattigo ciphertext written before envelopes were introduced, and is
+// returned as payload of SchemeUnknown with an empty key ID. Payload shares memory with data
+func Unmarshal(data []byte) (Envelope, error) {
+	if !bytes.HasPrefix(data, Magic) {
+		return Envelope{Payload: data}, nil
+	}
+
+	header := data[len(Magic):]
+	if len(header) < 1 {
+		return Envelope{}, ErrMalformed
+	}
+
+	var e Envelope
+	switch header[0] {
+	case 1:
+		header = header[1:]
+	case Version:
+		if len(header) < 10 {
+			return Envelope{}, ErrMalformed
+		}
+		e.Scheme = Scheme(header[1])
+		e.ParamsHash = binary.BigEndian.Uint64(header[2:10])
+		header = header[10:]
+	default:
+		return Envelope{}, &VersionError{Version: header[0]}
+	}
+
+	if len(header) < 1 || len(header) < 1+int(header[0]) {
+		return Envelope{}, ErrMalformed
+	}
+	keyIDLength := int(header[0])
+	e.KeyID = string(header[1 : 1+keyIDLength])
+	e.Payload = header[1+keyIDLength:]
+	return e, nil
+}
+
+// Open Decodes data and checks that it was produced with scheme and parameters
+// with paramsHash, returning SchemeMismatchError or ParamsMismatchError otherwise.
+// Data written before scheme and parameters were recorded is rejected with ErrLegacy
+func Open(data []byte, scheme Scheme, paramsHash uint64) (Envelope, error) {
+	return open(data, scheme, paramsHash, false)
+}
+
+// OpenLegacy Same as Open, but also accepts data written before scheme and parameters
+// were recorded, which can't be checked. Only use it for data known to be produced with
+// scheme and parameters with paramsHash, e.g. while re-encrypting old records
+func OpenLegacy(data []byte, scheme Scheme, paramsHash uint64) (Envelope, error) {
+	return open(data, scheme, paramsHash, true)
+}
+
+// open decodes data and checks its scheme and parameters, accepting data
+// without them if legacy is set
+func open(data []byte, scheme Scheme, paramsHash uint64, legacy bool) (Envelope, error) {
+	e, err := Unmarshal(data)
+	if err != nil {
+		return Envelope{}, err
+	}
+	if e.Scheme == SchemeUnknown {
+		if !legacy {
+			return Envelope{}, ErrLegacy
+		}
+		return e, nil
+	}
+	if e.Scheme != scheme {
+		return Envelope{}, &SchemeMismatchError{Expected: scheme, Actual: e.Scheme}
+	}
+	if e.ParamsHash != paramsHash {
+		return Envelope{}, &ParamsMismatchError{Expected: paramsHash, Actual: e.ParamsHash}
+	}
+	return e, nil
+}
+
+// KeyID Returns the ID of the key data was encrypted with
+func KeyID(data []byte) (string, error) {
+	e, err := Unmarshal(data)
+	if err != nil {
+		return "", err
+	}
+	return e.KeyID, nil
+}
+
+// CommonKeyID Returns the key ID shared by all data, or KeyMismatchError
+// if data was encrypted with different keys
+func CommonKeyID(data ...[]byte) (string, error) {
+	var keyID string
+	for i, d := range data {
+		id, err := KeyID(d)
+		if err != nil {
+			return "", err
+		}
+		if i == 0 {
+			keyID = id
+		} else if id != keyID {
+			return "", &KeyMismatchError{Expected: keyID, Actual: id}
+		}
+	}
+	return keyID, nil
+}
+
+// String Returns the name of scheme
+func (s Scheme) String() string {
+	switch s {
+	case SchemeCKKS:
+		return "CKKS"
+	case SchemeBFV:
+		return "BFV"
+	default:
+		return "unknown"
+	}
+}
@@ -134,13 +134,14 @@ func performArrayOperationWithParamReturningArrayCkks(operation ckksMath.ArrayOp
 		log.Fatal(err)
 	}
 
-	var decryptedArray = make([]float64, len(calcRes))
-	for i, val := range calcRes {
-		decryptedCalcResultCkks, err := he.SendComputationResultToServerCkks(serverUrl+"/decrypt_computations_ckks", val)
+	decryptedArray, errs, err := he.SendComputationResultsToServerCkks(serverUrl+"/decrypt_computations_ckks/batch", calcRes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, err := range errs {
 		if err != nil {
 			log.Fatal(err)
 		}
-		decryptedArray[i] = decryptedCalcResultCkks
 	}
 
 	fmt.Printf("CKKS %s result: %.2f \n", getFunctionName(operation), decryptedArray)
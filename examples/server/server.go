@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	he "github.com/SamBridgess/homomorphicEncryption"
 	_ "github.com/lib/pq"
 	"log"
+	"os"
+	"os/signal"
+	"time"
 )
 
 const (
@@ -15,7 +19,11 @@ const (
 )
 
 func main() {
-	he.SetupServer("ckksKeys.json", "bfvKeys.json")
+	keyStore, err := he.NewFileKeyStore("keys", []byte(os.Getenv("HE_KEYS_PASSPHRASE")))
+	if err != nil {
+		log.Fatal(err)
+	}
+	he.SetupServerWithKeyStore(keyStore)
 
 	encryptedDataCkks1, _ := he.EncryptCKKS(5.0)
 	encryptedDataCkks2, _ := he.EncryptCKKS(4.0)
@@ -43,8 +51,25 @@ func main() {
 		encryptedDataBfv5,
 	)
 
+	server := he.NewDefaultServer(he.WithAddress(":443"), he.WithCertificate("cert.pem", "key.pem"))
+	go shutdownOnInterrupt(server)
+
 	log.Println("Server is running")
-	he.StartSecureServer("443", "cert.pem", "key.pem")
+	if err := server.Start(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func shutdownOnInterrupt(server *he.Server) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println(err)
+	}
 }
 
 func serverInsert(
@@ -0,0 +1,293 @@
+package homomorphicEncryption
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"log"
+	"sort"
+	"sync"
+)
+
+// KeyStatus Status of a key version in a KeyRing
+type KeyStatus int
+
+const (
+	// KeyActive Key version new data is encrypted with
+	KeyActive KeyStatus = iota
+	// KeyRetired Key version that can only decrypt data encrypted before rotation
+	KeyRetired
+)
+
+// KeyVersion Describes a key version held by a KeyRing
+type KeyVersion struct {
+	ID     string
+	Status KeyStatus
+}
+
+// ErrUnknownKey Returned when a ciphertext was encrypted with a key version
+// the KeyRing doesn't hold
+var ErrUnknownKey = errors.New("unknown key ID")
+
+// KeyRing Holds several key versions of a server, each as a separate Context.
+// One version is active and encrypts new data, retired versions are kept to decrypt
+// data encrypted before rotation until it is re-encrypted with ReEncryptCKKS and
+// ReEncryptBFV or a ReEncryptionJob. Every ciphertext carries the ID of its key
+// version, so decryption is routed to the right Context. Keys of version ID are
+// kept in a KeyStore under names "ID-ckks" and "ID-bfv"
+type KeyRing struct {
+	config ContextConfig
+	store  KeyStore
+
+	// rotateMu serializes Rotate, which generates keys without holding mu
+	rotateMu sync.Mutex
+	mu       sync.RWMutex
+	active   string
+	versions map[string]*Context
+}
+
+// NewKeyRing Creates a KeyRing with activeKeyID as the active version and retiredKeyIDs
+// as retired ones. Keys of the active version are generated and saved to store if store
+// doesn't have them, keys of retired versions must be present in store.
+// An empty retired key ID stands for keys saved without version by
+// NewServerContextWithKeyStore, which decrypt data encrypted before key rotation was set up
+func NewKeyRing(config ContextConfig, store KeyStore, activeKeyID string, retiredKeyIDs ...string) (*KeyRing, error) {
+	ring := &KeyRing{
+		config:   config,
+		store:    store,
+		versions: make(map[string]*Context),
+	}
+
+	for _, keyID := range retiredKeyIDs {
+		if err := ring.add(keyID, false); err != nil {
+			return nil, err
+		}
+	}
+	if err := ring.add(activeKeyID, true); err != nil {
+		return nil, err
+	}
+	ring.active = activeKeyID
+	return ring, nil
+}
+
+// add loads key version keyID from store, generating it if generate is set
+func (ring *KeyRing) add(keyID string, generate bool) error {
+	if err := ring.checkKeyID(keyID, generate); err != nil {
+		return err
+	}
+
+	ctx, err := ring.load(keyID, generate)
+	if err != nil {
+		return err
+	}
+	ring.versions[keyID] = ctx
+	return nil
+}
+
+// checkKeyID returns an error if keyID is invalid or already held by ring
+func (ring *KeyRing) checkKeyID(keyID string, generate bool) error {
+	if keyID == "" && generate {
+		return errors.New("active key version must have an ID")
+	}
+	if err := validateKeyName(keyID); keyID != "" && err != nil {
+		return fmt.Errorf("invalid key ID '%s'", keyID)
+	}
+	if len(keyID) > envelope.MaxKeyIDLength {
+		return fmt.Errorf("key ID is longer than %d bytes", envelope.MaxKeyIDLength)
+	}
+	if _, ok := ring.versions[keyID]; ok {
+		return fmt.Errorf("key ID '%s' is listed twice", keyID)
+	}
+	return nil
+}
+
+// load loads the Context of key version keyID from store, generating it if generate is set
+func (ring *KeyRing) load(keyID string, generate bool) (*Context, error) {
+	ctx, err := newServerContextFromStore(ring.config, ring.store, keyID, generate)
+	if err != nil {
+		return nil, fmt.Errorf("key version '%s': %w", keyID, err)
+	}
+	return ctx, nil
+}
+
+// Active Returns the Context of the active key version
+func (ring *KeyRing) Active() *Context {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	return ring.versions[ring.active]
+}
+
+// Get Returns the Context of key version keyID or ErrUnknownKey
+func (ring *KeyRing) Get(keyID string) (*Context, error) {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	ctx, ok := ring.versions[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKey, keyID)
+	}
+	return ctx, nil
+}
+
+// ContextFor Returns the Context of the key version encryptedData was encrypted with
+func (ring *KeyRing) ContextFor(encryptedData []byte) (*Context, error) {
+	keyID, err := envelope.KeyID(encryptedData)
+	if err != nil {
+		return nil, err
+	}
+	return ring.Get(keyID)
+}
+
+// Versions Returns all key versions sorted by ID
+func (ring *KeyRing) Versions() []KeyVersion {
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	versions := make([]KeyVersion, 0, len(ring.versions))
+	for keyID := range ring.versions {
+		status := KeyRetired
+		if keyID == ring.active {
+			status = KeyActive
+		}
+		versions = append(versions, KeyVersion{ID: keyID, Status: status})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ID < versions[j].ID
+	})
+	return versions
+}
+
+// Rotate Generates key version newKeyID, makes it active and retires the
+// previously active version. Data encrypted with retired versions stays decryptable.
+// Keys are generated without blocking encryption and decryption with the KeyRing
+func (ring *KeyRing) Rotate(newKeyID string) error {
+	ring.rotateMu.Lock()
+	defer ring.rotateMu.Unlock()
+
+	ring.mu.RLock()
+	err := ring.checkKeyID(newKeyID, true)
+	ring.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	ctx, err := ring.load(newKeyID, true)
+	if err != nil {
+		return err
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	if err := ring.checkKeyID(newKeyID, true); err != nil {
+		return err
+	}
+	ring.versions[newKeyID] = ctx
+	log.Printf("Key rotated from '%s' to '%s'\n", ring.active, newKeyID)
+	ring.active = newKeyID
+	return nil
+}
+
+// Remove Removes retired key version keyID from the KeyRing and deletes its keys
+// from store. Data still encrypted with it will be lost, so run a ReEncryptionJob first
+func (ring *KeyRing) Remove(keyID string) error {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	if _, ok := ring.versions[keyID]; !ok {
+		return fmt.Errorf("%w '%s'", ErrUnknownKey, keyID)
+	}
+	if keyID == ring.active {
+		return errors.New("cannot remove active key version")
+	}
+
+	for _, method := range []Method{CKKS, BFV} {
+		if err := ring.store.Delete(keyStoreName(keyID, method)); err != nil && !errors.Is(err, ErrKeysNotFound) {
+			return err
+		}
+	}
+	delete(ring.versions, keyID)
+	log.Printf("Key version '%s' removed\n", keyID)
+	return nil
+}
+
+// EncryptCKKSVector Encrypts data with the active key version, see Context.EncryptCKKSVector
+func (ring *KeyRing) EncryptCKKSVector(data []float64) ([]byte, error) {
+	return ring.Active().EncryptCKKSVector(data)
+}
+
+// DecryptCKKSVector Decrypts data with the key version it was encrypted with,
+// see Context.DecryptCKKSVector
+func (ring *KeyRing) DecryptCKKSVector(data []byte) ([]float64, error) {
+	ctx, err := ring.ContextFor(data)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.DecryptCKKSVector(data)
+}
+
+// EncryptBFVVector Encrypts data with the active key version, see Context.EncryptBFVVector
+func (ring *KeyRing) EncryptBFVVector(data []int64) ([]byte, error) {
+	return ring.Active().EncryptBFVVector(data)
+}
+
+// DecryptBFVVector Decrypts data with the key version it was encrypted with,
+// see Context.DecryptBFVVector
+func (ring *KeyRing) DecryptBFVVector(data []byte) ([]int64, error) {
+	ctx, err := ring.ContextFor(data)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.DecryptBFVVector(data)
+}
+
+// ReEncryptCKKS Decrypts data with the key version it was encrypted with and encrypts
+// it again with the active key version. Data already encrypted with the active version
+// is returned as is. Note that CKKS is approximate, so values get a new small error
+func (ring *KeyRing) ReEncryptCKKS(data []byte) ([]byte, error) {
+	active := ring.Active()
+	keyID, err := envelope.KeyID(data)
+	if err != nil {
+		return nil, err
+	}
+	if keyID == active.KeyID {
+		return data, nil
+	}
+
+	decrypted, err := ring.DecryptCKKSVector(data)
+	if err != nil {
+		return nil, err
+	}
+	return active.EncryptCKKSVector(decrypted)
+}
+
+// ReEncryptBFV Decrypts data with the key version it was encrypted with and encrypts
+// it again with the active key version. Data already encrypted with the active version
+// is returned as is
+func (ring *KeyRing) ReEncryptBFV(data []byte) ([]byte, error) {
+	active := ring.Active()
+	keyID, err := envelope.KeyID(data)
+	if err != nil {
+		return nil, err
+	}
+	if keyID == active.KeyID {
+		return data, nil
+	}
+
+	decrypted, err := ring.DecryptBFVVector(data)
+	if err != nil {
+		return nil, err
+	}
+	return active.EncryptBFVVector(decrypted)
+}
+
+// String Returns the name of key status
+func (s KeyStatus) String() string {
+	switch s {
+	case KeyActive:
+		return "active"
+	case KeyRetired:
+		return "retired"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,387 @@
+package homomorphicEncryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ldsec/lattigo/v2/rlwe"
+	"golang.org/x/crypto/scrypt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// KeyStore Stores KeyPairs under names, e.g. "ckks" and "bfv".
+// Implementations must be safe for concurrent use
+type KeyStore interface {
+	// Load Returns KeyPair saved under name or ErrKeysNotFound
+	Load(name string) (KeyPair, error)
+	// Save Saves KeyPair under name, replacing the existing one
+	Save(name string, keys KeyPair) error
+	// List Returns names of all saved KeyPairs in sorted order
+	List() ([]string, error)
+	// Delete Deletes KeyPair saved under name or returns ErrKeysNotFound
+	Delete(name string) error
+}
+
+// ErrKeysNotFound Returned by KeyStore when there are no keys with requested name
+var ErrKeysNotFound = errors.New("keys not found")
+
+// ErrWrongPassphrase Returned by FileKeyStore when a key file can't be decrypted
+// with its passphrase, either because the passphrase is wrong or the file is corrupted
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted key file")
+
+var keyNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+// validateKeyName returns an error for names that can't be used as file names
+func validateKeyName(name string) error {
+	if !keyNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid key name '%s'", name)
+	}
+	return nil
+}
+
+// keyPairBinary is KeyPair in lattigo binary form, used as plaintext of key files
+type keyPairBinary struct {
+	Sk []byte `json:"sk,omitempty"`
+	Pk []byte `json:"pk,omitempty"`
+}
+
+// marshalKeyPair encodes keys in lattigo binary form
+func marshalKeyPair(keys KeyPair) ([]byte, error) {
+	var data keyPairBinary
+	var err error
+	if keys.Sk != nil {
+		if data.Sk, err = keys.Sk.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	if keys.Pk != nil {
+		if data.Pk, err = keys.Pk.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(data)
+}
+
+// unmarshalKeyPair decodes keys encoded with marshalKeyPair
+func unmarshalKeyPair(b []byte) (KeyPair, error) {
+	var data keyPairBinary
+	if err := json.Unmarshal(b, &data); err != nil {
+		return KeyPair{}, err
+	}
+
+	var keys KeyPair
+	if len(data.Sk) > 0 {
+		keys.Sk = new(rlwe.SecretKey)
+		if err := keys.Sk.UnmarshalBinary(data.Sk); err != nil {
+			return KeyPair{}, err
+		}
+	}
+	if len(data.Pk) > 0 {
+		keys.Pk = new(rlwe.PublicKey)
+		if err := keys.Pk.UnmarshalBinary(data.Pk); err != nil {
+			return KeyPair{}, err
+		}
+	}
+	return keys, nil
+}
+
+// MemoryKeyStore KeyStore keeping KeyPairs in memory, e.g. for tests
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string][]byte
+}
+
+// NewMemoryKeyStore Creates an empty MemoryKeyStore
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: make(map[string][]byte)}
+}
+
+// Load Returns a copy of KeyPair saved under name
+func (store *MemoryKeyStore) Load(name string) (KeyPair, error) {
+	store.mu.RLock()
+	data, ok := store.keys[name]
+	store.mu.RUnlock()
+	if !ok {
+		return KeyPair{}, ErrKeysNotFound
+	}
+	return unmarshalKeyPair(data)
+}
+
+// Save Saves a copy of keys under name
+func (store *MemoryKeyStore) Save(name string, keys KeyPair) error {
+	if err := validateKeyName(name); err != nil {
+		return err
+	}
+	data, err := marshalKeyPair(keys)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.keys[name] = data
+	return nil
+}
+
+// List Returns names of all saved KeyPairs
+func (store *MemoryKeyStore) List() ([]string, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	names := make([]string, 0, len(store.keys))
+	for name := range store.keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete Deletes KeyPair saved under name
+func (store *MemoryKeyStore) Delete(name string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.keys[name]; !ok {
+		return ErrKeysNotFound
+	}
+	delete(store.keys, name)
+	return nil
+}
+
+const (
+	keyFileExtension = ".keys"
+	keyFileVersion   = 1
+
+	// scrypt cost parameters recommended for interactive logins as of 2017,
+	// stored in every key file so they can be raised later
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltLen      = 16
+
+	// limits of scrypt parameters read from key files, up to 256 MiB of memory and 16 times
+	// the work of the defaults, so a corrupted file can't exhaust the host before it fails
+	// authentication
+	maxScryptN = 1 << 18
+	maxScryptR = 8
+	maxScryptP = 2
+)
+
+// encryptedKeyFile is the on-disk format of FileKeyStore. The KeyPair is sealed
+// with AES-256-GCM under a key derived from the passphrase with scrypt
+type encryptedKeyFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// FileKeyStore KeyStore keeping every KeyPair in its own file in a directory.
+// Key material is encrypted with a passphrase-derived key, the directory is
+// only accessible by its owner and files are only readable by their owner
+type FileKeyStore struct {
+	dir        string
+	passphrase []byte
+	mu         sync.Mutex
+}
+
+// NewFileKeyStore Creates a FileKeyStore in dir, creating dir with 0700 permissions
+// if it doesn't exist and restricting it to 0700 if it's accessible by others.
+// Passphrase must not be empty
+func NewFileKeyStore(dir string, passphrase []byte) (*FileKeyStore, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase must not be empty")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		if err := os.Chmod(dir, 0700); err != nil {
+			return nil, fmt.Errorf("key directory '%s' is accessible by others: %w", dir, err)
+		}
+	}
+	return &FileKeyStore{
+		dir:        dir,
+		passphrase: append([]byte(nil), passphrase...),
+	}, nil
+}
+
+// path returns location of the key file for name
+func (store *FileKeyStore) path(name string) string {
+	return filepath.Join(store.dir, name+keyFileExtension)
+}
+
+// Load Reads and decrypts KeyPair saved under name
+func (store *FileKeyStore) Load(name string) (KeyPair, error) {
+	if err := validateKeyName(name); err != nil {
+		return KeyPair{}, err
+	}
+
+	data, err := os.ReadFile(store.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return KeyPair{}, ErrKeysNotFound
+	}
+	if err != nil {
+		return KeyPair{}, err
+	}
+
+	var file encryptedKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return KeyPair{}, err
+	}
+	if file.Version != keyFileVersion || file.KDF != "scrypt" {
+		return KeyPair{}, fmt.Errorf("unsupported key file version %d (%s)", file.Version, file.KDF)
+	}
+	if file.N < 2 || file.N > maxScryptN || file.R < 1 || file.R > maxScryptR || file.P < 1 || file.P > maxScryptP {
+		return KeyPair{}, fmt.Errorf("unsupported scrypt parameters N=%d, r=%d, p=%d in key file", file.N, file.R, file.P)
+	}
+
+	aead, err := newKeyFileCipher(store.passphrase, file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return KeyPair{}, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return KeyPair{}, ErrWrongPassphrase
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, []byte(name))
+	if err != nil {
+		return KeyPair{}, ErrWrongPassphrase
+	}
+	return unmarshalKeyPair(plaintext)
+}
+
+// Save Encrypts keys and writes them to the key file for name with 0600 permissions.
+// The file is replaced atomically, so a crash never leaves a partially written key
+func (store *FileKeyStore) Save(name string, keys KeyPair) error {
+	if err := validateKeyName(name); err != nil {
+		return err
+	}
+
+	plaintext, err := marshalKeyPair(keys)
+	if err != nil {
+		return err
+	}
+
+	file := encryptedKeyFile{
+		Version: keyFileVersion,
+		KDF:     "scrypt",
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, saltLen),
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	aead, err := newKeyFileCipher(store.passphrase, file.Salt, file.N, file.R, file.P)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	// name is authenticated, so a key file can't be swapped for another one
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, []byte(name))
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return writeFileAtomic(store.path(name), data, 0600)
+}
+
+// List Returns names of all key files in the directory
+func (store *FileKeyStore) List() ([]string, error) {
+	entries, err := os.ReadDir(store.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), keyFileExtension)
+		if !entry.Type().IsRegular() || !ok || validateKeyName(name) != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete Removes the key file for name
+func (store *FileKeyStore) Delete(name string) error {
+	if err := validateKeyName(name); err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	err := os.Remove(store.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrKeysNotFound
+	}
+	return err
+}
+
+// newKeyFileCipher derives an AES-256-GCM cipher from passphrase and salt
+func newKeyFileCipher(passphrase []byte, salt []byte, n int, r int, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, n, r, p, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeFileAtomic writes data to a temporary file with perm permissions
+// and renames it to path
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -2,11 +2,14 @@ package homomorphicEncryption
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/ldsec/lattigo/v2/bfv"
 	"github.com/ldsec/lattigo/v2/ckks"
 	"github.com/ldsec/lattigo/v2/rlwe"
 	"log"
 	"os"
+	"strings"
 )
 
 type Method int
@@ -20,6 +23,25 @@ type KeyPair struct {
 // EvalKeys Struct containing rlwe.EvaluationKey for sending it to client
 type EvalKeys struct {
 	EvalKey1 rlwe.EvaluationKey
+	// KeyID ID of the key version EvalKey1 belongs to
+	KeyID string
+}
+
+// evalKeysJSON is the wire format of EvalKeys. Keys are stored in lattigo binary
+// form, since rotation keys are too large for JSON arrays of numbers
+type evalKeysJSON struct {
+	Rlk  []byte `json:"rlk,omitempty"`
+	Rtks []byte `json:"rtks,omitempty"`
+}
+
+// evalKeysVersion Version of the EvalKeys JSON format written by MarshalJSON. JSON without
+// a version is the format of earlier releases, encoding keys field by field
+const evalKeysVersion = 1
+
+// legacyEvalKeys EvalKeys without MarshalJSON and UnmarshalJSON, decoding the format of earlier releases
+type legacyEvalKeys struct {
+	EvalKey1 rlwe.EvaluationKey
+	KeyID    string
 }
 
 const (
@@ -35,47 +57,32 @@ var (
 	EvalKeysBfv  EvalKeys
 )
 
+var errUnknownMethod = errors.New("unknown method")
+
 // GenKeysCKKS Generates new KeyPair of ckks keys, returns Sk and Pk KeyPair
 func GenKeysCKKS() KeyPair {
-	return NewKeyPair(ckks.NewKeyGenerator(CkksParams).GenKeyPair())
+	return globalContext().GenKeysCKKS()
 }
 
 // GenKeysBFV Generates new KeyPair bfv keys
 func GenKeysBFV() KeyPair {
-	return NewKeyPair(bfv.NewKeyGenerator(BfvParams).GenKeyPair())
+	return globalContext().GenKeysBFV()
 }
 
 func SetEvalKeysByMethod(method Method) {
-	switch method {
-	case CKKS:
-		EvalKeysCkks = EvalKeys{
-			EvalKey1: GenEvalKeyCkks(1),
-		}
-		log.Println("EvalKeys keys generated (CKKS)")
-	case BFV:
-		EvalKeysBfv = EvalKeys{
-			EvalKey1: GenEvalKeyBfv(1),
-		}
-		log.Println("EvalKeys keys generated (BFV)")
-	default:
-		log.Panic("unknown method")
+	ctx := newGlobalContext()
+	if err := ctx.SetEvalKeysByMethod(method); err != nil {
+		log.Panic(err)
 	}
+	ctx.setGlobals()
 }
 
 func GenEvalKeyCkks(maxDegree int) rlwe.EvaluationKey {
-	eval := rlwe.EvaluationKey{
-		Rlk:  ckks.NewKeyGenerator(CkksParams).GenRelinearizationKey(CkksKeys.Sk, maxDegree),
-		Rtks: nil,
-	}
-	return eval
+	return globalContext().GenEvalKeyCkks(maxDegree)
 }
 
 func GenEvalKeyBfv(maxDegree int) rlwe.EvaluationKey {
-	eval := rlwe.EvaluationKey{
-		Rlk:  bfv.NewKeyGenerator(BfvParams).GenRelinearizationKey(CkksKeys.Sk, maxDegree),
-		Rtks: nil,
-	}
-	return eval
+	return globalContext().GenEvalKeyBfv(maxDegree)
 }
 
 // NewKeyPair Creates a new KeyPair struct
@@ -88,73 +95,334 @@ func NewKeyPair(Sk *rlwe.SecretKey, Pk *rlwe.PublicKey) KeyPair {
 }
 
 // LoadOrGenerateKeys checks if keys file exists and if it does - loads it
-// If it doesn't - generates a new keys file for specified method
+// If it doesn't - generates a new keys file for specified method.
+//
+// Deprecated: the secret key is stored unencrypted, use Context.LoadOrGenerateKeysFromStore
 func LoadOrGenerateKeys(keysFileLocation string, method Method) {
-	if _, err := os.Stat(keysFileLocation); os.IsNotExist(err) {
-		log.Printf("Keys file '%s' not found. Generating new keys\n", keysFileLocation)
-		GenerateAndSetAndSaveKeys(keysFileLocation, method)
-	} else {
-		log.Println("Loading keys from file...")
-		LoadAndSetKeys(keysFileLocation, method)
+	ctx := newGlobalContext()
+	if err := ctx.LoadOrGenerateKeys(keysFileLocation, method); err != nil {
+		panic(err)
 	}
-	SetEvalKeysByMethod(method)
+	ctx.setGlobals()
 }
 
 // GenerateAndSetAndSaveKeys Generates new KeyPair and saves it to keysFileLocation
 // json file
 func GenerateAndSetAndSaveKeys(keysFileLocation string, method Method) {
-	var data []byte
-	var err error
+	ctx := newGlobalContext()
+	if err := ctx.GenerateAndSetAndSaveKeys(keysFileLocation, method); err != nil {
+		panic(err)
+	}
+	ctx.setGlobals()
+}
+
+// LoadAndSetKeys Loads KeyPair from keysFileLocation json file
+func LoadAndSetKeys(keysFileLocation string, method Method) {
+	ctx := newGlobalContext()
+	if err := ctx.LoadAndSetKeys(keysFileLocation, method); err != nil {
+		panic(err)
+	}
+	ctx.setGlobals()
+}
 
+// GenKeysCKKS Generates new KeyPair of ckks keys for ctx params
+func (ctx *Context) GenKeysCKKS() KeyPair {
+	return NewKeyPair(ckks.NewKeyGenerator(ctx.CkksParams).GenKeyPair())
+}
+
+// GenKeysBFV Generates new KeyPair of bfv keys for ctx params
+func (ctx *Context) GenKeysBFV() KeyPair {
+	return NewKeyPair(bfv.NewKeyGenerator(ctx.BfvParams).GenKeyPair())
+}
+
+// GenEvalKeyCkks Generates rlwe.EvaluationKey from ctx ckks secret key, containing a
+// relinearization key and rotation keys for every power of two below ctx.RotationSlots
+func (ctx *Context) GenEvalKeyCkks(maxDegree int) rlwe.EvaluationKey {
+	keygen := ckks.NewKeyGenerator(ctx.CkksParams)
+	eval := rlwe.EvaluationKey{
+		Rlk:  keygen.GenRelinearizationKey(ctx.CkksKeys.Sk, maxDegree),
+		Rtks: nil,
+	}
+
+	rotationSlots := ctx.rotationSlots(ctx.CkksParams.Slots())
+	if rotations := powerOfTwoRotations(rotationSlots); len(rotations) > 0 {
+		eval.Rtks = keygen.GenRotationKeysForRotations(rotations, false, ctx.CkksKeys.Sk)
+	}
+	return eval
+}
+
+// GenEvalKeyBfv Generates rlwe.EvaluationKey from ctx bfv secret key, containing a
+// relinearization key and rotation keys for every power of two below ctx.RotationSlots.
+// BFV slots form two rows, so a row rotation key is added if aggregates span both rows
+func (ctx *Context) GenEvalKeyBfv(maxDegree int) rlwe.EvaluationKey {
+	keygen := bfv.NewKeyGenerator(ctx.BfvParams)
+	eval := rlwe.EvaluationKey{
+		Rlk:  keygen.GenRelinearizationKey(ctx.BfvKeys.Sk, maxDegree),
+		Rtks: nil,
+	}
+
+	rowSize := ctx.BfvParams.N() >> 1
+	rotationSlots := ctx.rotationSlots(ctx.BfvParams.N())
+	rotations := powerOfTwoRotations(min(rotationSlots, rowSize))
+	if len(rotations) > 0 || rotationSlots > rowSize {
+		eval.Rtks = keygen.GenRotationKeysForRotations(rotations, rotationSlots > rowSize, ctx.BfvKeys.Sk)
+	}
+	return eval
+}
+
+// rotationSlots returns ctx.RotationSlots limited by the number of slots,
+// treating RotationSlotsAll as all slots and zero as no rotations
+func (ctx *Context) rotationSlots(slots int) int {
+	if ctx.RotationSlots == 0 {
+		return 1
+	}
+	if ctx.RotationSlots < 0 || ctx.RotationSlots > slots {
+		return slots
+	}
+	return ctx.RotationSlots
+}
+
+// powerOfTwoRotations returns all powers of two below n, which is enough
+// to sum any number of leading slots up to n with rotate-and-add
+func powerOfTwoRotations(n int) []int {
+	var rotations []int
+	for k := 1; k < n; k <<= 1 {
+		rotations = append(rotations, k)
+	}
+	return rotations
+}
+
+// SetEvalKeysByMethod Generates EvalKeys for specified method and recreates
+// the evaluator of ctx
+func (ctx *Context) SetEvalKeysByMethod(method Method) error {
 	switch method {
 	case CKKS:
-		CkksKeys = GenKeysCKKS()
-		data, err = json.Marshal(CkksKeys)
-		if err != nil {
-			panic(err)
+		ctx.EvalKeysCkks = EvalKeys{
+			EvalKey1: ctx.GenEvalKeyCkks(1),
+			KeyID:    ctx.KeyID,
 		}
-		err = os.WriteFile(keysFileLocation, data, 0644)
-		if err != nil {
-			panic(err)
-		}
-		log.Println("Keys generated and saved (CKKS)")
+		ctx.resetCkks()
+		log.Println("EvalKeys keys generated (CKKS)")
 	case BFV:
-		BfvKeys = GenKeysBFV()
-		data, err = json.Marshal(BfvKeys)
-		if err != nil {
-			panic(err)
-		}
-		err = os.WriteFile(keysFileLocation, data, 0644)
-		if err != nil {
-			panic(err)
+		ctx.EvalKeysBfv = EvalKeys{
+			EvalKey1: ctx.GenEvalKeyBfv(1),
+			KeyID:    ctx.KeyID,
 		}
-		log.Println("Keys generated and saved (BFV)")
+		ctx.resetBfv()
+		log.Println("EvalKeys keys generated (BFV)")
 	default:
-		log.Panic("unknown method")
+		return errUnknownMethod
 	}
+	return nil
 }
 
-// LoadAndSetKeys Loads KeyPair from keysFileLocation json file
-func LoadAndSetKeys(keysFileLocation string, method Method) {
+// LoadOrGenerateKeys checks if keys file exists and if it does - loads it into ctx
+// If it doesn't - generates a new keys file for specified method.
+// The secret key is stored unencrypted, use LoadOrGenerateKeysFromStore to keep it encrypted
+func (ctx *Context) LoadOrGenerateKeys(keysFileLocation string, method Method) error {
+	var err error
+	if _, statErr := os.Stat(keysFileLocation); os.IsNotExist(statErr) {
+		log.Printf("Keys file '%s' not found. Generating new keys\n", keysFileLocation)
+		err = ctx.GenerateAndSetAndSaveKeys(keysFileLocation, method)
+	} else {
+		log.Println("Loading keys from file...")
+		err = ctx.LoadAndSetKeys(keysFileLocation, method)
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.SetEvalKeysByMethod(method)
+}
+
+// GenerateAndSetAndSaveKeys Generates new KeyPair for ctx and saves it to
+// keysFileLocation json file
+func (ctx *Context) GenerateAndSetAndSaveKeys(keysFileLocation string, method Method) error {
+	var keys KeyPair
+	switch method {
+	case CKKS:
+		keys = ctx.GenKeysCKKS()
+	case BFV:
+		keys = ctx.GenKeysBFV()
+	default:
+		return errUnknownMethod
+	}
+
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(keysFileLocation, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	ctx.setKeys(keys, method)
+	log.Printf("Keys generated and saved (%s)\n", method)
+	return nil
+}
+
+// LoadAndSetKeys Loads KeyPair from keysFileLocation json file into ctx
+func (ctx *Context) LoadAndSetKeys(keysFileLocation string, method Method) error {
+	if method != CKKS && method != BFV {
+		return errUnknownMethod
+	}
+
 	data, err := os.ReadFile(keysFileLocation)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	var keys KeyPair
+	err = json.Unmarshal(data, &keys)
+	if err != nil {
+		return err
+	}
+
+	ctx.setKeys(keys, method)
+	log.Printf("Keys loaded from file (%s)\n", method)
+	return nil
+}
+
+// LoadOrGenerateKeysFromStore Loads KeyPair of specified method from store into ctx.
+// If store has no such keys - generates new keys and saves them to store
+func (ctx *Context) LoadOrGenerateKeysFromStore(store KeyStore, method Method) error {
+	err := ctx.loadKeysFromStore(store, method)
+	if !errors.Is(err, ErrKeysNotFound) {
+		return err
+	}
+
+	name := keyStoreName(ctx.KeyID, method)
+	log.Printf("Keys '%s' not found in key store. Generating new keys\n", name)
+	var keys KeyPair
+	if method == CKKS {
+		keys = ctx.GenKeysCKKS()
+	} else {
+		keys = ctx.GenKeysBFV()
+	}
+	if err := store.Save(name, keys); err != nil {
+		return err
+	}
+	log.Printf("Keys generated and saved to key store (%s)\n", method)
+
+	ctx.setKeys(keys, method)
+	return ctx.SetEvalKeysByMethod(method)
+}
+
+// loadKeysFromStore loads KeyPair of specified method and ctx.KeyID from store into ctx
+func (ctx *Context) loadKeysFromStore(store KeyStore, method Method) error {
+	if method != CKKS && method != BFV {
+		return errUnknownMethod
 	}
 
+	keys, err := store.Load(keyStoreName(ctx.KeyID, method))
+	if err != nil {
+		return err
+	}
+	log.Printf("Keys loaded from key store (%s)\n", method)
+
+	ctx.setKeys(keys, method)
+	return ctx.SetEvalKeysByMethod(method)
+}
+
+// keyStoreName returns the name KeyPair of method and key version keyID is saved
+// under in a KeyStore, e.g. "ckks" for keys without version or "2024-01-bfv"
+func keyStoreName(keyID string, method Method) string {
+	name := strings.ToLower(method.String())
+	if keyID == "" {
+		return name
+	}
+	return keyID + "-" + name
+}
+
+// setKeys sets KeyPair of specified method and recreates primitives depending on it
+func (ctx *Context) setKeys(keys KeyPair, method Method) {
 	switch method {
 	case CKKS:
-		err = json.Unmarshal(data, &CkksKeys)
-		if err != nil {
-			panic(err)
-		}
-		log.Println("Keys loaded from file (CKKS)")
+		ctx.CkksKeys = keys
+		ctx.resetCkks()
 	case BFV:
-		err = json.Unmarshal(data, &BfvKeys)
-		if err != nil {
-			panic(err)
+		ctx.BfvKeys = keys
+		ctx.resetBfv()
+	}
+}
+
+// String Returns the name of encryption method
+func (m Method) String() string {
+	switch m {
+	case CKKS:
+		return "CKKS"
+	case BFV:
+		return "BFV"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON Encodes EvalKeys as JSON, keeping relinearization and rotation
+// keys in lattigo binary form
+func (keys EvalKeys) MarshalJSON() ([]byte, error) {
+	var data evalKeysJSON
+	var err error
+	if keys.EvalKey1.Rlk != nil {
+		if data.Rlk, err = keys.EvalKey1.Rlk.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	if keys.EvalKey1.Rtks != nil {
+		if data.Rtks, err = keys.EvalKey1.Rtks.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(struct {
+		Version  int `json:"version"`
+		EvalKey1 evalKeysJSON
+		KeyID    string `json:",omitempty"`
+	}{evalKeysVersion, data, keys.KeyID})
+}
+
+// UnmarshalJSON Decodes EvalKeys encoded with MarshalJSON, or in the format of earlier releases
+func (keys *EvalKeys) UnmarshalJSON(b []byte) error {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(b, &header); err != nil {
+		return err
+	}
+
+	switch header.Version {
+	case 0:
+		var legacy legacyEvalKeys
+		if err := json.Unmarshal(b, &legacy); err != nil {
+			return err
 		}
-		log.Println("Keys loaded from file (BFV)")
+		*keys = EvalKeys(legacy)
+		return nil
+	case evalKeysVersion:
 	default:
-		log.Panic("unknown method")
+		return fmt.Errorf("unsupported eval keys format version %d", header.Version)
+	}
+
+	var wrapper struct {
+		EvalKey1 evalKeysJSON
+		KeyID    string
+	}
+	if err := json.Unmarshal(b, &wrapper); err != nil {
+		return err
+	}
+
+	keys.KeyID = wrapper.KeyID
+	keys.EvalKey1 = rlwe.EvaluationKey{}
+	if len(wrapper.EvalKey1.Rlk) > 0 {
+		keys.EvalKey1.Rlk = new(rlwe.RelinearizationKey)
+		if err := keys.EvalKey1.Rlk.UnmarshalBinary(wrapper.EvalKey1.Rlk); err != nil {
+			return err
+		}
+	}
+	if len(wrapper.EvalKey1.Rtks) > 0 {
+		keys.EvalKey1.Rtks = new(rlwe.RotationKeySet)
+		if err := keys.EvalKey1.Rtks.UnmarshalBinary(wrapper.EvalKey1.Rtks); err != nil {
+			return err
+		}
 	}
+	return nil
 }
@@ -2,8 +2,12 @@ package homomorphicEncryption
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
 	"github.com/gin-gonic/gin"
 	"github.com/ldsec/lattigo/v2/bfv"
 	"github.com/ldsec/lattigo/v2/ckks"
@@ -36,268 +40,706 @@ var (
 	}
 )
 
-// StartSecureServer Start HTTPS server. Port must be passed as is, without ':'
+// APIToken Bearer API token client helpers present to the server, if not empty
+var APIToken string
+
+// UseClientCertificate Makes client helpers present the certificate in PEM file certFile
+// with the private key in PEM file keyFile to servers requiring mutual TLS
+func UseClientCertificate(certFile string, keyFile string) error {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	transport, ok := HttpsServer.Transport.(*http.Transport)
+	if !ok {
+		return errors.New("HttpsServer must use *http.Transport to present a client certificate")
+	}
+	transport = transport.Clone()
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.Certificates = []tls.Certificate{certificate}
+
+	HttpsServer = &http.Client{Transport: transport, Timeout: HttpsServer.Timeout}
+	return nil
+}
+
+// StartSecureServer Start HTTPS server. Port must be passed as is, without ':'.
+// Panics if the server can't start.
+//
+// Deprecated: use NewDefaultServer, which can be configured and shut down
 func StartSecureServer(port string, certFile string, keyFile string) {
+	StartSecureServerWithContext(globalContext(), port, certFile, keyFile)
+}
+
+// StartSecureServerWithContext Start HTTPS server serving params and keys of ctx.
+// Port must be passed as is, without ':'. Panics if the server can't start.
+//
+// Deprecated: use NewServer, which can be configured and shut down
+func StartSecureServerWithContext(ctx *Context, port string, certFile string, keyFile string) {
 	gin.SetMode(gin.ReleaseMode)
-	r := gin.Default()
 
-	r.POST("/decrypt_computations_ckks", handleDecryptCkks)
-	r.GET("/get_ckks_params", handleGetCkksParams)
-	r.GET("/get_ckks_eval_keys", handleGetEvalKeysCkks)
+	server := NewServer(ctx, WithAddress(":"+port), WithCertificate(certFile, keyFile))
+	if err := server.Start(); err != nil {
+		panic("HTTPS server could not start: " + err.Error())
+	}
+}
 
-	r.POST("/decrypt_computations_bfv", handleDecryptBfv)
-	r.GET("/get_bfv_params", handleGetBfvParams)
-	r.GET("/get_bfv_eval_keys", handleGetEvalKeysBfv)
+// StartSecureServerWithKeyRing Start HTTPS server serving params and keys of the active
+// key version of ring and decrypting data encrypted with any of its key versions.
+// Port must be passed as is, without ':'. Panics if the server can't start.
+//
+// Deprecated: use NewKeyRingServer, which can be configured and shut down
+func StartSecureServerWithKeyRing(ring *KeyRing, port string, certFile string, keyFile string) {
+	gin.SetMode(gin.ReleaseMode)
 
-	server := &http.Server{
-		Addr:    ":" + port,
-		Handler: r,
+	server := NewKeyRingServer(ring, WithAddress(":"+port), WithCertificate(certFile, keyFile))
+	if err := server.Start(); err != nil {
+		panic("HTTPS server could not start: " + err.Error())
 	}
+}
 
-	err := server.ListenAndServeTLS(certFile, keyFile)
-	if err != nil {
-		panic("HTTPS server could not start: " + err.Error())
+// keyResolver finds the Context serving a request
+type keyResolver interface {
+	// Active Returns the Context used for params and keys requests without key ID
+	Active() *Context
+	// Get Returns the Context of key version keyID or ErrUnknownKey
+	Get(keyID string) (*Context, error)
+}
+
+// singleKey keyResolver serving a single Context
+type singleKey struct {
+	ctx *Context
+}
+
+func (k singleKey) Active() *Context {
+	return k.ctx
+}
+
+func (k singleKey) Get(keyID string) (*Context, error) {
+	if keyID != k.ctx.KeyID {
+		return nil, fmt.Errorf("%w '%s'", ErrUnknownKey, keyID)
 	}
+	return k.ctx, nil
 }
 
-// GetCKKSParamsFromServer Retrieve CKKS parameters from server
-func GetCKKSParamsFromServer(serverURL string) (ckks.Parameters, error) {
-	client := HttpsServer
+// RouterOption Configures optional features of routers created by NewRouter and NewKeyRingRouter,
+// and of the routes of a Server
+type RouterOption func(*routerConfig)
+
+// routerConfig optional features of a router
+type routerConfig struct {
+	policy       *DecryptionPolicy
+	auth         Authenticator
+	prefix       string
+	middleware   []gin.HandlerFunc
+	maxBodyBytes int64
+	// maxBatchItems Maximum number of items of batch decryption requests, zero means no limit
+	maxBatchItems int
+}
 
-	resp, err := client.Get(serverURL)
-	if err != nil {
-		return ckks.Parameters{}, err
+// DefaultMaxBatchItems Maximum number of items of batch decryption requests unless
+// changed with WithMaxBatchItems
+const DefaultMaxBatchItems = 1000
+
+// WithDecryptionPolicy Makes decryption endpoints only decrypt results policy approves,
+// responding with http.StatusForbidden to others. Without a policy any ciphertext is decrypted
+func WithDecryptionPolicy(policy *DecryptionPolicy) RouterOption {
+	return func(config *routerConfig) {
+		config.policy = policy
 	}
-	defer resp.Body.Close()
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return ckks.Parameters{}, err
+// WithAuthenticator Makes all routes require clients authenticated by auth, responding with
+// http.StatusUnauthorized to others and with http.StatusForbidden to clients lacking
+// PermissionParams, PermissionEvalKeys or PermissionDecrypt of the route
+func WithAuthenticator(auth Authenticator) RouterOption {
+	return func(config *routerConfig) {
+		config.auth = auth
 	}
+}
 
-	var response struct {
-		CKKSParams string `json:"ckks_params"`
+// WithRoutePrefix Mounts all routes under prefix, e.g. "/he/v1" serves
+// "/he/v1/decrypt_computations_ckks"
+func WithRoutePrefix(prefix string) RouterOption {
+	return func(config *routerConfig) {
+		config.prefix = prefix
 	}
+}
 
-	if err := json.Unmarshal(body, &response); err != nil {
-		return ckks.Parameters{}, err
+// WithMiddleware Adds middleware running before every route, in the order passed
+func WithMiddleware(middleware ...gin.HandlerFunc) RouterOption {
+	return func(config *routerConfig) {
+		config.middleware = append(config.middleware, middleware...)
 	}
+}
 
-	var ckksParams ckks.Parameters
-	if err := json.Unmarshal([]byte(response.CKKSParams), &ckksParams); err != nil {
-		return ckks.Parameters{}, err
+// WithMaxBodyBytes Limits request bodies to n bytes, responding with
+// http.StatusRequestEntityTooLarge to larger ones. Zero means no limit
+func WithMaxBodyBytes(n int64) RouterOption {
+	return func(config *routerConfig) {
+		config.maxBodyBytes = n
 	}
+}
 
-	return ckksParams, nil
+// WithMaxBatchItems Limits batch decryption requests to n items, responding with
+// http.StatusRequestEntityTooLarge to larger ones. Items are decrypted one by one while
+// the request is handled, so this bounds the time a single request takes.
+// Defaults to DefaultMaxBatchItems, zero means no limit
+func WithMaxBatchItems(n int) RouterOption {
+	return func(config *routerConfig) {
+		config.maxBatchItems = n
+	}
 }
 
-// GetBFVParamsFromServer Retrieve BFV parameters from server
-func GetBFVParamsFromServer(serverURL string) (bfv.Parameters, error) {
-	client := HttpsServer
+// NewRouter Creates a gin.Engine with all server routes bound to ctx
+func NewRouter(ctx *Context, options ...RouterOption) *gin.Engine {
+	return newRouter(singleKey{ctx}, options)
+}
 
-	resp, err := client.Get(serverURL)
-	if err != nil {
-		return bfv.Parameters{}, err
+// NewKeyRingRouter Creates a gin.Engine with all server routes bound to ring.
+// Decryption requests are routed to the key version of the ciphertext, eval keys
+// of retired versions can be requested with key_id query parameter
+func NewKeyRingRouter(ring *KeyRing, options ...RouterOption) *gin.Engine {
+	return newRouter(ring, options)
+}
+
+// newRouter creates a gin.Engine with all server routes bound to keys
+func newRouter(keys keyResolver, options []RouterOption) *gin.Engine {
+	config := routerConfig{maxBatchItems: DefaultMaxBatchItems}
+	for _, option := range options {
+		option(&config)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return bfv.Parameters{}, err
+	params := requirePermission(config.auth, PermissionParams)
+	evalKeys := requirePermission(config.auth, PermissionEvalKeys)
+	decrypt := requirePermission(config.auth, PermissionDecrypt)
+
+	r := gin.Default()
+	r.Use(config.middleware...)
+	if config.maxBodyBytes > 0 {
+		r.Use(limitBodySize(config.maxBodyBytes))
 	}
 
-	var response struct {
-		BFVParams string `json:"bfv_params"`
+	routes := r.Group(config.prefix)
+
+	routes.POST("/decrypt_computations_ckks", decrypt, handleDecryptCkks(keys, config.policy))
+	routes.POST("/decrypt_computations_ckks/batch", decrypt, handleDecryptBatchCkks(keys, config.policy, config.maxBatchItems))
+	routes.GET("/get_ckks_params", params, handleGetCkksParams(keys))
+	routes.GET("/get_ckks_eval_keys", evalKeys, handleGetEvalKeysCkks(keys))
+
+	routes.POST("/decrypt_computations_bfv", decrypt, handleDecryptBfv(keys, config.policy))
+	routes.POST("/decrypt_computations_bfv/batch", decrypt, handleDecryptBatchBfv(keys, config.policy, config.maxBatchItems))
+	routes.GET("/get_bfv_params", params, handleGetBfvParams(keys))
+	routes.GET("/get_bfv_eval_keys", evalKeys, handleGetEvalKeysBfv(keys))
+
+	return r
+}
+
+// limitBodySize returns a middleware limiting request bodies to n bytes
+func limitBodySize(n int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
 	}
+}
 
-	if err := json.Unmarshal(body, &response); err != nil {
-		return bfv.Parameters{}, err
+// GetCKKSParamsFromServer Retrieve CKKS parameters from server
+func GetCKKSParamsFromServer(serverURL string) (ckks.Parameters, error) {
+	body, err := sendRequest(http.MethodGet, serverURL, nil)
+	if err != nil {
+		return ckks.Parameters{}, err
 	}
+	return decodeCkksParams(body)
+}
 
-	var bfvParams bfv.Parameters
-	if err := json.Unmarshal([]byte(response.BFVParams), &bfvParams); err != nil {
+// GetBFVParamsFromServer Retrieve BFV parameters from server
+func GetBFVParamsFromServer(serverURL string) (bfv.Parameters, error) {
+	body, err := sendRequest(http.MethodGet, serverURL, nil)
+	if err != nil {
 		return bfv.Parameters{}, err
 	}
-
-	return bfvParams, nil
+	return decodeBfvParams(body)
 }
 
 // GetCKKSParamsFromServer Retrieve CKKS EvalKeys from server
 func GetCkksEvalKeysFromServer(serverURL string) (EvalKeys, error) {
-	client := HttpsServer
-
-	resp, err := client.Get(serverURL)
+	body, err := sendRequest(http.MethodGet, serverURL, nil)
 	if err != nil {
 		return EvalKeys{}, err
 	}
-	defer resp.Body.Close()
+	return decodeCkksEvalKeys(body)
+}
 
-	body, err := io.ReadAll(resp.Body)
+// GetCKKSParamsFromServer Retrieve BFV EvalKeys from server
+func GetBfvEvalKeysFromServer(serverURL string) (EvalKeys, error) {
+	body, err := sendRequest(http.MethodGet, serverURL, nil)
 	if err != nil {
 		return EvalKeys{}, err
 	}
+	return decodeBfvEvalKeys(body)
+}
 
-	response := CkksEvalKeysResult{}
-	if err := json.Unmarshal(body, &response); err != nil {
-		return EvalKeys{}, err
+// SendComputationResultToServerCkks Send CKKS computation results to server and get a decrypted result
+func SendComputationResultToServerCkks(url string, encryptedResult []byte) (float64, error) {
+	return SendVerifiableResultToServerCkks(url, encryptedResult, nil)
+}
+
+// SendComputationResultToServerBfv Send BFV computation results to server and get a decrypted result
+func SendComputationResultToServerBfv(url string, encryptedResult []byte) (int64, error) {
+	return SendVerifiableResultToServerBfv(url, encryptedResult, nil)
+}
+
+// SendVerifiableResultToServerCkks Send CKKS computation results along with the transcript of
+// their computation to server with a DecryptionPolicy and get a decrypted result
+func SendVerifiableResultToServerCkks(url string, encryptedResult []byte, transcript *ComputationTranscript) (float64, error) {
+	body, err := sendDecryptRequest(url, decryptRequest{encryptedResult, transcript})
+	if err != nil {
+		return 0.0, err
 	}
+	return decodeDecryptedResult[float64](body)
+}
 
-	var ckksEvalKeys EvalKeys
-	if err := json.Unmarshal([]byte(response.EvalKeys), &ckksEvalKeys); err != nil {
-		return EvalKeys{}, err
+// SendVerifiableResultToServerBfv Send BFV computation results along with the transcript of
+// their computation to server with a DecryptionPolicy and get a decrypted result
+func SendVerifiableResultToServerBfv(url string, encryptedResult []byte, transcript *ComputationTranscript) (int64, error) {
+	body, err := sendDecryptRequest(url, decryptRequest{encryptedResult, transcript})
+	if err != nil {
+		return 0, err
 	}
+	return decodeDecryptedResult[int64](body)
+}
 
-	return ckksEvalKeys, nil
+// SendComputationResultsToServerCkks Send several CKKS computation results to the batch decryption
+// route of server, e.g. ".../decrypt_computations_ckks/batch", in a single request. Returns
+// decrypted results along with per-result errors, which are nil for decrypted results
+// and *ServerError for the others. The error is only returned if the whole request failed
+func SendComputationResultsToServerCkks(url string, encryptedResults [][]byte) ([]float64, []error, error) {
+	return SendVerifiableResultsToServerCkks(url, encryptedResults, nil)
 }
 
-// GetCKKSParamsFromServer Retrieve BFV EvalKeys from server
-func GetBfvEvalKeysFromServer(serverURL string) (EvalKeys, error) {
-	client := HttpsServer
+// SendComputationResultsToServerBfv Send several BFV computation results to the batch decryption
+// route of server, see SendComputationResultsToServerCkks
+func SendComputationResultsToServerBfv(url string, encryptedResults [][]byte) ([]int64, []error, error) {
+	return SendVerifiableResultsToServerBfv(url, encryptedResults, nil)
+}
 
-	resp, err := client.Get(serverURL)
+// SendVerifiableResultsToServerCkks Same as SendComputationResultsToServerCkks, sending
+// transcripts[i] along with encryptedResults[i] to server with a DecryptionPolicy
+func SendVerifiableResultsToServerCkks(url string, encryptedResults [][]byte, transcripts []*ComputationTranscript) ([]float64, []error, error) {
+	client, err := helperClient(url)
 	if err != nil {
-		return EvalKeys{}, err
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
+	return decryptBatch[float64](context.Background(), client, "", encryptedResults, transcripts)
+}
 
-	body, err := io.ReadAll(resp.Body)
+// SendVerifiableResultsToServerBfv Same as SendComputationResultsToServerBfv, sending
+// transcripts[i] along with encryptedResults[i] to server with a DecryptionPolicy
+func SendVerifiableResultsToServerBfv(url string, encryptedResults [][]byte, transcripts []*ComputationTranscript) ([]int64, []error, error) {
+	client, err := helperClient(url)
 	if err != nil {
-		return EvalKeys{}, err
+		return nil, nil, err
 	}
+	return decryptBatch[int64](context.Background(), client, "", encryptedResults, transcripts)
+}
 
-	response := BfvEvalKeysResult{}
-	if err := json.Unmarshal(body, &response); err != nil {
-		return EvalKeys{}, err
+// decryptRequest Body of decryption requests
+type decryptRequest struct {
+	EncryptedResult []byte                 `json:"encrypted_result"`
+	Transcript      *ComputationTranscript `json:"transcript,omitempty"`
+}
+
+// batchDecryptRequest Body of batch decryption requests
+type batchDecryptRequest struct {
+	Items []decryptRequest `json:"items"`
+}
+
+// batchDecryptResult Decrypted result or error of an item of a batch decryption request
+type batchDecryptResult[T int64 | float64] struct {
+	DecryptedResult *T     `json:"decrypted_result,omitempty"`
+	Error           string `json:"error,omitempty"`
+	Status          int    `json:"status,omitempty"`
+}
+
+// batchDecryptResponse Body of batch decryption responses, holding results in the order of request items
+type batchDecryptResponse[T int64 | float64] struct {
+	Results []batchDecryptResult[T] `json:"results"`
+}
+
+// newBatchDecryptRequest pairs encryptedResults with transcripts, which may be nil if
+// results have no transcripts
+func newBatchDecryptRequest(encryptedResults [][]byte, transcripts []*ComputationTranscript) (batchDecryptRequest, error) {
+	if transcripts != nil && len(transcripts) != len(encryptedResults) {
+		return batchDecryptRequest{}, fmt.Errorf("got %d transcripts for %d results", len(transcripts), len(encryptedResults))
 	}
 
-	var bfvEvalKeys EvalKeys
-	if err := json.Unmarshal([]byte(response.EvalKeys), &bfvEvalKeys); err != nil {
-		return EvalKeys{}, err
+	req := batchDecryptRequest{Items: make([]decryptRequest, len(encryptedResults))}
+	for i, encryptedResult := range encryptedResults {
+		req.Items[i].EncryptedResult = encryptedResult
+		if transcripts != nil {
+			req.Items[i].Transcript = transcripts[i]
+		}
 	}
+	return req, nil
+}
 
-	return bfvEvalKeys, nil
+// sendDecryptRequest posts req to url, returning the response body
+func sendDecryptRequest(url string, req decryptRequest) ([]byte, error) {
+	client, err := helperClient(url)
+	if err != nil {
+		return nil, err
+	}
+	return client.decrypt(context.Background(), "", req)
 }
 
-// SendComputationResultToServerCkks Send CKKS computation results to server and get a decrypted result
-func SendComputationResultToServerCkks(url string, encryptedResult []byte) (float64, error) {
-	data, err := json.Marshal(map[string][]byte{"encrypted_result": encryptedResult})
+// sendRequest sends a request with credentials of client helpers, see APIToken and
+// UseClientCertificate, returning the response body. Responses with statuses other
+// than http.StatusOK are returned as *ServerError
+func sendRequest(method string, url string, body []byte) ([]byte, error) {
+	client, err := helperClient(url)
 	if err != nil {
-		return 0.0, err
+		return nil, err
 	}
+	return client.do(context.Background(), method, "", body)
+}
 
-	client := HttpsServer
+// helperClient returns a Client of route url of a server, e.g. ".../decrypt_computations_ckks",
+// configured with the package-level HttpsServer and APIToken of client helpers
+func helperClient(url string) (*Client, error) {
+	return NewClient(url, WithHTTPClient(HttpsServer), WithAPIToken(APIToken))
+}
 
-	resp, err := client.Post(url, "application/json", bytes.NewBuffer(data))
+// doRequest sends a single request with httpClient, presenting token if not empty, and returns
+// the response body. Responses with statuses other than http.StatusOK are returned as *ServerError
+func doRequest(ctx context.Context, httpClient *http.Client, token string, method string, url string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
 	if err != nil {
-		return 0.0, err
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	response := DecryptedResultResponseFloat{}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return 0.0, err
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		serverErr := &ServerError{StatusCode: resp.StatusCode, Status: resp.Status}
+		var response struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &response) == nil {
+			serverErr.Message = response.Error
+		}
+		return nil, serverErr
+	}
+	return data, nil
+}
+
+// decodeCkksParams decodes the response of the CKKS params route
+func decodeCkksParams(body []byte) (ckks.Parameters, error) {
+	var response struct {
+		CKKSParams string `json:"ckks_params"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ckks.Parameters{}, err
 	}
 
-	return response.DecryptedResult, nil
+	var ckksParams ckks.Parameters
+	if err := json.Unmarshal([]byte(response.CKKSParams), &ckksParams); err != nil {
+		return ckks.Parameters{}, err
+	}
+	return ckksParams, nil
 }
 
-// SendComputationResultToServerBfv Send BFV computation results to server and get a decrypted result
-func SendComputationResultToServerBfv(url string, encryptedResult []byte) (int64, error) {
-	data, err := json.Marshal(map[string][]byte{"encrypted_result": encryptedResult})
-	if err != nil {
-		return 0.0, err
+// decodeBfvParams decodes the response of the BFV params route
+func decodeBfvParams(body []byte) (bfv.Parameters, error) {
+	var response struct {
+		BFVParams string `json:"bfv_params"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return bfv.Parameters{}, err
 	}
 
-	client := HttpsServer
+	var bfvParams bfv.Parameters
+	if err := json.Unmarshal([]byte(response.BFVParams), &bfvParams); err != nil {
+		return bfv.Parameters{}, err
+	}
+	return bfvParams, nil
+}
 
-	resp, err := client.Post(url, "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return 0.0, err
+// decodeCkksEvalKeys decodes the response of the CKKS eval keys route
+func decodeCkksEvalKeys(body []byte) (EvalKeys, error) {
+	response := CkksEvalKeysResult{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return EvalKeys{}, err
 	}
-	defer resp.Body.Close()
 
-	response := DecryptedResultResponseInt{}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return 0.0, err
+	var ckksEvalKeys EvalKeys
+	if err := json.Unmarshal([]byte(response.EvalKeys), &ckksEvalKeys); err != nil {
+		return EvalKeys{}, err
 	}
+	return ckksEvalKeys, nil
+}
 
-	return response.DecryptedResult, nil
+// decodeBfvEvalKeys decodes the response of the BFV eval keys route
+func decodeBfvEvalKeys(body []byte) (EvalKeys, error) {
+	response := BfvEvalKeysResult{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return EvalKeys{}, err
+	}
+
+	var bfvEvalKeys EvalKeys
+	if err := json.Unmarshal([]byte(response.EvalKeys), &bfvEvalKeys); err != nil {
+		return EvalKeys{}, err
+	}
+	return bfvEvalKeys, nil
+}
+
+// decodeBatchDecryptedResults decodes the response of a batch decryption route for n results,
+// returning per-result errors as *ServerError
+func decodeBatchDecryptedResults[T int64 | float64](body []byte, n int) ([]T, []error, error) {
+	var response batchDecryptResponse[T]
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, nil, err
+	}
+	if len(response.Results) != n {
+		return nil, nil, fmt.Errorf("server returned %d results for %d encrypted results", len(response.Results), n)
+	}
+
+	decResults := make([]T, n)
+	errs := make([]error, n)
+	for i, result := range response.Results {
+		switch {
+		case result.Error != "" || result.Status != 0:
+			status := result.Status
+			if status == 0 {
+				status = http.StatusInternalServerError
+			}
+			errs[i] = &ServerError{StatusCode: status, Status: fmt.Sprintf("%d %s", status, http.StatusText(status)), Message: result.Error}
+		case result.DecryptedResult == nil:
+			errs[i] = ErrNoDecryptedResult
+		default:
+			decResults[i] = *result.DecryptedResult
+		}
+	}
+	return decResults, errs, nil
+}
+
+// decodeDecryptedResult decodes the response of a decryption route, returning ErrNoDecryptedResult
+// instead of a zero result if the response has none
+func decodeDecryptedResult[T int64 | float64](body []byte) (T, error) {
+	var response struct {
+		DecryptedResult *T `json:"decrypted_result"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0, err
+	}
+	if response.DecryptedResult == nil {
+		return 0, ErrNoDecryptedResult
+	}
+	return *response.DecryptedResult, nil
 }
 
 // handleGetCkksParams A request handler for CkksParams retrieving
-func handleGetCkksParams(c *gin.Context) {
-	paramsJSON, err := json.Marshal(CkksParams)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ckks serialization error"})
-		return
+func handleGetCkksParams(keys keyResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		paramsJSON, err := json.Marshal(keys.Active().CkksParams)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ckks serialization error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ckks_params": string(paramsJSON)})
 	}
-	c.JSON(http.StatusOK, gin.H{"ckks_params": string(paramsJSON)})
 }
 
 // handleGetBfvParams A request handler for BfvParams retrieving
-func handleGetBfvParams(c *gin.Context) {
-	paramsJSON, err := json.Marshal(BfvParams)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "bfv serialization error"})
-		return
+func handleGetBfvParams(keys keyResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		paramsJSON, err := json.Marshal(keys.Active().BfvParams)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "bfv serialization error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"bfv_params": string(paramsJSON)})
 	}
-	c.JSON(http.StatusOK, gin.H{"bfv_params": string(paramsJSON)})
 }
 
 // handleDecryptCkks A request handler for decrypting a result of client calculations with CKKS
-func handleDecryptCkks(c *gin.Context) {
-	var req struct {
-		EncryptedResult []byte `json:"encrypted_result"`
-	}
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
+func handleDecryptCkks(keys keyResolver, policy *DecryptionPolicy) gin.HandlerFunc {
+	return handleDecrypt(keys, policy, (*Context).DecryptCKKS)
+}
 
-	decResult, err := DecryptCKKS(req.EncryptedResult)
+// handleDecryptBfv A request handler for decrypting a result of client calculations with BFV
+func handleDecryptBfv(keys keyResolver, policy *DecryptionPolicy) gin.HandlerFunc {
+	return handleDecrypt(keys, policy, (*Context).DecryptBFV)
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// handleDecryptBatchCkks A request handler for decrypting several results of client calculations with CKKS
+func handleDecryptBatchCkks(keys keyResolver, policy *DecryptionPolicy, maxItems int) gin.HandlerFunc {
+	return handleDecryptBatch(keys, policy, maxItems, (*Context).DecryptCKKS)
+}
+
+// handleDecryptBatchBfv A request handler for decrypting several results of client calculations with BFV
+func handleDecryptBatchBfv(keys keyResolver, policy *DecryptionPolicy, maxItems int) gin.HandlerFunc {
+	return handleDecryptBatch(keys, policy, maxItems, (*Context).DecryptBFV)
+}
+
+// handleDecrypt returns a request handler decrypting a result of client calculations with decrypt
+func handleDecrypt[T int64 | float64](keys keyResolver, policy *DecryptionPolicy, decrypt func(*Context, []byte) (T, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req decryptRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(bindErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		decResult, status, err := decryptResult(keys, policy, req, decrypt)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"decrypted_result": decResult})
 	}
-	c.JSON(http.StatusOK, gin.H{"decrypted_result": decResult})
 }
 
-// handleDecryptBfv A request handler for decrypting a result of client calculations with BFV
-func handleDecryptBfv(c *gin.Context) {
-	var req struct {
-		EncryptedResult []byte `json:"encrypted_result"`
+// handleDecryptBatch returns a request handler decrypting several results of client calculations
+// with decrypt. Results are decrypted independently, the response holds either the decrypted
+// result or the error of every one of them. Requests with more than maxItems items are rejected,
+// unless maxItems is zero
+func handleDecryptBatch[T int64 | float64](keys keyResolver, policy *DecryptionPolicy, maxItems int, decrypt func(*Context, []byte) (T, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req batchDecryptRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(bindErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		if maxItems > 0 && len(req.Items) > maxItems {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("batch has %d items, at most %d are allowed", len(req.Items), maxItems)})
+			return
+		}
+
+		results := make([]batchDecryptResult[T], len(req.Items))
+		for i, item := range req.Items {
+			decResult, status, err := decryptResult(keys, policy, item, decrypt)
+			if err != nil {
+				results[i] = batchDecryptResult[T]{Error: err.Error(), Status: status}
+				continue
+			}
+			results[i].DecryptedResult = &decResult
+		}
+		c.JSON(http.StatusOK, batchDecryptResponse[T]{Results: results})
 	}
-	if err := c.BindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+}
+
+// decryptResult decrypts the result of req with decrypt if policy approves it, returning
+// the HTTP status of the failure along with the error
+func decryptResult[T int64 | float64](keys keyResolver, policy *DecryptionPolicy, req decryptRequest, decrypt func(*Context, []byte) (T, error)) (T, int, error) {
+	ctx, err := contextFor(keys, req.EncryptedResult)
+	if err != nil {
+		return 0, http.StatusBadRequest, err
 	}
 
-	decResult, err := DecryptBFV(req.EncryptedResult)
+	if policy != nil {
+		if err := policy.Authorize(ctx, req.EncryptedResult, req.Transcript); err != nil {
+			return 0, http.StatusForbidden, err
+		}
+	}
 
+	decResult, err := decrypt(ctx, req.EncryptedResult)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return 0, decryptErrorStatus(err), err
 	}
-	c.JSON(http.StatusOK, gin.H{"decrypted_result": decResult})
+	return decResult, http.StatusOK, nil
 }
 
 // handleGetBfvParams A request handler for CKKS EvalKeys retrieving
-func handleGetEvalKeysCkks(c *gin.Context) {
-	paramsJSON, err := json.Marshal(EvalKeysCkks)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "ckks eval keys serialization error"})
-		return
+func handleGetEvalKeysCkks(keys keyResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := keys.Active()
+		if keyID, ok := c.GetQuery("key_id"); ok {
+			var err error
+			if ctx, err = keys.Get(keyID); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		paramsJSON, err := json.Marshal(ctx.EvalKeysCkks)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ckks eval keys serialization error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ckks_eval_keys": string(paramsJSON)})
 	}
-	c.JSON(http.StatusOK, gin.H{"ckks_eval_keys": string(paramsJSON)})
 }
 
 // handleGetBfvParams A request handler for BFV EvalKeys retrieving
-func handleGetEvalKeysBfv(c *gin.Context) {
-	paramsJSON, err := json.Marshal(EvalKeysBfv)
+func handleGetEvalKeysBfv(keys keyResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := keys.Active()
+		if keyID, ok := c.GetQuery("key_id"); ok {
+			var err error
+			if ctx, err = keys.Get(keyID); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		paramsJSON, err := json.Marshal(ctx.EvalKeysBfv)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "bfv eval keys serialization error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"bfv_eval_keys": string(paramsJSON)})
+	}
+}
+
+// contextFor returns the Context of the key version encryptedData was encrypted with
+func contextFor(keys keyResolver, encryptedData []byte) (*Context, error) {
+	keyID, err := envelope.KeyID(encryptedData)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "bfv eval keys serialization error"})
-		return
+		return nil, err
 	}
-	c.JSON(http.StatusOK, gin.H{"bfv_eval_keys": string(paramsJSON)})
+	return keys.Get(keyID)
+}
+
+// bindErrorStatus returns http.StatusRequestEntityTooLarge for request bodies exceeding
+// the limit of WithMaxBodyBytes and http.StatusBadRequest for other malformed requests
+func bindErrorStatus(err error) int {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// decryptErrorStatus returns http.StatusBadRequest for ciphertexts that don't belong
+// to the server, e.g. of another scheme, and http.StatusInternalServerError otherwise
+func decryptErrorStatus(err error) int {
+	var schemeMismatch *envelope.SchemeMismatchError
+	var paramsMismatch *envelope.ParamsMismatchError
+	var keyMismatch *envelope.KeyMismatchError
+	var version *envelope.VersionError
+	if errors.As(err, &schemeMismatch) || errors.As(err, &paramsMismatch) || errors.As(err, &keyMismatch) ||
+		errors.As(err, &version) || errors.Is(err, envelope.ErrMalformed) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
 }
@@ -0,0 +1,217 @@
+package homomorphicEncryption
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"github.com/lib/pq"
+	"log"
+	"strings"
+)
+
+// ReEncryptionJob Migrates ciphertexts stored in BYTEA columns of a database table
+// from retired key versions to the active key version of Ring. Rows are processed
+// in batches ordered by IDColumn, each batch in its own transaction, so an interrupted
+// job can simply be run again
+type ReEncryptionJob struct {
+	Ring *KeyRing
+	DB   *sql.DB
+
+	Table    string
+	IDColumn string
+
+	CkksColumns []string
+	BfvColumns  []string
+
+	// BatchSize Number of rows per transaction, 100 if not set
+	BatchSize int
+}
+
+// ReEncryptionStats Result of a ReEncryptionJob run
+type ReEncryptionStats struct {
+	RowsScanned        int
+	RowsUpdated        int
+	CiphertextsUpdated int
+}
+
+// ReEncryptRow Re-encrypts ciphertexts of a single row in place, returning the number of
+// changed values. Values must be listed in the order of CkksColumns followed by BfvColumns.
+// NULL values are kept as is
+func (job *ReEncryptionJob) ReEncryptRow(values [][]byte) (int, error) {
+	if len(values) != len(job.CkksColumns)+len(job.BfvColumns) {
+		return 0, fmt.Errorf("expected %d values, got %d", len(job.CkksColumns)+len(job.BfvColumns), len(values))
+	}
+
+	activeKeyID := job.Ring.Active().KeyID
+	changed := 0
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		keyID, err := envelope.KeyID(value)
+		if err != nil {
+			return 0, err
+		}
+		if keyID == activeKeyID {
+			continue
+		}
+
+		var reEncrypted []byte
+		if i < len(job.CkksColumns) {
+			reEncrypted, err = job.Ring.ReEncryptCKKS(value)
+		} else {
+			reEncrypted, err = job.Ring.ReEncryptBFV(value)
+		}
+		if err != nil {
+			return 0, err
+		}
+		values[i] = reEncrypted
+		changed++
+	}
+	return changed, nil
+}
+
+// Run Re-encrypts all rows of the table. Stops at the first error or when ctx is done,
+// returning statistics of the batches committed so far
+func (job *ReEncryptionJob) Run(ctx context.Context) (ReEncryptionStats, error) {
+	var stats ReEncryptionStats
+	if job.Ring == nil || job.DB == nil {
+		return stats, errors.New("re-encryption job needs a KeyRing and a database")
+	}
+	if job.Table == "" || job.IDColumn == "" || len(job.CkksColumns)+len(job.BfvColumns) == 0 {
+		return stats, errors.New("re-encryption job needs a table, an id column and ciphertext columns")
+	}
+
+	batchSize := job.BatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	var lastID interface{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		batchStats, nextID, err := job.runBatch(ctx, lastID, batchSize)
+		if err != nil {
+			return stats, err
+		}
+		stats.RowsScanned += batchStats.RowsScanned
+		stats.RowsUpdated += batchStats.RowsUpdated
+		stats.CiphertextsUpdated += batchStats.CiphertextsUpdated
+
+		if batchStats.RowsScanned < batchSize {
+			log.Printf("Re-encryption of '%s' finished: %d rows scanned, %d rows updated\n", job.Table, stats.RowsScanned, stats.RowsUpdated)
+			return stats, nil
+		}
+		lastID = nextID
+	}
+}
+
+// runBatch re-encrypts up to batchSize rows with id greater than lastID in one
+// transaction, returning the id of the last scanned row
+func (job *ReEncryptionJob) runBatch(ctx context.Context, lastID interface{}, batchSize int) (ReEncryptionStats, interface{}, error) {
+	var stats ReEncryptionStats
+
+	tx, err := job.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, nil, err
+	}
+	defer tx.Rollback()
+
+	query, args := job.selectQuery(lastID, batchSize)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return stats, nil, err
+	}
+
+	type row struct {
+		id     interface{}
+		values [][]byte
+	}
+	var batch []row
+	columns := len(job.CkksColumns) + len(job.BfvColumns)
+	for rows.Next() {
+		r := row{values: make([][]byte, columns)}
+		dest := make([]interface{}, 0, columns+1)
+		dest = append(dest, &r.id)
+		for i := range r.values {
+			dest = append(dest, &r.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return stats, nil, err
+		}
+		batch = append(batch, r)
+	}
+	if err := rows.Err(); err != nil {
+		return stats, nil, err
+	}
+	rows.Close()
+
+	update := job.updateQuery()
+	var nextID interface{}
+	for _, r := range batch {
+		stats.RowsScanned++
+		nextID = r.id
+
+		changed, err := job.ReEncryptRow(r.values)
+		if err != nil {
+			return stats, nil, fmt.Errorf("row %v: %w", r.id, err)
+		}
+		if changed == 0 {
+			continue
+		}
+
+		args := make([]interface{}, 0, columns+1)
+		for _, value := range r.values {
+			args = append(args, value)
+		}
+		args = append(args, r.id)
+		if _, err := tx.ExecContext(ctx, update, args...); err != nil {
+			return stats, nil, fmt.Errorf("row %v: %w", r.id, err)
+		}
+		stats.RowsUpdated++
+		stats.CiphertextsUpdated += changed
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ReEncryptionStats{}, nil, err
+	}
+	return stats, nextID, nil
+}
+
+// selectQuery returns a query selecting the next batch of rows after lastID
+func (job *ReEncryptionJob) selectQuery(lastID interface{}, batchSize int) (string, []interface{}) {
+	columns := []string{pq.QuoteIdentifier(job.IDColumn)}
+	for _, column := range append(append([]string{}, job.CkksColumns...), job.BfvColumns...) {
+		columns = append(columns, pq.QuoteIdentifier(column))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), pq.QuoteIdentifier(job.Table))
+	args := []interface{}{}
+	if lastID != nil {
+		query += fmt.Sprintf(" WHERE %s > $1", pq.QuoteIdentifier(job.IDColumn))
+		args = append(args, lastID)
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT %d FOR UPDATE", pq.QuoteIdentifier(job.IDColumn), batchSize)
+	return query, args
+}
+
+// updateQuery returns a query updating all ciphertext columns of a row,
+// taking column values followed by row id as arguments
+func (job *ReEncryptionJob) updateQuery() string {
+	var assignments []string
+	for i, column := range append(append([]string{}, job.CkksColumns...), job.BfvColumns...) {
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", pq.QuoteIdentifier(column), i+1))
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s = $%d",
+		pq.QuoteIdentifier(job.Table),
+		strings.Join(assignments, ", "),
+		pq.QuoteIdentifier(job.IDColumn),
+		len(assignments)+1,
+	)
+}
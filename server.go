@@ -0,0 +1,163 @@
+package homomorphicEncryption
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Server HTTPS server serving params, eval keys and decryption routes. A Server is also
+// an http.Handler, so it can be mounted in an existing server or API gateway instead of
+// being started on its own
+type Server struct {
+	httpServer *http.Server
+	certFile   string
+	keyFile    string
+}
+
+// ServerOption Configures a Server created by NewServer or NewKeyRingServer. Every RouterOption
+// is also a ServerOption
+type ServerOption interface {
+	applyServer(config *serverConfig)
+}
+
+// serverOption ServerOption configuring only the Server, not its routes
+type serverOption func(*serverConfig)
+
+func (option serverOption) applyServer(config *serverConfig) {
+	option(config)
+}
+
+func (option RouterOption) applyServer(config *serverConfig) {
+	config.routerOptions = append(config.routerOptions, option)
+}
+
+// serverConfig options of a Server
+type serverConfig struct {
+	addr              string
+	tlsConfig         *tls.Config
+	certFile          string
+	keyFile           string
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	routerOptions     []RouterOption
+}
+
+// WithAddress Makes Start listen on addr, e.g. ":443" or "127.0.0.1:8443". Defaults to ":https"
+func WithAddress(addr string) ServerOption {
+	return serverOption(func(config *serverConfig) {
+		config.addr = addr
+	})
+}
+
+// WithTLSConfig Makes the Server use tlsConfig, e.g. one returned by MutualTLSConfig.
+// Certificates of tlsConfig are used if WithCertificate isn't passed
+func WithTLSConfig(tlsConfig *tls.Config) ServerOption {
+	return serverOption(func(config *serverConfig) {
+		config.tlsConfig = tlsConfig
+	})
+}
+
+// WithCertificate Makes the Server present the certificate in PEM file certFile with the private key
+// in PEM file keyFile
+func WithCertificate(certFile string, keyFile string) ServerOption {
+	return serverOption(func(config *serverConfig) {
+		config.certFile = certFile
+		config.keyFile = keyFile
+	})
+}
+
+// WithTimeouts Sets the maximum durations of reading a request, writing a response and waiting
+// for the next request on a keep-alive connection. Zero means no timeout. Decryption requests
+// of a DecryptionPolicy recompute their results, so write timeouts must leave time for that.
+// Defaults to no read and write timeouts and an idle timeout of 2 minutes, request headers
+// always time out after 10 seconds
+func WithTimeouts(read time.Duration, write time.Duration, idle time.Duration) ServerOption {
+	return serverOption(func(config *serverConfig) {
+		config.readTimeout = read
+		config.writeTimeout = write
+		config.idleTimeout = idle
+	})
+}
+
+// NewServer Creates a Server with all server routes bound to ctx
+func NewServer(ctx *Context, options ...ServerOption) *Server {
+	return newServer(singleKey{ctx}, options)
+}
+
+// NewDefaultServer Same as NewServer, using package-level variables
+func NewDefaultServer(options ...ServerOption) *Server {
+	return NewServer(globalContext(), options...)
+}
+
+// NewKeyRingServer Creates a Server with all server routes bound to ring, see NewKeyRingRouter
+func NewKeyRingServer(ring *KeyRing, options ...ServerOption) *Server {
+	return newServer(ring, options)
+}
+
+// newServer creates a Server with all server routes bound to keys
+func newServer(keys keyResolver, options []ServerOption) *Server {
+	config := serverConfig{
+		addr:              ":https",
+		readHeaderTimeout: 10 * time.Second,
+		idleTimeout:       2 * time.Minute,
+	}
+	for _, option := range options {
+		option.applyServer(&config)
+	}
+
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              config.addr,
+			Handler:           newRouter(keys, config.routerOptions),
+			TLSConfig:         config.tlsConfig,
+			ReadTimeout:       config.readTimeout,
+			ReadHeaderTimeout: config.readHeaderTimeout,
+			WriteTimeout:      config.writeTimeout,
+			IdleTimeout:       config.idleTimeout,
+		},
+		certFile: config.certFile,
+		keyFile:  config.keyFile,
+	}
+}
+
+// ServeHTTP Serves r with the routes of server, so that server can be mounted as http.Handler
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.httpServer.Handler.ServeHTTP(w, r)
+}
+
+// Start Listens on the address of server and serves HTTPS until Shutdown is called,
+// returning nil after Shutdown and an error if the server couldn't start or failed
+func (server *Server) Start() error {
+	listener, err := net.Listen("tcp", server.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	return server.Serve(listener)
+}
+
+// Serve Same as Start, serving HTTPS on listener instead of the address of server
+func (server *Server) Serve(listener net.Listener) error {
+	tlsConfig := server.httpServer.TLSConfig
+	if server.certFile == "" && (tlsConfig == nil || (len(tlsConfig.Certificates) == 0 && tlsConfig.GetCertificate == nil)) {
+		listener.Close()
+		return errors.New("server has no certificate, pass WithCertificate or WithTLSConfig")
+	}
+
+	err := server.httpServer.ServeTLS(listener, server.certFile, server.keyFile)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown Gracefully stops server: stops accepting connections and waits for requests in progress
+// to complete, until ctx is done
+func (server *Server) Shutdown(ctx context.Context) error {
+	return server.httpServer.Shutdown(ctx)
+}
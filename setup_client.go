@@ -10,7 +10,7 @@ import (
 )
 
 // SetupClient Sets up CkksParams on client side and creates an Evaluator using
-// newly set up CkksParams. Evaluation key is skipped for now
+// newly set up CkksParams. Use NewClientContext to hold several key sets
 func SetupClient(ckksParams ckks.Parameters, bfvParams bfv.Parameters, ckksEvalKey rlwe.EvaluationKey, bfvEvalKey rlwe.EvaluationKey) {
 	ckksMath.CkksParams = ckksParams
 	ckksMath.CkksEvalkey = ckksEvalKey
@@ -11,15 +11,28 @@ var BfvParams bfv.Parameters
 
 // SetupServer Loads secret and public keys from file or generates new keys
 // and saves them to file if such location doesn't exist.
-// Sets up CkksParams on server side
+// Sets up CkksParams on server side. Use NewServerContext to hold several key sets.
+// Secret keys are stored unencrypted, use SetupServerWithKeyStore to keep them encrypted
 func SetupServer(ckksKeysFileLocation string, bfvKeysFileLocation string) {
-	var err error
-	CkksParams, err = ckks.NewParametersFromLiteral(ckks.PN14QP438)
-	BfvParams, err = bfv.NewParametersFromLiteral(bfv.PN14QP438)
+	ctx, err := NewServerContext(ckksKeysFileLocation, bfvKeysFileLocation)
 	if err != nil {
 		panic(err)
 	}
-	LoadOrGenerateKeys(ckksKeysFileLocation, CKKS)
-	LoadOrGenerateKeys(bfvKeysFileLocation, BFV)
+	ctx.setGlobals()
+	log.Println("Server setup successful")
+}
+
+// SetupServerWithKeyStore Same as SetupServer, but keys are loaded from store
+// or generated and saved to store if it doesn't have them
+func SetupServerWithKeyStore(store KeyStore) {
+	config, err := DefaultContextConfig()
+	if err != nil {
+		panic(err)
+	}
+	ctx, err := NewServerContextWithKeyStore(config, store)
+	if err != nil {
+		panic(err)
+	}
+	ctx.setGlobals()
 	log.Println("Server setup successful")
 }
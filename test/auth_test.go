@@ -0,0 +1,179 @@
+package test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate creates a certificate of commonName signed by parent, or a self-signed
+// CA certificate if parent is nil, and writes it and its key into PEM files in dir
+func writeTestCertificate(t *testing.T, dir string, commonName string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, _ := x509.ParseCertificate(der)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+
+	certFile := filepath.Join(dir, commonName+".pem")
+	keyFile := filepath.Join(dir, commonName+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certificate, key, certFile, keyFile
+}
+
+func TestAuthentication(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	dir := t.TempDir()
+
+	paramsToken, _ := he.GenerateAPIToken()
+	fullToken, _ := he.GenerateAPIToken()
+	config := he.AuthConfig{Clients: []he.AuthClient{
+		{Name: "params", TokenHash: he.HashAPIToken(paramsToken), Permissions: []he.Permission{he.PermissionParams}},
+		{Name: "full", TokenHash: he.HashAPIToken(fullToken), Permissions: []he.Permission{he.PermissionParams, he.PermissionEvalKeys, he.PermissionDecrypt}},
+		{Name: "reports", CertificateCN: "reports", Permissions: []he.Permission{he.PermissionParams, he.PermissionDecrypt}},
+	}}
+	configFile := filepath.Join(dir, "auth.json")
+	data, _ := json.Marshal(config)
+	os.WriteFile(configFile, data, 0600)
+
+	loaded, err := he.LoadAuthConfig(configFile)
+	assert.NoError(err, "Error loading auth config")
+	auth, err := he.NewAuthenticator(loaded)
+	assert.NoError(err, "Error creating authenticator")
+
+	ca, caKey, caFile, _ := writeTestCertificate(t, dir, "ca", nil, nil)
+	_, _, reportsCert, reportsKey := writeTestCertificate(t, dir, "reports", ca, caKey)
+	_, _, strangerCert, strangerKey := writeTestCertificate(t, dir, "stranger", ca, caKey)
+
+	tlsConfig, err := he.MutualTLSConfig(caFile)
+	assert.NoError(err, "Error creating TLS config")
+
+	ts := httptest.NewUnstartedServer(he.NewRouter(server, he.WithAuthenticator(auth)))
+	ts.TLS = tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	httpsServer := he.HttpsServer
+	defer func() {
+		he.HttpsServer = httpsServer
+		he.APIToken = ""
+	}()
+
+	encrypted, _ := server.EncryptBFV(42)
+	request := func(method string, path string, token string) int {
+		data, _ := json.Marshal(map[string][]byte{"encrypted_result": encrypted})
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		ts.Config.Handler.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	routeTests := []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		expected int
+	}{
+		{"no token", http.MethodGet, "/get_bfv_params", "", http.StatusUnauthorized},
+		{"unknown token", http.MethodGet, "/get_bfv_params", "unknown", http.StatusUnauthorized},
+		{"params", http.MethodGet, "/get_ckks_params", paramsToken, http.StatusOK},
+		{"eval keys without permission", http.MethodGet, "/get_bfv_eval_keys", paramsToken, http.StatusForbidden},
+		{"decrypt without permission", http.MethodPost, "/decrypt_computations_bfv", paramsToken, http.StatusForbidden},
+		{"eval keys", http.MethodGet, "/get_bfv_eval_keys", fullToken, http.StatusOK},
+		{"decrypt", http.MethodPost, "/decrypt_computations_bfv", fullToken, http.StatusOK},
+	}
+
+	for _, currentTest := range routeTests {
+		t.Run("token "+currentTest.name, func(t *testing.T) {
+			assert.Equal(currentTest.expected, request(currentTest.method, currentTest.path, currentTest.token))
+		})
+	}
+
+	t.Run("client helpers with token", func(t *testing.T) {
+		he.HttpsServer = ts.Client()
+		he.APIToken = ""
+		_, err := he.GetBFVParamsFromServer(ts.URL + "/get_bfv_params")
+		assert.Error(err, "Didn't get expected error, client is not authenticated")
+
+		he.APIToken = fullToken
+		params, err := he.GetBFVParamsFromServer(ts.URL + "/get_bfv_params")
+		assert.NoError(err, "Authenticated client was rejected")
+		assert.Equal(server.BfvParams.N(), params.N())
+
+		decrypted, err := he.SendComputationResultToServerBfv(ts.URL+"/decrypt_computations_bfv", encrypted)
+		assert.NoError(err, "Authenticated client was rejected")
+		assert.Equal(int64(42), decrypted)
+	})
+
+	t.Run("client helpers with certificate", func(t *testing.T) {
+		he.APIToken = ""
+		he.HttpsServer = ts.Client()
+		assert.NoError(he.UseClientCertificate(reportsCert, reportsKey))
+
+		decrypted, err := he.SendComputationResultToServerBfv(ts.URL+"/decrypt_computations_bfv", encrypted)
+		assert.NoError(err, "Authenticated client was rejected")
+		assert.Equal(int64(42), decrypted)
+
+		_, err = he.GetBfvEvalKeysFromServer(ts.URL + "/get_bfv_eval_keys")
+		assert.Error(err, "Didn't get expected error, client has no eval keys permission")
+
+		he.HttpsServer = ts.Client()
+		assert.NoError(he.UseClientCertificate(strangerCert, strangerKey))
+		_, err = he.GetBFVParamsFromServer(ts.URL + "/get_bfv_params")
+		assert.Error(err, "Didn't get expected error, certificate belongs to no client")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := he.LoadAuthConfig(filepath.Join(dir, "missing.json"))
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = he.NewAuthenticator(he.AuthConfig{Clients: []he.AuthClient{{Name: "broken", TokenHash: "abc"}}})
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = he.MutualTLSConfig(configFile)
+		assert.Error(err, "Didn't get expected error")
+
+		assert.Error(he.UseClientCertificate(configFile, configFile), "Didn't get expected error")
+	})
+}
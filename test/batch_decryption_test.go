@@ -0,0 +1,135 @@
+package test
+
+import (
+	"context"
+	"errors"
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/SamBridgess/homomorphicEncryption/bfvMath"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBatchDecryption(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ts := httptest.NewTLSServer(he.NewRouter(server))
+	defer ts.Close()
+
+	httpsServer := he.HttpsServer
+	he.HttpsServer = ts.Client()
+	defer func() { he.HttpsServer = httpsServer }()
+
+	wrongInput := []byte{0x00, 0x00, 0x00}
+
+	t.Run("ckks", func(t *testing.T) {
+		values := []float64{1.5, -2.25, 1000.125}
+		var encrypted [][]byte
+		for _, value := range values {
+			data, _ := server.EncryptCKKS(value)
+			encrypted = append(encrypted, data)
+		}
+
+		decrypted, errs, err := newTestClient(t, ts, "").DecryptBatchCkks(context.Background(), encrypted)
+		assert.NoError(err, "Error decrypting batch")
+		for i, value := range values {
+			assert.NoError(errs[i], "Error decrypting batch item")
+			assert.InDelta(value, decrypted[i], 0.001, "Decrypted value is not equal to expected value")
+		}
+	})
+
+	t.Run("bfv", func(t *testing.T) {
+		values := []int64{3, 0, 42, 7}
+		var encrypted [][]byte
+		for _, value := range values {
+			data, _ := server.EncryptBFV(value)
+			encrypted = append(encrypted, data)
+		}
+
+		decrypted, errs, err := he.SendComputationResultsToServerBfv(ts.URL+"/decrypt_computations_bfv/batch", encrypted)
+		assert.NoError(err, "Error decrypting batch")
+		assert.Equal(values, decrypted, "Decrypted values are not equal to expected values")
+		assert.Equal(make([]error, len(values)), errs, "Error decrypting batch items")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		valid, _ := server.EncryptBFV(5)
+		decrypted, errs, err := he.SendComputationResultsToServerBfv(ts.URL+"/decrypt_computations_bfv/batch", [][]byte{valid, wrongInput})
+		assert.NoError(err, "Batch failed because of a single item")
+
+		assert.NoError(errs[0], "Error decrypting valid batch item")
+		assert.Equal(int64(5), decrypted[0], "Decrypted value is not equal to expected value")
+
+		var serverErr *he.ServerError
+		if assert.True(errors.As(errs[1], &serverErr), "Item error not returned as ServerError") {
+			assert.NotEmpty(serverErr.Message, "Error message of server not returned")
+		}
+	})
+
+	t.Run("empty batch", func(t *testing.T) {
+		decrypted, errs, err := he.SendComputationResultsToServerCkks(ts.URL+"/decrypt_computations_ckks/batch", nil)
+		assert.NoError(err, "Error decrypting empty batch")
+		assert.Empty(decrypted, "Empty batch returned results")
+		assert.Empty(errs, "Empty batch returned errors")
+	})
+}
+
+func TestBatchDecryptionLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ts := httptest.NewTLSServer(he.NewRouter(server, he.WithMaxBatchItems(2)))
+	defer ts.Close()
+
+	client := newTestClient(t, ts, "")
+	encrypted, _ := server.EncryptBFV(5)
+
+	decrypted, _, err := client.DecryptBatchBfv(context.Background(), [][]byte{encrypted, encrypted})
+	assert.NoError(err, "Error decrypting batch within the limit")
+	assert.Equal([]int64{5, 5}, decrypted, "Decrypted values are not equal to expected values")
+
+	_, _, err = client.DecryptBatchBfv(context.Background(), [][]byte{encrypted, encrypted, encrypted})
+	var serverErr *he.ServerError
+	if assert.True(errors.As(err, &serverErr), "Batch above the limit not rejected") {
+		assert.Equal(http.StatusRequestEntityTooLarge, serverErr.StatusCode, "Unexpected status code")
+	}
+}
+
+func TestBatchDecryptionPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	policy := he.NewDecryptionPolicy()
+	policy.ApproveBfvAggregate("ArraySum", 2, (*bfvMath.Context).ArraySum)
+
+	ts := httptest.NewTLSServer(he.NewRouter(server, he.WithDecryptionPolicy(policy)))
+	defer ts.Close()
+
+	rows := make([][]byte, 3)
+	for i := range rows {
+		rows[i], _ = server.EncryptBFV(int64(i + 1))
+	}
+	policy.RegisterInputs(rows...)
+
+	client := he.NewClientContext(server.CkksParams, server.BfvParams, server.EvalKeysCkks.EvalKey1, server.EvalKeysBfv.EvalKey1)
+	sum, err := client.Bfv.ArraySum(rows)
+	assert.NoError(err, "Error performing operation")
+
+	results := [][]byte{sum, rows[0]}
+	transcripts := []*he.ComputationTranscript{{Operation: "ArraySum", Inputs: rows}, nil}
+
+	decrypted, errs, err := newTestClient(t, ts, "").DecryptVerifiableBatchBfv(context.Background(), results, transcripts)
+	assert.NoError(err, "Error decrypting batch")
+	assert.NoError(errs[0], "Approved result was not decrypted")
+	assert.Equal(int64(6), decrypted[0], "Decrypted value is not equal to expected value")
+
+	var serverErr *he.ServerError
+	if assert.True(errors.As(errs[1], &serverErr), "Item error not returned as ServerError") {
+		assert.Equal(http.StatusForbidden, serverErr.StatusCode, "Stored row was decrypted")
+	}
+
+	_, _, err = newTestClient(t, ts, "").DecryptVerifiableBatchBfv(context.Background(), results, transcripts[:1])
+	assert.Error(err, "Batch with missing transcripts sent")
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBfvCiphertext(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ctx := server.Bfv
+
+	t.Run("round trip", func(t *testing.T) {
+		encrypted, _ := server.EncryptBFV(42)
+
+		ciphertext, err := ctx.Unmarshal(encrypted)
+		assert.NoError(err, "Error unmarshalling ciphertext")
+		assert.Equal(server.KeyID, ciphertext.KeyID, "Key ID is not preserved")
+
+		marshalled, err := ciphertext.Marshal()
+		assert.NoError(err, "Error marshalling ciphertext")
+
+		decrypted, err := server.DecryptBFV(marshalled)
+		assert.NoError(err, "Error decrypting data")
+		assert.Equal(int64(42), decrypted, "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		values := []int64{1, 2, 3, 4}
+		encryptedArray := make([][]byte, len(values))
+		for i, value := range values {
+			encryptedArray[i], _ = server.EncryptBFV(value)
+		}
+
+		array, err := ctx.UnmarshalArray(encryptedArray)
+		assert.NoError(err, "Error unmarshalling array")
+
+		sum, err := array.Sum()
+		assert.NoError(err, "Error performing operation")
+		square, err := sum.Mult(sum)
+		assert.NoError(err, "Error performing operation")
+		square, err = square.Relinearize()
+		assert.NoError(err, "Error relinearizing ciphertext")
+		result, err := square.Subtract(array[0])
+		assert.NoError(err, "Error performing operation")
+		result, err = result.MultByPositiveConst(3)
+		assert.NoError(err, "Error performing operation")
+
+		marshalled, err := result.Marshal()
+		assert.NoError(err, "Error marshalling ciphertext")
+
+		decrypted, _ := server.DecryptBFV(marshalled)
+		assert.Equal(int64(297), decrypted, "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		_, err := ctx.Unmarshal(wrongInput)
+		assert.Error(err, "Didn't get expected error")
+
+		encrypted, _ := server.EncryptCKKS(1.0)
+		_, err = ctx.Unmarshal(encrypted)
+		var schemeErr *envelope.SchemeMismatchError
+		assert.ErrorAs(err, &schemeErr, "CKKS data accepted as BFV ciphertext")
+
+		array, _ := ctx.UnmarshalArray([][]byte{})
+		_, err = array.Sum()
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("key mismatch", func(t *testing.T) {
+		encrypted, _ := server.EncryptBFV(1)
+		ciphertext1, _ := ctx.Unmarshal(encrypted)
+		ciphertext2, _ := ctx.Unmarshal(encrypted)
+		ciphertext2.KeyID = "other"
+
+		_, err := ciphertext1.Mult(ciphertext2)
+		var keyErr *envelope.KeyMismatchError
+		assert.ErrorAs(err, &keyErr, "Ciphertexts of different keys combined")
+	})
+}
@@ -63,3 +63,29 @@ func TestBfvEncDec(t *testing.T) {
 		assert.Error(err, "Didn't get expected error")
 	})
 }
+
+func TestBfvVectorEncDec(t *testing.T) {
+	assert := assert.New(t)
+
+	input := []int64{1, -2, 100, 0, 12345}
+
+	encrypted, err := he.EncryptBFVVector(input)
+	assert.NoError(err, "Error encrypting original data")
+
+	decrypted, err := he.DecryptBFVVector(encrypted)
+	assert.NoError(err, "Error decrypting original data")
+	assert.Len(decrypted, he.BfvSlots(), "Decrypted vector must contain all slots")
+
+	assert.Equal(input, decrypted[:len(input)], "Decrypted values are not equal to expected values")
+	assert.Equal(int64(0), decrypted[len(input)], "Unused slot is not zero")
+
+	t.Run("too many values", func(t *testing.T) {
+		_, err := he.EncryptBFVVector(make([]int64, he.BfvSlots()+1))
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := he.DecryptBFVVector([]byte{0x00, 0x00, 0x00})
+		assert.Error(err, "Didn't get expected error")
+	})
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/SamBridgess/homomorphicEncryption/bfvMath"
+	"github.com/ldsec/lattigo/v2/bfv"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+// newTestServerContextForEquality creates a server Context with BFV parameters deep enough
+// for equality tests and without rotation keys, which are large for such parameters
+func newTestServerContextForEquality(t *testing.T) *he.Context {
+	config, err := he.DefaultContextConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.BfvParams, err = bfv.NewParametersFromLiteral(bfv.PN15QP880)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.RotationSlots = 1
+
+	dir := t.TempDir()
+	ctx, err := he.NewServerContextWithConfig(config, filepath.Join(dir, "ckksKeys.json"), filepath.Join(dir, "bfvKeys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+func TestBfvEquality(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContextForEquality(t)
+	ctx := server.Bfv
+
+	t.Run("is equal", func(t *testing.T) {
+		encrypted, _ := server.EncryptBFVVector([]int64{0, 7, -3, 42, 1, -32768})
+		encrypted2, _ := server.EncryptBFVVector([]int64{0, 7, 3, 41, -65536, 32768})
+
+		operationResultBytes, err := ctx.IsEqual(encrypted, encrypted2)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptBFVVector(operationResultBytes)
+		// -65536 is 1 modulo the plaintext modulus, -32768 and 32768 are not congruent
+		assert.Equal([]int64{1, 1, 0, 0, 1, 0}, decrypted[:6], "Decrypted values are not equal to expected values")
+	})
+
+	t.Run("contains", func(t *testing.T) {
+		// every slot of x is looked up in the same slot of set elements
+		set := make([][]byte, 3)
+		for i, id := range []int64{1001, 2002, -3003} {
+			set[i], _ = server.EncryptBFVVector([]int64{id, id, id, id})
+		}
+		encrypted, _ := server.EncryptBFVVector([]int64{2002, 1002, -3003, 0})
+
+		operationResultBytes, err := ctx.Contains(set, encrypted)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptBFVVector(operationResultBytes)
+		assert.Equal([]int64{1, 0, 1, 0}, decrypted[:4], "Decrypted values are not equal to expected values")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		encrypted, _ := server.EncryptBFV(1)
+
+		_, err := bfvMath.IsEqual(wrongInput, wrongInput)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.IsEqual(encrypted, wrongInput)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.Contains([][]byte{}, encrypted)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.Contains([][]byte{wrongInput}, encrypted)
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("default parameters", func(t *testing.T) {
+		defaultServer := newTestServerContext(t)
+		encrypted, _ := defaultServer.EncryptBFV(1)
+
+		_, err := defaultServer.Bfv.IsEqual(encrypted, encrypted)
+		assert.ErrorContains(err, "equality test needs multiplicative depth 16, but parameters support only 8", "Didn't get expected error")
+
+		_, err = defaultServer.Bfv.Contains([][]byte{encrypted, encrypted}, encrypted)
+		assert.ErrorContains(err, "equality test needs multiplicative depth 16", "Didn't get expected error")
+	})
+}
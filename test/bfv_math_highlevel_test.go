@@ -3,8 +3,10 @@ package test
 import (
 	he "github.com/SamBridgess/homomorphicEncryption"
 	"github.com/SamBridgess/homomorphicEncryption/bfvMath"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -51,3 +53,77 @@ func TestBfvArraySum(t *testing.T) {
 		assert.Error(err, "Didn't get expected error")
 	})
 }
+
+func TestBfvHistogram(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContextForEquality(t)
+	ctx := server.Bfv
+	wrongInput := []byte{0x00, 0x00, 0x00}
+
+	// two categorical columns, one per slot
+	rows := [][]int64{{1, 3}, {1, -1}}
+	encryptedArray := make([][]byte, len(rows))
+	for i, row := range rows {
+		encryptedArray[i], _ = server.EncryptBFVVector(row)
+	}
+
+	t.Run("count if", func(t *testing.T) {
+		operationResultBytes, err := ctx.CountIf(encryptedArray, -1)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptBFVVector(operationResultBytes)
+		assert.Equal([]int64{0, 1}, decrypted[:2], "Decrypted values are not equal to expected values")
+	})
+
+	t.Run("histogram", func(t *testing.T) {
+		operationResultBytes, err := ctx.Histogram(encryptedArray, []int64{1, 3})
+		assert.NoError(err, "Error performing operation")
+		assert.Len(operationResultBytes, 2)
+
+		expected := [][]int64{{2, 0}, {0, 1}}
+		for i, counts := range operationResultBytes {
+			decrypted, _ := server.DecryptBFVVector(counts)
+			assert.Equal(expected[i], decrypted[:2], "Decrypted values are not equal to expected values")
+		}
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := bfvMath.CountIf([][]byte{wrongInput}, 1)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.CountIf([][]byte{}, 1)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.Histogram(encryptedArray, []int64{})
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.Histogram([][]byte{wrongInput}, []int64{1})
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("default parameters", func(t *testing.T) {
+		defaultServer := newTestServerContext(t)
+		encrypted, _ := defaultServer.EncryptBFV(1)
+
+		_, err := defaultServer.Bfv.CountIf([][]byte{encrypted, encrypted}, 1)
+		assert.ErrorContains(err, "equality test needs multiplicative depth 16, but parameters support only 8", "Didn't get expected error")
+
+		_, err = defaultServer.Bfv.Histogram([][]byte{encrypted, encrypted}, []int64{0, 1})
+		assert.ErrorContains(err, "equality test needs multiplicative depth 16", "Didn't get expected error")
+	})
+
+	t.Run("key mismatch", func(t *testing.T) {
+		array, _ := ctx.UnmarshalArray(encryptedArray)
+		array[1].KeyID = "other"
+
+		// keys are checked before any equality test is evaluated
+		start := time.Now()
+		var keyErr *envelope.KeyMismatchError
+		_, err := array.CountIf(1)
+		assert.ErrorAs(err, &keyErr, "Ciphertexts of different keys counted")
+		_, err = array.Histogram([]int64{1, 3})
+		assert.ErrorAs(err, &keyErr, "Ciphertexts of different keys counted")
+		assert.Less(time.Since(start), time.Second, "Equality tests evaluated before checking keys")
+	})
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"github.com/SamBridgess/homomorphicEncryption/bfvMath"
+	"github.com/ldsec/lattigo/v2/rlwe"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testBfvPow struct {
+	name     string
+	value    int64
+	n        uint64
+	expected int64
+}
+
+type testBfvPolynomial struct {
+	name     string
+	coeffs   []int64
+	value    int64
+	expected int64
+}
+
+func TestBfvPolynomial(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ctx := server.Bfv
+
+	t.Run("array product", func(t *testing.T) {
+		for _, values := range [][]int64{{7}, {-2, 5}, {2, 3, 4}, {1, 2, 3, 4, 5}} {
+			expected := int64(1)
+			encryptedArray := make([][]byte, len(values))
+			for i, value := range values {
+				expected *= value
+				encryptedArray[i], _ = server.EncryptBFV(value)
+			}
+
+			operationResultBytes, err := ctx.ArrayProduct(encryptedArray)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := server.DecryptBFV(operationResultBytes)
+			assert.Equal(expected, decrypted, "Decrypted value is not equal to expected value")
+		}
+	})
+
+	powTests := []testBfvPow{
+		{"zero power", 3, 0, 1},
+		{"first power", 3, 1, 3},
+		{"odd power of negative", -2, 5, -32},
+		{"not power of two", 3, 7, 2187},
+		{"wraparound", 2, 16, -1},
+		{"depth 8", 5, 256, 58102 - 65537},
+	}
+
+	for _, currentTest := range powTests {
+		t.Run("pow "+currentTest.name, func(t *testing.T) {
+			encrypted, _ := server.EncryptBFV(currentTest.value)
+
+			operationResultBytes, err := ctx.Pow(encrypted, currentTest.n)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := server.DecryptBFV(operationResultBytes)
+			assert.Equal(currentTest.expected, decrypted, "Decrypted value is not equal to expected value")
+		})
+	}
+
+	polynomialTests := []testBfvPolynomial{
+		{"constant", []int64{5}, 3, 5},
+		{"cubic", []int64{1, -2, 0, 1}, 3, 22},
+		{"trailing zeros", []int64{2, 1, 0, 0}, 4, 6},
+		{"degree 7", []int64{0, 0, 0, 0, 0, 0, 0, 1}, -2, -128},
+	}
+
+	for _, currentTest := range polynomialTests {
+		t.Run("polynomial "+currentTest.name, func(t *testing.T) {
+			encrypted, _ := server.EncryptBFV(currentTest.value)
+
+			operationResultBytes, err := ctx.EvaluatePolynomial(encrypted, currentTest.coeffs)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := server.DecryptBFV(operationResultBytes)
+			assert.Equal(currentTest.expected, decrypted, "Decrypted value is not equal to expected value")
+		})
+	}
+
+	t.Run("polynomial of every slot", func(t *testing.T) {
+		encrypted, _ := server.EncryptBFVVector([]int64{0, 1, 2, -3})
+
+		operationResultBytes, err := ctx.EvaluatePolynomial(encrypted, []int64{1, 0, 2})
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptBFVVector(operationResultBytes)
+		assert.Equal([]int64{1, 3, 9, 19}, decrypted[:4], "Decrypted values are not equal to expected values")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		_, err := bfvMath.Pow(wrongInput, 2)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.ArrayProduct([][]byte{})
+		assert.Error(err, "Didn't get expected error")
+
+		encrypted, _ := server.EncryptBFV(2)
+		_, err = ctx.EvaluatePolynomial(encrypted, []int64{})
+		assert.Error(err, "Didn't get expected error")
+
+		noKeys := bfvMath.NewContext(server.BfvParams, rlwe.EvaluationKey{})
+		_, err = noKeys.Pow(encrypted, 2)
+		assert.Error(err, "Didn't get expected error, relinearization key is missing")
+	})
+
+	t.Run("depth above budget", func(t *testing.T) {
+		assert.Equal(8, ctx.MaxDepth(), "Unexpected depth of default parameters")
+
+		encrypted, _ := server.EncryptBFV(2)
+		_, err := ctx.Pow(encrypted, 257)
+		assert.ErrorContains(err, "needs multiplicative depth 9", "Didn't get expected error")
+
+		_, err = ctx.EvaluatePolynomial(encrypted, make([]int64, 258))
+		assert.NoError(err, "Zero leading coefficients don't add depth")
+		coeffs := make([]int64, 258)
+		coeffs[257] = 1
+		_, err = ctx.EvaluatePolynomial(encrypted, coeffs)
+		assert.ErrorContains(err, "needs multiplicative depth 9", "Didn't get expected error")
+
+		array := make([][]byte, 257)
+		for i := range array {
+			array[i] = encrypted
+		}
+		_, err = ctx.ArrayProduct(array)
+		assert.ErrorContains(err, "needs multiplicative depth 9", "Didn't get expected error")
+	})
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testBfvSlotMath struct {
+	name     string
+	value1   []int64
+	value2   []int64
+	n        int
+	expected int64
+}
+
+func TestBfvSlotOperations(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContextWithRotations(t, 16)
+	ctx := server.Bfv
+
+	sumTests := []testBfvSlotMath{
+		{"power of two", []int64{1, 2, 3, 4}, nil, 4, 10},
+		{"not power of two", []int64{1, 2, 3, 4, 5, 6, 7}, nil, 7, 28},
+		{"ignores tail", []int64{1, 2, 3, 4, 5, 6, 7}, nil, 5, 15},
+		{"negative", []int64{-1, -2, -3}, nil, 3, -6},
+	}
+	for _, currentTest := range sumTests {
+		t.Run("sum "+currentTest.name, func(t *testing.T) {
+			encrypted, _ := server.EncryptBFVVector(currentTest.value1)
+
+			operationResultBytes, err := ctx.SlotSum(encrypted, currentTest.n)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := server.DecryptBFV(operationResultBytes)
+			assert.Equal(currentTest.expected, decrypted, "Decrypted value is not equal to expected value")
+		})
+	}
+
+	t.Run("inner product", func(t *testing.T) {
+		encrypted1, _ := server.EncryptBFVVector([]int64{1, 2, 3})
+		encrypted2, _ := server.EncryptBFVVector([]int64{4, -5, 6})
+
+		operationResultBytes, err := ctx.InnerProduct(encrypted1, encrypted2, 3)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptBFV(operationResultBytes)
+		assert.Equal(int64(12), decrypted, "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("mult by vector", func(t *testing.T) {
+		encrypted, _ := server.EncryptBFVVector([]int64{1, 2, 3, 4})
+
+		operationResultBytes, err := ctx.MultByVector(encrypted, []int64{5, -1, 0})
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptBFVVector(operationResultBytes)
+		assert.Equal([]int64{5, -2, 0, 0}, decrypted[:4], "Decrypted values are not equal to expected values")
+
+		_, err = ctx.MultByVector(encrypted, make([]int64, server.BfvSlots()+1))
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("both rows", func(t *testing.T) {
+		full := newTestServerContextWithRotations(t, he.RotationSlotsAll)
+
+		values := make([]int64, full.BfvSlots())
+		for i := range values {
+			values[i] = 1
+		}
+		encrypted, _ := full.EncryptBFVVector(values)
+
+		operationResultBytes, err := full.Bfv.SlotSum(encrypted, full.BfvSlots()/2+3)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := full.DecryptBFV(operationResultBytes)
+		assert.Equal(int64(full.BfvSlots()/2+3), decrypted, "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		encrypted, _ := server.EncryptBFVVector([]int64{1, 2, 3})
+
+		_, err := ctx.SlotSum([]byte{0x00, 0x00, 0x00}, 2)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.SlotSum(encrypted, 0)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.SlotSum(encrypted, 17)
+		assert.Error(err, "Didn't get expected error, rotation key is missing")
+	})
+}
@@ -4,6 +4,7 @@ import (
 	he "github.com/SamBridgess/homomorphicEncryption"
 	"github.com/SamBridgess/homomorphicEncryption/bfvMath"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 )
 
@@ -56,6 +57,150 @@ func TestBfvMultByPositiveConst(t *testing.T) {
 	})
 }
 
+func TestBfvAddConst(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []testBfvMath{
+		{"positive", 2, 3, 5},
+		{"negative", 2, -5, -3},
+		{"multiple of modulus", 2, 65537 * 3, 2},
+		{"wraparound", 32768, 10, -32759},
+	}
+	wrongInput := []byte{0x00, 0x00, 0x00}
+
+	for _, currentTest := range tests {
+		t.Run(currentTest.name, func(t *testing.T) {
+			encrypted1, _ := he.EncryptBFV(currentTest.value1)
+
+			operationResultBytes, err := bfvMath.AddConst(encrypted1, currentTest.value2)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := he.DecryptBFV(operationResultBytes)
+
+			assert.Equal(currentTest.expected, decrypted, "Decrypted value is not equal to expected value")
+		})
+	}
+
+	t.Run("every slot", func(t *testing.T) {
+		encrypted, _ := he.EncryptBFVVector([]int64{1, 2, 3})
+
+		operationResultBytes, err := bfvMath.AddConst(encrypted, 10)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := he.DecryptBFVVector(operationResultBytes)
+		assert.Equal([]int64{11, 12, 13, 10}, decrypted[:4], "Decrypted values are not equal to expected values")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := bfvMath.AddConst(wrongInput, 1)
+		assert.Error(err, "Didn't get expected error")
+	})
+}
+
+func TestBfvSubtractConst(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []testBfvMath{
+		{"positive", 2, 3, -1},
+		{"negative", -2, -4, 2},
+		{"min int64", 5, math.MinInt64, -32763},
+	}
+	wrongInput := []byte{0x00, 0x00, 0x00}
+
+	for _, currentTest := range tests {
+		t.Run(currentTest.name, func(t *testing.T) {
+			encrypted1, _ := he.EncryptBFV(currentTest.value1)
+
+			operationResultBytes, err := bfvMath.SubtractConst(encrypted1, currentTest.value2)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := he.DecryptBFV(operationResultBytes)
+
+			assert.Equal(currentTest.expected, decrypted, "Decrypted value is not equal to expected value")
+		})
+	}
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := bfvMath.SubtractConst(wrongInput, 1)
+		assert.Error(err, "Didn't get expected error")
+	})
+}
+
+func TestBfvNegate(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []testBfvMath{
+		{"positive", 5, 0, -5},
+		{"negative", -7, 0, 7},
+		{"zero", 0, 0, 0},
+	}
+	wrongInput := []byte{0x00, 0x00, 0x00}
+
+	for _, currentTest := range tests {
+		t.Run(currentTest.name, func(t *testing.T) {
+			encrypted1, _ := he.EncryptBFV(currentTest.value1)
+
+			operationResultBytes, err := bfvMath.Negate(encrypted1)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := he.DecryptBFV(operationResultBytes)
+
+			assert.Equal(currentTest.expected, decrypted, "Decrypted value is not equal to expected value")
+		})
+	}
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := bfvMath.Negate(wrongInput)
+		assert.Error(err, "Didn't get expected error")
+	})
+}
+
+func TestBfvMultByConst(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []testBfvMath{
+		{"positive", 2, 100, 200},
+		{"negative", 7, -3, -21},
+		{"both negative", -4, -5, 20},
+		{"zero", 2, 0, 0},
+		{"wraparound", 300, 300, 24463},
+	}
+	wrongInput := []byte{0x00, 0x00, 0x00}
+
+	for _, currentTest := range tests {
+		t.Run(currentTest.name, func(t *testing.T) {
+			encrypted1, _ := he.EncryptBFV(currentTest.value1)
+
+			operationResultBytes, err := bfvMath.MultByConst(encrypted1, currentTest.value2)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := he.DecryptBFV(operationResultBytes)
+
+			assert.Equal(currentTest.expected, decrypted, "Decrypted value is not equal to expected value")
+		})
+	}
+
+	t.Run("chained negative", func(t *testing.T) {
+		// every multiplication by t - 1 would grow noise by a factor of t
+		operationResultBytes, _ := he.EncryptBFV(7)
+		expected := int64(7)
+		for _, value := range []int64{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -3, -3, -3, -3, -3, -3, -3, -3, -3, -3} {
+			var err error
+			operationResultBytes, err = bfvMath.MultByConst(operationResultBytes, value)
+			assert.NoError(err, "Error performing operation")
+			expected = expected * value % 65537
+		}
+
+		decrypted, _ := he.DecryptBFV(operationResultBytes)
+		assert.Equal(expected, decrypted, "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := bfvMath.MultByConst(wrongInput, 1)
+		assert.Error(err, "Didn't get expected error")
+	})
+}
+
 func TestBfvSum(t *testing.T) {
 	assert := assert.New(t)
 
@@ -0,0 +1,191 @@
+package test
+
+import (
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestCkksCiphertext(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ctx := server.Ckks
+
+	t.Run("round trip", func(t *testing.T) {
+		encrypted, _ := server.EncryptCKKS(4.25)
+
+		ciphertext, err := ctx.Unmarshal(encrypted)
+		assert.NoError(err, "Error unmarshalling ciphertext")
+		assert.Equal(server.KeyID, ciphertext.KeyID, "Key ID is not preserved")
+
+		marshalled, err := ciphertext.Marshal()
+		assert.NoError(err, "Error marshalling ciphertext")
+
+		decrypted, err := server.DecryptCKKS(marshalled)
+		assert.NoError(err, "Error decrypting data")
+		assert.InDelta(4.25, decrypted, 1e-3, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		values := []float64{1, 2, 3, 4, 5}
+		encryptedArray := make([][]byte, len(values))
+		for i, value := range values {
+			encryptedArray[i], _ = server.EncryptCKKS(value)
+		}
+
+		array, err := ctx.UnmarshalArray(encryptedArray)
+		assert.NoError(err, "Error unmarshalling array")
+
+		variance, err := array.Variance()
+		assert.NoError(err, "Error performing operation")
+		result, err := variance.AddConst(1)
+		assert.NoError(err, "Error performing operation")
+		result, err = result.MultByConst(2)
+		assert.NoError(err, "Error performing operation")
+
+		marshalled, err := result.Marshal()
+		assert.NoError(err, "Error marshalling ciphertext")
+
+		decrypted, _ := server.DecryptCKKS(marshalled)
+		assert.InDelta(6.0, decrypted, 1e-2, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("relinearize", func(t *testing.T) {
+		encrypted1, _ := server.EncryptCKKS(1.5)
+		encrypted2, _ := server.EncryptCKKS(-2.0)
+
+		lazy := ctx.ShallowCopy()
+		lazy.LazyRelinearization = true
+		ciphertext1, _ := lazy.Unmarshal(encrypted1)
+		ciphertext2, _ := lazy.Unmarshal(encrypted2)
+
+		product, err := ciphertext1.Mult(ciphertext2)
+		assert.NoError(err, "Error performing operation")
+		assert.Equal(2, product.Ciphertext.Degree(), "Product is not of degree 2")
+
+		relinearized, err := product.Relinearize()
+		assert.NoError(err, "Error relinearizing ciphertext")
+		assert.Equal(1, relinearized.Ciphertext.Degree(), "Relinearized product is not of degree 1")
+
+		marshalled, _ := relinearized.Marshal()
+		decrypted, _ := server.DecryptCKKS(marshalled)
+		assert.InDelta(-3.0, decrypted, 1e-2, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		_, err := ctx.Unmarshal(wrongInput)
+		assert.Error(err, "Didn't get expected error")
+
+		encrypted, _ := server.EncryptBFV(1)
+		_, err = ctx.Unmarshal(encrypted)
+		var schemeErr *envelope.SchemeMismatchError
+		assert.ErrorAs(err, &schemeErr, "BFV data accepted as CKKS ciphertext")
+
+		array, _ := ctx.UnmarshalArray([][]byte{})
+		_, err = array.Sum()
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("key mismatch", func(t *testing.T) {
+		encrypted, _ := server.EncryptCKKS(1.0)
+		ciphertext1, _ := ctx.Unmarshal(encrypted)
+		ciphertext2, _ := ctx.Unmarshal(encrypted)
+		ciphertext2.KeyID = "other"
+
+		_, err := ciphertext1.Sum(ciphertext2)
+		var keyErr *envelope.KeyMismatchError
+		assert.ErrorAs(err, &keyErr, "Ciphertexts of different keys combined")
+	})
+}
+
+func TestCkksMultChain(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ctx := server.Ckks
+
+	encrypted, _ := server.EncryptCKKS(1.1)
+	ciphertext, _ := ctx.Unmarshal(encrypted)
+	maxLevel := ciphertext.Level()
+
+	t.Run("rescales every multiplication", func(t *testing.T) {
+		result := ciphertext
+		expected := 1.1
+		for i := 1; i <= 5; i++ {
+			var err error
+			result, err = result.Mult(ciphertext)
+			assert.NoError(err, "Error performing operation")
+			expected *= 1.1
+
+			assert.Equal(1, result.Ciphertext.Degree(), "Product is not relinearized")
+			assert.Equal(maxLevel-i, result.Level(), "Product is not rescaled")
+			assert.InDelta(server.CkksParams.DefaultScale(), result.Scale(), server.CkksParams.DefaultScale()/1e3, "Scale is not kept close to the default scale")
+		}
+
+		marshalled, _ := result.Marshal()
+		decrypted, _ := server.DecryptCKKS(marshalled)
+		assert.InDelta(expected, decrypted, 1e-3, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("sum of different levels", func(t *testing.T) {
+		square, _ := ciphertext.Pow2()
+		sum, err := square.Sum(ciphertext)
+		assert.NoError(err, "Error performing operation")
+
+		assert.Equal(square.Level(), sum.Level(), "Sum lost a level aligning operands")
+
+		// compared to decrypted operands, since fresh encryption noise alone is close to 1e-4
+		marshalled, _ := square.Marshal()
+		decryptedSquare, _ := server.DecryptCKKS(marshalled)
+		decrypted, _ := server.DecryptCKKS(encrypted)
+		marshalled, _ = sum.Marshal()
+		decryptedSum, _ := server.DecryptCKKS(marshalled)
+		assert.InDelta(decryptedSquare+decrypted, decryptedSum, 1e-4, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		result := encrypted
+		for i := 0; i < 4; i++ {
+			var err error
+			result, err = ctx.Pow2(result)
+			assert.NoError(err, "Error performing operation")
+		}
+
+		decrypted, _ := server.DecryptCKKS(result)
+		assert.InDelta(math.Pow(1.1, 16), decrypted, 1e-2, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("lazy relinearization", func(t *testing.T) {
+		lazy := ctx.ShallowCopy()
+		lazy.LazyRelinearization = true
+		lazyCiphertext, _ := lazy.Unmarshal(encrypted)
+
+		product, err := lazyCiphertext.Mult(lazyCiphertext)
+		assert.NoError(err, "Error performing operation")
+		assert.Equal(2, product.Ciphertext.Degree(), "Product is relinearized")
+		assert.Equal(maxLevel-1, product.Level(), "Product is not rescaled")
+
+		sum, err := ckksMath.CKKSCiphertextArray{product, product}.Sum()
+		assert.NoError(err, "Error performing operation")
+		sum, err = sum.Relinearize()
+		assert.NoError(err, "Error relinearizing ciphertext")
+		assert.Equal(1, sum.Ciphertext.Degree(), "Sum is not relinearized")
+
+		marshalled, _ := sum.Marshal()
+		decrypted, _ := server.DecryptCKKS(marshalled)
+		assert.InDelta(2*1.1*1.1, decrypted, 1e-3, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("no levels left", func(t *testing.T) {
+		result := ciphertext
+		var err error
+		for i := 0; i <= maxLevel && err == nil; i++ {
+			result, err = result.Mult(ciphertext)
+		}
+		assert.Error(err, "Didn't get expected error")
+	})
+}
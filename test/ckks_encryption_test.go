@@ -47,3 +47,31 @@ func TestCkksEncDec(t *testing.T) {
 		assert.Error(err, "Didn't get expected error")
 	})
 }
+
+func TestCkksVectorEncDec(t *testing.T) {
+	assert := assert.New(t)
+
+	input := []float64{1.0, -2.5, 100.0, 0.0, 12345.678}
+
+	encrypted, err := he.EncryptCKKSVector(input)
+	assert.NoError(err, "Error encrypting original data")
+
+	decrypted, err := he.DecryptCKKSVector(encrypted)
+	assert.NoError(err, "Error decrypting original data")
+	assert.Len(decrypted, he.CkksSlots(), "Decrypted vector must contain all slots")
+
+	for i, expected := range input {
+		assert.InDelta(expected, decrypted[i], 1e-3, "Decrypted value is not within the allowed delta")
+	}
+	assert.InDelta(0.0, decrypted[len(input)], 1e-3, "Unused slot is not zero")
+
+	t.Run("too many values", func(t *testing.T) {
+		_, err := he.EncryptCKKSVector(make([]float64, he.CkksSlots()+1))
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := he.DecryptCKKSVector([]byte{0x00, 0x00, 0x00})
+		assert.Error(err, "Didn't get expected error")
+	})
+}
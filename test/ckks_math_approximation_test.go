@@ -0,0 +1,115 @@
+package test
+
+import (
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+type testCkksApproximation struct {
+	name      string
+	operation ckksMath.IntervalOperation
+	function  func(float64) float64
+	a         float64
+	b         float64
+	values    []float64
+	// delta allowed error, 2 * ckksMath.DefaultMaxApproximationError if zero
+	delta float64
+}
+
+func TestCkksApproximations(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ctx := server.Ckks
+
+	tests := []testCkksApproximation{
+		{"sigmoid", ctx.Sigmoid, func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }, -8, 8, []float64{-8, -1.5, 0, 2, 7.9}, 0},
+		{"exp", ctx.Exp, math.Exp, -4, 4, []float64{-4, -0.5, 0, 1, 3.5}, 0},
+		{"log", ctx.Log, math.Log, 0.1, 10, []float64{0.1, 0.5, 1, 4, 10}, 0},
+		{"sqrt", ctx.Sqrt, math.Sqrt, 0.1, 100, []float64{0.25, 2, 16, 99}, 0},
+		{"inverse square root", ctx.InvSqrt, func(x float64) float64 { return 1 / math.Sqrt(x) }, 0.1, 10, []float64{0.1, 1, 4, 9}, 0},
+		{"inverse", ctx.Inverse, func(x float64) float64 { return 1 / x }, 0.1, 10, []float64{0.1, 0.5, 2, 8}, 5e-3},
+		{"negative inverse", ctx.Inverse, func(x float64) float64 { return 1 / x }, -10, -0.5, []float64{-10, -2, -0.5}, 0},
+	}
+
+	for _, currentTest := range tests {
+		t.Run(currentTest.name, func(t *testing.T) {
+			encrypted, _ := server.EncryptCKKSVector(currentTest.values)
+
+			operationResultBytes, err := currentTest.operation(encrypted, currentTest.a, currentTest.b)
+			assert.NoError(err, "Error performing operation")
+
+			delta := currentTest.delta
+			if delta == 0 {
+				delta = 2 * ckksMath.DefaultMaxApproximationError
+			}
+
+			decrypted, _ := server.DecryptCKKSVector(operationResultBytes)
+			for i, value := range currentTest.values {
+				assert.InDelta(currentTest.function(value), decrypted[i], delta, "Decrypted value is not within the allowed delta")
+			}
+		})
+	}
+
+	t.Run("documented error", func(t *testing.T) {
+		approximation, err := ckksMath.LogApproximation(0.1, 10, 1e-3)
+		assert.NoError(err, "Error approximating function")
+		assert.LessOrEqual(approximation.MaxError, 1e-3, "Approximation error is above the requested one")
+		for _, x := range []float64{0.1, 0.3, 1, 5, 10} {
+			assert.InDelta(math.Log(x), approximation.Polynomial.Evaluate(x), approximation.MaxError, "Approximation error is above the reported one")
+		}
+
+		approximation, err = ckksMath.InverseApproximation(0.1, 10, 1e-3)
+		assert.NoError(err, "Error approximating function")
+		assert.InDelta(10, approximation.Polynomial.Evaluate(0.1), 1e-3, "Approximation error is above the documented one")
+
+		approximation, err = ckksMath.ExpApproximation(-1, 1, 1e-2)
+		assert.NoError(err, "Error approximating function")
+		assert.Equal(3, approximation.Polynomial.Degree(), "Approximation is not of the lowest sufficient degree")
+	})
+
+	t.Run("levels left", func(t *testing.T) {
+		encrypted, _ := server.EncryptCKKSVector([]float64{-0.5, 0.5})
+		ciphertext, _ := ctx.Unmarshal(encrypted)
+		// every multiplication by a non-integer constant consumes a level
+		factor := 1.0
+		for ciphertext.Level() > 4 {
+			ciphertext, _ = ciphertext.MultByConst(0.9)
+			factor *= 0.9
+		}
+
+		_, err := ciphertext.Sigmoid(-8, 8)
+		assert.ErrorContains(err, "only degree 7 fits, reaching error", "Didn't get expected error")
+
+		result, err := ciphertext.Exp(-1, 1)
+		assert.NoError(err, "Error performing operation")
+		marshalled, _ := result.Marshal()
+		decrypted, _ := server.DecryptCKKSVector(marshalled)
+		assert.InDelta(math.Exp(-0.5*factor), decrypted[0], 2*ckksMath.DefaultMaxApproximationError, "Decrypted value is not within the allowed delta")
+		assert.InDelta(math.Exp(0.5*factor), decrypted[1], 2*ckksMath.DefaultMaxApproximationError, "Decrypted value is not within the allowed delta")
+
+		for ciphertext.Level() > 2 {
+			ciphertext, _ = ciphertext.MultByConst(0.9)
+		}
+		_, err = ciphertext.Exp(-1, 1)
+		assert.ErrorContains(err, "approximation needs at least 3 levels, but 2 are left", "Didn't get expected error")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		_, err := ckksMath.Sigmoid(wrongInput, -1, 1)
+		assert.Error(err, "Didn't get expected error")
+
+		encrypted, _ := server.EncryptCKKS(1)
+		_, err = ctx.Log(encrypted, 0, 1)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.Inverse(encrypted, -1, 1)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.Sqrt(encrypted, 0, 1)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.Exp(encrypted, 1, -1)
+		assert.Error(err, "Didn't get expected error")
+	})
+}
@@ -0,0 +1,198 @@
+package test
+
+import (
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+type testCkksComparison struct {
+	name      string
+	operation ckksMath.BoundOperation2
+	function  func(float64, float64) float64
+	bound     float64
+	delta     float64
+	values1   []float64
+	values2   []float64
+}
+
+type testCkksArrayComparison struct {
+	name      string
+	operation ckksMath.ArrayBoundOperation
+	function  func(...float64) float64
+	bound     float64
+	delta     float64
+	values    []float64
+}
+
+func compare(a float64, b float64) float64 {
+	switch {
+	case a > b:
+		return 1
+	case a < b:
+		return 0
+	}
+	return 0.5
+}
+
+func TestCkksComparison(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ctx := server.Ckks
+
+	t.Run("resolution", func(t *testing.T) {
+		encrypted, _ := server.EncryptCKKS(1)
+		ciphertext, _ := ctx.Unmarshal(encrypted)
+
+		resolution, err := ckksMath.SignResolution(ciphertext.Level())
+		assert.NoError(err, "Error getting resolution")
+		assert.Equal(0.16, resolution, "Unexpected resolution of fresh ciphertexts")
+
+		maxError, err := ckksMath.MaxMinError(ciphertext.Level(), 2)
+		assert.NoError(err, "Error getting error")
+		assert.Equal(0.014, maxError, "Unexpected error of fresh ciphertexts")
+
+		_, err = ckksMath.MaxMinError(ciphertext.Level(), 5)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ckksMath.SignResolution(3)
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("sign", func(t *testing.T) {
+		values := []float64{-10, -3, -1.6, 0, 1.6, 4, 9.9}
+		encrypted, _ := server.EncryptCKKSVector(values)
+
+		operationResultBytes, err := ctx.Sign(encrypted, 10)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptCKKSVector(operationResultBytes)
+		for i, value := range values {
+			expected := 0.0
+			if value != 0 {
+				expected = math.Copysign(1, value)
+			}
+			assert.InDelta(expected, decrypted[i], 0.05, "Decrypted value is not within the allowed delta")
+		}
+	})
+
+	tests := []testCkksComparison{
+		{"compare", ctx.Compare, compare, 10, 0.05, []float64{5, 1, 7, -2}, []float64{2, 4, 7, 3}},
+		{"max", ctx.Max, math.Max, 10, 0.2, []float64{5, 1, 7, -2}, []float64{2, 4, 7, 3}},
+		{"min", ctx.Min, math.Min, 10, 0.2, []float64{5, 1, 7, -2}, []float64{2, 4, 7, 3}},
+	}
+
+	for _, currentTest := range tests {
+		t.Run(currentTest.name, func(t *testing.T) {
+			encrypted1, _ := server.EncryptCKKSVector(currentTest.values1)
+			encrypted2, _ := server.EncryptCKKSVector(currentTest.values2)
+
+			operationResultBytes, err := currentTest.operation(encrypted1, encrypted2, currentTest.bound)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := server.DecryptCKKSVector(operationResultBytes)
+			for i := range currentTest.values1 {
+				expected := currentTest.function(currentTest.values1[i], currentTest.values2[i])
+				assert.InDelta(expected, decrypted[i], currentTest.delta, "Decrypted value is not within the allowed delta")
+			}
+		})
+	}
+
+	arrayTests := []testCkksArrayComparison{
+		{"array max of 2", ctx.ArrayMax, arrayMax, 10, 0.2, []float64{3, 8}},
+		{"array min of 2", ctx.ArrayMin, arrayMin, 10, 0.2, []float64{3, 8}},
+		{"array max of 3", ctx.ArrayMax, arrayMax, 4, 0.5, []float64{2, 5, 3.5}},
+		{"array min of 4", ctx.ArrayMin, arrayMin, 4, 0.5, []float64{2, 5, 3.5, 4}},
+	}
+
+	for _, currentTest := range arrayTests {
+		t.Run(currentTest.name, func(t *testing.T) {
+			encryptedArray := encryptCkksArray(t, server.EncryptCKKS, currentTest.values)
+
+			operationResultBytes, err := currentTest.operation(encryptedArray, currentTest.bound)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := server.DecryptCKKS(operationResultBytes)
+			assert.InDelta(currentTest.function(currentTest.values...), decrypted, currentTest.delta, "Decrypted value is not within the allowed delta")
+		})
+	}
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		_, err := ckksMath.Sign(wrongInput, 1)
+		assert.Error(err, "Didn't get expected error")
+
+		encrypted, _ := server.EncryptCKKS(1)
+		_, err = ctx.Compare(encrypted, encrypted, 0)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.ArrayMax([][]byte{}, 1)
+		assert.Error(err, "Didn't get expected error")
+
+		tooLong := encryptCkksArray(t, server.EncryptCKKS, []float64{1, 2, 3, 4, 5})
+		_, err = ctx.ArrayMin(tooLong, 10)
+		assert.Error(err, "Didn't get expected error")
+
+		multiplied, _ := ctx.Pow2(encrypted)
+		for i := 0; i < 7; i++ {
+			multiplied, _ = ctx.Pow2(multiplied)
+		}
+		_, err = ctx.Max(multiplied, multiplied, 10)
+		assert.Error(err, "Didn't get expected error")
+	})
+}
+
+func arrayMax(values ...float64) float64 {
+	result := values[0]
+	for _, value := range values {
+		result = math.Max(result, value)
+	}
+	return result
+}
+
+func arrayMin(values ...float64) float64 {
+	result := values[0]
+	for _, value := range values {
+		result = math.Min(result, value)
+	}
+	return result
+}
+
+func TestCkksComparisonNearThreshold(t *testing.T) {
+	assert := assert.New(t)
+
+	config, err := he.DefaultContextConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.CkksParams, err = ckks.NewParametersFromLiteral(ckks.PN15QP880)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := he.NewServerContextWithKeyStore(config, he.NewMemoryKeyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []float64{-10, -3, -0.5, 0, 0.5, 4, 9.9}
+	encrypted, _ := server.EncryptCKKSVector(values)
+	ciphertext, _ := server.Ckks.Unmarshal(encrypted)
+
+	resolution, err := ckksMath.SignResolution(ciphertext.Level())
+	assert.NoError(err, "Error getting resolution")
+	assert.LessOrEqual(resolution, 0.05, "Deeper parameters don't resolve 5% of bound")
+
+	operationResultBytes, err := server.Ckks.Sign(encrypted, 10)
+	assert.NoError(err, "Error performing operation")
+
+	decrypted, _ := server.DecryptCKKSVector(operationResultBytes)
+	for i, value := range values {
+		expected := 0.0
+		if value != 0 {
+			expected = math.Copysign(1, value)
+		}
+		assert.InDelta(expected, decrypted[i], 0.05, "Decrypted value is not within the allowed delta")
+	}
+}
@@ -0,0 +1,124 @@
+package test
+
+import (
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testCkksMatVec struct {
+	name     string
+	matrix   [][]float64
+	vector   []float64
+	expected []float64
+}
+
+func TestCkksMatrix(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContextWithRotations(t, he.RotationSlotsAll)
+	ctx := server.Ckks
+
+	tests := []testCkksMatVec{
+		{"square", [][]float64{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}, []float64{1, -1, 2}, []float64{5, 11, 17}},
+		{"wide", [][]float64{{1, 0, -1}, {0.5, 2, 1}}, []float64{3, 1, 2}, []float64{1, 5.5}},
+		{"tall", [][]float64{{1, 2}, {-1, 1}, {0, 3}}, []float64{2, 0.5}, []float64{3, -1.5, 1.5}},
+	}
+
+	for _, currentTest := range tests {
+		t.Run(currentTest.name, func(t *testing.T) {
+			encrypted, _ := server.EncryptCKKSVector(currentTest.vector)
+
+			operationResultBytes, err := ctx.MatVec(currentTest.matrix, encrypted)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := server.DecryptCKKSVector(operationResultBytes)
+			assert.InDeltaSlice(currentTest.expected, decrypted[:len(currentTest.expected)], 1e-3, "Decrypted values are not within the allowed delta")
+			assert.InDelta(0, decrypted[len(currentTest.expected)+3], 1e-3, "Slots after the result are not zero")
+
+			encryptedMatrix, err := server.EncryptCKKSMatrix(currentTest.matrix)
+			assert.NoError(err, "Error encrypting matrix")
+
+			operationResultBytes, err = ctx.MatVecEncrypted(encryptedMatrix, encrypted)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ = server.DecryptCKKSVector(operationResultBytes)
+			assert.InDeltaSlice(currentTest.expected, decrypted[:len(currentTest.expected)], 1e-3, "Decrypted values are not within the allowed delta")
+		})
+	}
+
+	t.Run("layers", func(t *testing.T) {
+		encrypted, _ := server.EncryptCKKSVector([]float64{1, 2})
+
+		hidden, err := ctx.MatVec([][]float64{{1, 1}, {1, -1}, {2, 0}}, encrypted)
+		assert.NoError(err, "Error performing operation")
+
+		output, err := ctx.MatVec([][]float64{{1, 2, 0.5}}, hidden)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptCKKS(output)
+		assert.InDelta(2.0, decrypted, 1e-3, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("matmul", func(t *testing.T) {
+		a := [][]float64{{1, 2, 0}, {0, 1, -1}}
+		columns := encryptCkksVectors(t, server.EncryptCKKSVector, [][]float64{{1, 0, 2}, {3, -1, 1}})
+		expected := [][]float64{{1, -2}, {1, -2}}
+
+		operationResultBytes, err := ctx.MatMul(a, columns)
+		assert.NoError(err, "Error performing operation")
+		assert.Len(operationResultBytes, 2, "Wrong number of columns")
+		for i, column := range operationResultBytes {
+			decrypted, _ := server.DecryptCKKSVector(column)
+			assert.InDeltaSlice(expected[i], decrypted[:2], 1e-3, "Decrypted values are not within the allowed delta")
+		}
+
+		encryptedMatrix, _ := server.EncryptCKKSMatrix(a)
+		operationResultBytes, err = ctx.MatMulEncrypted(encryptedMatrix, columns)
+		assert.NoError(err, "Error performing operation")
+		for i, column := range operationResultBytes {
+			decrypted, _ := server.DecryptCKKSVector(column)
+			assert.InDeltaSlice(expected[i], decrypted[:2], 1e-3, "Decrypted values are not within the allowed delta")
+		}
+	})
+
+	t.Run("diagonals", func(t *testing.T) {
+		diagonals, err := ckksMath.MatrixDiagonals([][]float64{{1, 2}, {3, 4}, {5, 6}})
+		assert.NoError(err, "Error computing diagonals")
+		assert.Equal([][]float64{{1, 4, 0}, {2, 0, 5}, {0, 3, 6}}, diagonals, "Wrong diagonals")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		_, err := ckksMath.MatVec([][]float64{{1}}, wrongInput)
+		assert.Error(err, "Didn't get expected error")
+
+		encrypted, _ := server.EncryptCKKSVector([]float64{1, 2})
+		_, err = ctx.MatVec([][]float64{{1, 2}, {3}}, encrypted)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.MatVec([][]float64{}, encrypted)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.MatVecEncrypted([][]byte{}, encrypted)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.MatMul([][]float64{{1}}, [][]byte{})
+		assert.Error(err, "Didn't get expected error")
+
+		limited := newTestServerContextWithRotations(t, 16)
+		encrypted, _ = limited.EncryptCKKSVector([]float64{1, 2})
+		_, err = limited.Ckks.MatVec([][]float64{{1, 2}, {3, 4}}, encrypted)
+		assert.Error(err, "Didn't get expected error, rotation keys are missing")
+	})
+}
+
+func encryptCkksVectors(t *testing.T, encrypt func([]float64) ([]byte, error), vectors [][]float64) [][]byte {
+	encrypted := make([][]byte, len(vectors))
+	for i, vector := range vectors {
+		var err error
+		encrypted[i], err = encrypt(vector)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return encrypted
+}
@@ -0,0 +1,220 @@
+package test
+
+import (
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestCkksLinearRegression(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ctx := server.Ckks
+
+	x := []float64{1, 2, 3, 4, 5, 6, 7, 8}
+	y := []float64{3.1, 4.9, 7, 9.1, 10.9, 13, 15.1, 16.9}
+
+	t.Run("simple", func(t *testing.T) {
+		encryptedX := encryptCkksArray(t, server.EncryptCKKS, x)
+		encryptedY := encryptCkksArray(t, server.EncryptCKKS, y)
+
+		model, err := ctx.LinearRegression(encryptedX, encryptedY, 1, 10)
+		assert.NoError(err, "Error performing operation")
+
+		slope, _ := server.DecryptCKKS(model[0])
+		intercept, _ := server.DecryptCKKS(model[1])
+		assert.InDelta(1.9929, slope, 1e-2, "Decrypted value is not within the allowed delta")
+		assert.InDelta(1.0321, intercept, 5e-2, "Decrypted value is not within the allowed delta")
+
+		encryptedFeature, _ := server.EncryptCKKS(10)
+		prediction, err := ctx.PredictLinearEncrypted([][]byte{encryptedFeature}, model)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptCKKS(prediction)
+		assert.InDelta(20.961, decrypted, 0.1, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("multiple", func(t *testing.T) {
+		x2 := []float64{2, 1, 4, 3, 6, 5, 8, 6}
+		target := make([]float64, len(x))
+		for i := range x {
+			target[i] = 3*x[i] - 2*x2[i] + 5
+		}
+
+		features := [][][]byte{encryptCkksArray(t, server.EncryptCKKS, x), encryptCkksArray(t, server.EncryptCKKS, x2)}
+		statistics, err := ctx.LinearRegressionStatistics(features, encryptCkksArray(t, server.EncryptCKKS, target))
+		assert.NoError(err, "Error performing operation")
+		assert.Len(statistics, 9, "Wrong number of statistics")
+
+		model, err := server.FitLinearRegression(statistics)
+		assert.NoError(err, "Error fitting model")
+		assert.InDeltaSlice([]float64{3, -2}, model.Weights, 1e-2, "Weights are not within the allowed delta")
+		assert.InDelta(5, model.Intercept, 5e-2, "Intercept is not within the allowed delta")
+
+		encryptedFeatures := encryptCkksArray(t, server.EncryptCKKS, []float64{2, 3})
+		prediction, err := ctx.PredictLinear(encryptedFeatures, model)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptCKKS(prediction)
+		assert.InDelta(5, decrypted, 0.1, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("gradient descent", func(t *testing.T) {
+		x2 := []float64{3, -1, 2, -2, -1, 2, -2, 1}
+		target := make([]float64, len(x))
+		for i := range x {
+			target[i] = 3*x[i] - 2*x2[i] + 5
+		}
+
+		// the same iterations in plaintext
+		features := [][]float64{x, x2}
+		rate, iterations := 0.1, 7
+		covariances, means := make([][]float64, len(features)+1), make([]float64, len(features)+1)
+		columns := append(features, target)
+		for i, column := range columns {
+			for _, value := range column {
+				means[i] += value / float64(len(column))
+			}
+		}
+		for i := range features {
+			covariances[i] = make([]float64, len(columns))
+			for j, column := range columns {
+				for row := range column {
+					covariances[i][j] += (columns[i][row] - means[i]) * (column[row] - means[j]) / float64(len(column))
+				}
+			}
+		}
+		expected := make([]float64, len(features)+1)
+		for iteration := 0; iteration < iterations; iteration++ {
+			next := make([]float64, len(features))
+			for i := range next {
+				next[i] = expected[i] + rate*covariances[i][len(features)]
+				for j := range features {
+					next[i] -= rate * covariances[i][j] * expected[j]
+				}
+			}
+			copy(expected, next)
+		}
+		expected[len(features)] = means[len(features)]
+		for i := range features {
+			expected[len(features)] -= expected[i] * means[i]
+		}
+
+		encryptedFeatures := [][][]byte{encryptCkksArray(t, server.EncryptCKKS, x), encryptCkksArray(t, server.EncryptCKKS, x2)}
+		encryptedTarget := encryptCkksArray(t, server.EncryptCKKS, target)
+		model, err := ctx.LinearRegressionGradientDescent(encryptedFeatures, encryptedTarget, rate, iterations)
+		assert.NoError(err, "Error performing operation")
+		assert.Len(model, 3, "Wrong number of weights")
+
+		decrypted := make([]float64, len(model))
+		for i := range model {
+			decrypted[i], _ = server.DecryptCKKS(model[i])
+		}
+		assert.InDeltaSlice(expected, decrypted, 1e-2, "Weights are not within the allowed delta")
+		assert.InDeltaSlice([]float64{3, -2, 5}, decrypted, 0.1, "Gradient descent didn't approach the model")
+
+		encryptedPoint := encryptCkksArray(t, server.EncryptCKKS, []float64{2, 3})
+		prediction, err := ctx.PredictLinearEncrypted(encryptedPoint, model)
+		assert.NoError(err, "Error performing operation")
+
+		decryptedPrediction, _ := server.DecryptCKKS(prediction)
+		assert.InDelta(expected[0]*2+expected[1]*3+expected[2], decryptedPrediction, 5e-2, "Decrypted value is not within the allowed delta")
+
+		_, err = ctx.LinearRegressionGradientDescent(encryptedFeatures, encryptedTarget, rate, 8)
+		assert.ErrorContains(err, "run at most 7 iterations", "Didn't get expected error")
+	})
+
+	t.Run("solve", func(t *testing.T) {
+		// y = 2 * x + 1 with variance of x 4 and mean 3
+		model, err := ckksMath.SolveLinearRegression([]float64{4, 8, 3, 7})
+		assert.NoError(err, "Error fitting model")
+		assert.InDeltaSlice([]float64{2}, model.Weights, 1e-9, "Weights are not within the allowed delta")
+		assert.InDelta(1, model.Intercept, 1e-9, "Intercept is not within the allowed delta")
+
+		_, err = ckksMath.SolveLinearRegression([]float64{1, 2, 3})
+		assert.Error(err, "Didn't get expected error")
+
+		// second feature is twice the first one
+		_, err = ckksMath.SolveLinearRegression([]float64{1, 2, 1, 2, 4, 2, 1, 2, 3})
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		_, err := ckksMath.LinearRegression([][]byte{wrongInput}, [][]byte{wrongInput}, 1, 10)
+		assert.Error(err, "Didn't get expected error")
+
+		encryptedX := encryptCkksArray(t, server.EncryptCKKS, x)
+		_, err = ctx.LinearRegression(encryptedX, encryptedX[1:], 1, 10)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.LinearRegressionStatistics([][][]byte{}, encryptedX)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.LinearRegressionGradientDescent([][][]byte{encryptedX}, encryptedX, 0, 1)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.LinearRegressionGradientDescent([][][]byte{encryptedX}, encryptedX, 0.1, 0)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.LinearRegressionGradientDescent([][][]byte{encryptedX[1:]}, encryptedX, 0.1, 1)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.PredictLinear(encryptedX[:2], &ckksMath.LinearModel{Weights: []float64{1}})
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.PredictLinearEncrypted(encryptedX[:2], encryptedX[:2])
+		assert.Error(err, "Didn't get expected error")
+	})
+}
+
+func TestCkksLogisticRegression(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContextWithRotations(t, 16)
+	ctx := server.Ckks
+
+	model := &ckksMath.LinearModel{Weights: []float64{0.8, -1.5, 0.3}, Intercept: 0.5}
+	probability := func(x []float64) float64 {
+		z := model.Intercept
+		for i, weight := range model.Weights {
+			z += weight * x[i]
+		}
+		return 1 / (1 + math.Exp(-z))
+	}
+
+	t.Run("single vector", func(t *testing.T) {
+		features := []float64{2, 1, -1}
+		encrypted, _ := server.EncryptCKKSVector(features)
+
+		operationResultBytes, err := ctx.PredictLogistic(encrypted, model, -8, 8)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptCKKS(operationResultBytes)
+		assert.InDelta(probability(features), decrypted, 1e-2, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("packed vectors", func(t *testing.T) {
+		vectors := [][]float64{{2, 1, -1}, {-1, 3, 0}, {0, 0, 0}, {4, -1, 2}}
+		var packed []float64
+		for _, vector := range vectors {
+			packed = append(packed, vector...)
+		}
+		encrypted, _ := server.EncryptCKKSVector(packed)
+
+		operationResultBytes, err := ctx.PredictLogistic(encrypted, model, -8, 8)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptCKKSVector(operationResultBytes)
+		for i, vector := range vectors {
+			assert.InDelta(probability(vector), decrypted[i*len(model.Weights)], 1e-2, "Decrypted value is not within the allowed delta")
+		}
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := ckksMath.PredictLogistic([]byte{0x00, 0x00, 0x00}, model, -8, 8)
+		assert.Error(err, "Didn't get expected error")
+
+		encrypted, _ := server.EncryptCKKSVector([]float64{1, 2, 3})
+		_, err = ctx.PredictLogistic(encrypted, &ckksMath.LinearModel{}, -8, 8)
+		assert.Error(err, "Didn't get expected error")
+		_, err = ctx.PredictLogistic(encrypted, &ckksMath.LinearModel{Weights: make([]float64, 17)}, -8, 8)
+		assert.Error(err, "Didn't get expected error, rotation key is missing")
+	})
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"encoding/json"
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func newTestServerContextWithRotations(t *testing.T, rotationSlots int) *he.Context {
+	config, err := he.DefaultContextConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.RotationSlots = rotationSlots
+
+	dir := t.TempDir()
+	ctx, err := he.NewServerContextWithConfig(config, filepath.Join(dir, "ckksKeys.json"), filepath.Join(dir, "bfvKeys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+type testCkksSlotMath struct {
+	name     string
+	value1   []float64
+	value2   []float64
+	n        int
+	expected float64
+}
+
+func TestCkksSlotOperations(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContextWithRotations(t, 16)
+
+	// eval keys go through JSON the same way they are sent to clients
+	evalKeysJSON, err := json.Marshal(server.EvalKeysCkks)
+	assert.NoError(err, "Error marshalling eval keys")
+	var evalKeys he.EvalKeys
+	assert.NoError(json.Unmarshal(evalKeysJSON, &evalKeys), "Error unmarshalling eval keys")
+	ctx := ckksMath.NewContext(server.CkksParams, evalKeys.EvalKey1)
+
+	sumTests := []testCkksSlotMath{
+		{"power of two", []float64{1, 2, 3, 4}, nil, 4, 10},
+		{"not power of two", []float64{1, 2, 3, 4, 5, 6, 7}, nil, 7, 28},
+		{"ignores tail", []float64{1, 2, 3, 4, 5, 6, 7}, nil, 5, 15},
+		{"single", []float64{-3.5}, nil, 1, -3.5},
+		{"full span", []float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, nil, 16, 16},
+	}
+	for _, currentTest := range sumTests {
+		t.Run("sum "+currentTest.name, func(t *testing.T) {
+			encrypted, _ := server.EncryptCKKSVector(currentTest.value1)
+
+			operationResultBytes, err := ctx.SlotSum(encrypted, currentTest.n)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := server.DecryptCKKS(operationResultBytes)
+			assert.InDelta(currentTest.expected, decrypted, 1e-2, "Decrypted value is not within the allowed delta")
+		})
+	}
+
+	t.Run("mean", func(t *testing.T) {
+		encrypted, _ := server.EncryptCKKSVector([]float64{1, 2, 3, 4, 5, 6})
+
+		operationResultBytes, err := ctx.SlotMean(encrypted, 6)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptCKKS(operationResultBytes)
+		assert.InDelta(3.5, decrypted, 1e-2, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("inner product", func(t *testing.T) {
+		encrypted1, _ := server.EncryptCKKSVector([]float64{1, 2, 3})
+		encrypted2, _ := server.EncryptCKKSVector([]float64{4, -5, 6})
+
+		operationResultBytes, err := ctx.InnerProduct(encrypted1, encrypted2, 3)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptCKKS(operationResultBytes)
+		assert.InDelta(12.0, decrypted, 1e-2, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("mult by vector", func(t *testing.T) {
+		encrypted, _ := server.EncryptCKKSVector([]float64{1, 2, 3, 4})
+
+		operationResultBytes, err := ctx.MultByVector(encrypted, []float64{0.5, -2, 3})
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptCKKSVector(operationResultBytes)
+		assert.InDeltaSlice([]float64{0.5, -4, 9, 0}, decrypted[:4], 1e-3, "Decrypted values are not within the allowed delta")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		encrypted, _ := server.EncryptCKKSVector([]float64{1, 2, 3})
+
+		_, err := ctx.SlotSum([]byte{0x00, 0x00, 0x00}, 2)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.SlotSum(encrypted, 0)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.SlotSum(encrypted, 17)
+		assert.Error(err, "Didn't get expected error, rotation key is missing")
+
+		_, err = ctx.MultByVector(encrypted, make([]float64, server.CkksSlots()+1))
+		assert.Error(err, "Didn't get expected error")
+	})
+}
@@ -0,0 +1,114 @@
+package test
+
+import (
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+type testCkksStatistics struct {
+	name   string
+	array1 []float64
+	array2 []float64
+	a      float64
+	b      float64
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func stdDev(values []float64) float64 {
+	m := mean(values)
+	var sum float64
+	for _, value := range values {
+		sum += (value - m) * (value - m)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+func pearsonCorrelation(values1 []float64, values2 []float64) float64 {
+	m1, m2 := mean(values1), mean(values2)
+	var sum float64
+	for i := range values1 {
+		sum += (values1[i] - m1) * (values2[i] - m2)
+	}
+	return sum / float64(len(values1)) / stdDev(values1) / stdDev(values2)
+}
+
+func encryptCkksArray(t *testing.T, encrypt func(float64) ([]byte, error), values []float64) [][]byte {
+	encrypted := make([][]byte, len(values))
+	for i, value := range values {
+		var err error
+		encrypted[i], err = encrypt(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return encrypted
+}
+
+func TestCkksStatistics(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ctx := server.Ckks
+
+	tests := []testCkksStatistics{
+		{"positive correlation", []float64{1, 2, 3, 4, 5, 6}, []float64{2.1, 3.9, 6.2, 7.8, 10.1, 12}, 1, 20},
+		{"negative correlation", []float64{-1, 0.5, 2, 3, 4.5}, []float64{3, 1, 0.5, -1.5, -2}, 1, 10},
+		{"weak correlation", []float64{3, 1, 4, 1, 5, 9, 2, 6}, []float64{2, 7, 1, 8, 2, 8, 1, 8}, 1, 10},
+	}
+
+	for _, currentTest := range tests {
+		t.Run(currentTest.name, func(t *testing.T) {
+			encrypted1 := encryptCkksArray(t, server.EncryptCKKS, currentTest.array1)
+			encrypted2 := encryptCkksArray(t, server.EncryptCKKS, currentTest.array2)
+
+			operationResultBytes, err := ctx.StdDev(encrypted1, currentTest.a, currentTest.b)
+			assert.NoError(err, "Error performing operation")
+			decrypted, _ := server.DecryptCKKS(operationResultBytes)
+			assert.InDelta(stdDev(currentTest.array1), decrypted, 1e-2, "Decrypted value is not within the allowed delta")
+
+			operationResultBytes, err = ctx.PearsonCorrelation(encrypted1, encrypted2, currentTest.a, currentTest.b)
+			assert.NoError(err, "Error performing operation")
+			decrypted, _ = server.DecryptCKKS(operationResultBytes)
+			assert.InDelta(pearsonCorrelation(currentTest.array1, currentTest.array2), decrypted, 1e-2, "Decrypted value is not within the allowed delta")
+
+			operationResultArray, err := ctx.ZScoreNormalize(encrypted1, currentTest.a, currentTest.b)
+			assert.NoError(err, "Error performing operation")
+			m, s := mean(currentTest.array1), stdDev(currentTest.array1)
+			for i, value := range currentTest.array1 {
+				decrypted, _ = server.DecryptCKKS(operationResultArray[i])
+				assert.InDelta((value-m)/s, decrypted, 1e-2, "Decrypted value is not within the allowed delta")
+			}
+		})
+	}
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		_, err := ckksMath.StdDev([][]byte{wrongInput, wrongInput}, 1, 10)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.StdDev([][]byte{}, 1, 10)
+		assert.Error(err, "Didn't get expected error")
+
+		encrypted := encryptCkksArray(t, server.EncryptCKKS, []float64{1, 2, 3})
+		_, err = ctx.PearsonCorrelation(encrypted, encrypted[:2], 0.1, 10)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.ZScoreNormalize(encrypted, 0, 10)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ctx.PearsonCorrelation(encrypted, encrypted, 1, 50)
+		assert.ErrorContains(err, "only degree 15 fits", "Didn't get expected error")
+
+		_, err = ctx.ZScoreNormalize(encrypted, 1, 300)
+		assert.ErrorContains(err, "only degree 31 fits", "Didn't get expected error")
+	})
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+type testCkksPolynomial struct {
+	name       string
+	polynomial *ckksMath.Polynomial
+	values     []float64
+	delta      float64
+}
+
+func TestCkksEvaluatePolynomial(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ctx := server.Ckks
+
+	tests := []testCkksPolynomial{
+		{"constant", ckksMath.NewPolynomial([]float64{2.5}), []float64{1, -3}, 1e-3},
+		{"linear", ckksMath.NewPolynomial([]float64{1, -2}), []float64{0, 1.5, -4}, 1e-3},
+		{"cubic", ckksMath.NewPolynomial([]float64{1, 0, -0.5, 0.25}), []float64{0.5, -1, 2}, 1e-3},
+		{"degree 7", ckksMath.NewPolynomial([]float64{1, 1, 0.5, 1.0 / 6, 1.0 / 24, 1.0 / 120, 1.0 / 720, 1.0 / 5040}), []float64{-1, 0, 0.75}, 1e-3},
+		{"zero leading coefficients", ckksMath.NewPolynomial([]float64{0, 3, 0, 0}), []float64{-2, 2}, 1e-3},
+		{"chebyshev", ckksMath.NewChebyshevPolynomial([]float64{0.5, -1, 0.25, 2}, -2, 2), []float64{-2, -0.5, 1, 2}, 1e-3},
+		{"chebyshev sin", ckksMath.ApproximatePolynomial(math.Sin, -3, 3, 15), []float64{-3, -1, 0.2, 2.5}, 1e-3},
+	}
+
+	for _, currentTest := range tests {
+		t.Run(currentTest.name, func(t *testing.T) {
+			encrypted, _ := server.EncryptCKKSVector(currentTest.values)
+
+			operationResultBytes, err := ctx.EvaluatePolynomial(encrypted, currentTest.polynomial)
+			assert.NoError(err, "Error performing operation")
+
+			decrypted, _ := server.DecryptCKKSVector(operationResultBytes)
+			for i, value := range currentTest.values {
+				assert.InDelta(currentTest.polynomial.Evaluate(value), decrypted[i], currentTest.delta, "Decrypted value is not within the allowed delta")
+			}
+		})
+	}
+
+	t.Run("plain evaluation", func(t *testing.T) {
+		polynomial := ckksMath.ApproximatePolynomial(math.Sin, -3, 3, 15)
+		for _, x := range []float64{-3, -1, 0, 1.5, 3} {
+			assert.InDelta(math.Sin(x), polynomial.Evaluate(x), 1e-6, "Approximation is not within the allowed delta")
+		}
+		assert.Equal(5, polynomial.Depth(), "Wrong polynomial depth")
+	})
+
+	t.Run("levels", func(t *testing.T) {
+		encrypted, _ := server.EncryptCKKS(0.5)
+		ciphertext, _ := ctx.Unmarshal(encrypted)
+		polynomial := ckksMath.NewPolynomial([]float64{0, 1, 1, 1})
+
+		result, err := ciphertext.EvaluatePolynomial(polynomial)
+		assert.NoError(err, "Error performing operation")
+		assert.Equal(ciphertext.Level()-polynomial.Depth(), result.Level(), "Wrong number of levels consumed")
+
+		for err == nil {
+			result, err = result.EvaluatePolynomial(polynomial)
+		}
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		_, err := ckksMath.EvaluatePolynomial(wrongInput, ckksMath.NewPolynomial([]float64{1, 1}))
+		assert.Error(err, "Didn't get expected error")
+
+		encrypted, _ := he.EncryptCKKS(1)
+		_, err = ckksMath.EvaluatePolynomial(encrypted, ckksMath.NewPolynomial(nil))
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = ckksMath.EvaluatePolynomial(encrypted, ckksMath.NewChebyshevPolynomial([]float64{1, 1}, 1, -1))
+		assert.Error(err, "Didn't get expected error")
+	})
+}
@@ -0,0 +1,212 @@
+package test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient creates a Client of ts trusting its certificate
+func newTestClient(t *testing.T, ts *httptest.Server, path string, options ...he.ClientOption) *he.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	client, err := he.NewClient(ts.URL+path, append([]he.ClientOption{he.WithRootCAs(pool)}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ts := httptest.NewTLSServer(he.NewServer(server, he.WithRoutePrefix("/he/v1")))
+	defer ts.Close()
+
+	client := newTestClient(t, ts, "/he/v1")
+	ctx := context.Background()
+
+	t.Run("params and eval keys", func(t *testing.T) {
+		ckksParams, err := client.CkksParams(ctx)
+		assert.NoError(err, "Error retrieving CKKS params")
+		assert.True(ckksParams.Equals(server.CkksParams), "Retrieved CKKS params are not equal to server params")
+
+		bfvParams, err := client.BfvParams(ctx)
+		assert.NoError(err, "Error retrieving BFV params")
+		assert.True(bfvParams.Equals(server.BfvParams), "Retrieved BFV params are not equal to server params")
+
+		_, err = client.CkksEvalKeys(ctx)
+		assert.NoError(err, "Error retrieving CKKS eval keys")
+		_, err = client.BfvEvalKeys(ctx)
+		assert.NoError(err, "Error retrieving BFV eval keys")
+	})
+
+	t.Run("decrypt", func(t *testing.T) {
+		encryptedCkks, _ := server.EncryptCKKS(3.5)
+		decryptedCkks, err := client.DecryptCkks(ctx, encryptedCkks)
+		assert.NoError(err, "Error decrypting CKKS result")
+		assert.InDelta(3.5, decryptedCkks, 0.001, "Decrypted value is not equal to expected value")
+
+		encryptedBfv, _ := server.EncryptBFV(7)
+		decryptedBfv, err := client.DecryptBfv(ctx, encryptedBfv)
+		assert.NoError(err, "Error decrypting BFV result")
+		assert.Equal(int64(7), decryptedBfv, "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+		_, err := client.DecryptCkks(ctx, wrongInput)
+
+		var serverErr *he.ServerError
+		if assert.True(errors.As(err, &serverErr), "Error response not returned as ServerError") {
+			assert.NotEqual(http.StatusOK, serverErr.StatusCode, "Unexpected status code")
+			assert.NotEmpty(serverErr.Message, "Error message of server not returned")
+		}
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := client.BfvParams(canceled)
+		assert.ErrorIs(err, context.Canceled, "Canceled request not stopped")
+	})
+
+	t.Run("invalid base URL", func(t *testing.T) {
+		_, err := he.NewClient("127.0.0.1/he/v1")
+		assert.Error(err, "Relative base URL accepted")
+	})
+}
+
+func TestClientErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	token, _ := he.GenerateAPIToken()
+	auth, _ := he.NewTokenAuthenticator(he.AuthConfig{Clients: []he.AuthClient{
+		{Name: "reports", TokenHash: he.HashAPIToken(token), Permissions: []he.Permission{he.PermissionDecrypt}},
+	}})
+	router := he.NewRouter(server, he.WithAuthenticator(auth))
+
+	var failures, requests atomic.Int32
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch {
+		case failures.Load() > 0:
+			failures.Add(-1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.URL.Path == "/empty/decrypt_computations_bfv":
+			w.Write([]byte(`{}`))
+		default:
+			router.ServeHTTP(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	encrypted, _ := server.EncryptBFV(5)
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := newTestClient(t, ts, "").DecryptBfv(ctx, encrypted)
+		assert.ErrorIs(err, he.ErrUnauthenticated, "Unauthenticated request not reported")
+
+		decrypted, err := newTestClient(t, ts, "", he.WithAPIToken(token)).DecryptBfv(ctx, encrypted)
+		assert.NoError(err, "Error decrypting with API token")
+		assert.Equal(int64(5), decrypted, "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("forbidden", func(t *testing.T) {
+		_, err := newTestClient(t, ts, "", he.WithAPIToken(token)).BfvParams(ctx)
+
+		var serverErr *he.ServerError
+		if assert.True(errors.As(err, &serverErr), "Error response not returned as ServerError") {
+			assert.Equal(http.StatusForbidden, serverErr.StatusCode, "Unexpected status code")
+		}
+	})
+
+	t.Run("no decrypted result", func(t *testing.T) {
+		_, err := newTestClient(t, ts, "/empty").DecryptBfv(ctx, encrypted)
+		assert.ErrorIs(err, he.ErrNoDecryptedResult, "Response without result decrypted to zero")
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		client := newTestClient(t, ts, "", he.WithAPIToken(token), he.WithRetries(2, time.Millisecond))
+
+		failures.Store(2)
+		requests.Store(0)
+		decrypted, err := client.DecryptBfv(ctx, encrypted)
+		assert.NoError(err, "Error decrypting after retries")
+		assert.Equal(int64(5), decrypted, "Decrypted value is not equal to expected value")
+		assert.Equal(int32(3), requests.Load(), "Unexpected number of attempts")
+
+		failures.Store(3)
+		requests.Store(0)
+		_, err = client.DecryptBfv(ctx, encrypted)
+		var serverErr *he.ServerError
+		if assert.True(errors.As(err, &serverErr), "Error response not returned as ServerError") {
+			assert.Equal(http.StatusServiceUnavailable, serverErr.StatusCode, "Unexpected status code")
+		}
+		assert.Equal(int32(3), requests.Load(), "Unexpected number of attempts")
+		failures.Store(0)
+	})
+
+	t.Run("certificate not retried", func(t *testing.T) {
+		var connections atomic.Int32
+		untrusted := httptest.NewUnstartedServer(router)
+		untrusted.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				connections.Add(1)
+			}
+		}
+		untrusted.StartTLS()
+		defer untrusted.Close()
+
+		client, err := he.NewClient(untrusted.URL, he.WithAPIToken(token), he.WithRetries(2, time.Millisecond))
+		assert.NoError(err, "Error creating client")
+
+		_, err = client.DecryptBfv(ctx, encrypted)
+		var verificationErr *tls.CertificateVerificationError
+		assert.True(errors.As(err, &verificationErr), "Untrusted certificate not reported")
+		assert.Equal(int32(1), connections.Load(), "Failed certificate verification was retried")
+	})
+
+	t.Run("rejected client certificate not retried", func(t *testing.T) {
+		var connections atomic.Int32
+		mtls := httptest.NewUnstartedServer(router)
+		mtls.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+		mtls.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				connections.Add(1)
+			}
+		}
+		mtls.StartTLS()
+		defer mtls.Close()
+
+		_, err := newTestClient(t, mtls, "", he.WithAPIToken(token), he.WithRetries(2, time.Millisecond)).BfvParams(ctx)
+		var opErr *net.OpError
+		if assert.True(errors.As(err, &opErr), "TLS alert not reported") {
+			assert.Equal("remote error", opErr.Op, "Unexpected error")
+		}
+		assert.Equal(int32(1), connections.Load(), "Rejected client certificate was retried")
+	})
+
+	t.Run("connection refused retried", func(t *testing.T) {
+		closed := httptest.NewTLSServer(router)
+		closed.Close()
+
+		start := time.Now()
+		_, err := newTestClient(t, closed, "", he.WithRetries(2, 50*time.Millisecond)).DecryptBfv(ctx, encrypted)
+		assert.Error(err, "Didn't get expected error")
+		assert.GreaterOrEqual(time.Since(start), 150*time.Millisecond, "Refused connection wasn't retried")
+	})
+}
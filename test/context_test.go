@@ -0,0 +1,119 @@
+package test
+
+import (
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func newTestServerContext(t *testing.T) *he.Context {
+	dir := t.TempDir()
+	ctx, err := he.NewServerContext(filepath.Join(dir, "ckksKeys.json"), filepath.Join(dir, "bfvKeys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ctx
+}
+
+func TestContextsWithDifferentKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx1 := newTestServerContext(t)
+	ctx2 := newTestServerContext(t)
+
+	t.Run("ckks", func(t *testing.T) {
+		encrypted, err := ctx1.EncryptCKKS(42.0)
+		assert.NoError(err, "Error encrypting original data")
+
+		decrypted, err := ctx1.DecryptCKKS(encrypted)
+		assert.NoError(err, "Error decrypting original data")
+		assert.InDelta(42.0, decrypted, 1e-3, "Decrypted value is not within the allowed delta")
+
+		decrypted, _ = ctx2.DecryptCKKS(encrypted)
+		assert.NotEqual(42, int(decrypted), "Data decrypted with foreign keys")
+	})
+
+	t.Run("bfv", func(t *testing.T) {
+		encrypted, err := ctx1.EncryptBFV(42)
+		assert.NoError(err, "Error encrypting original data")
+
+		decrypted, err := ctx1.DecryptBFV(encrypted)
+		assert.NoError(err, "Error decrypting original data")
+		assert.Equal(int64(42), decrypted, "Decrypted value is not equal to expected value")
+
+		decrypted, _ = ctx2.DecryptBFV(encrypted)
+		assert.NotEqual(int64(42), decrypted, "Data decrypted with foreign keys")
+	})
+}
+
+func TestClientContext(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	client := he.NewClientContext(server.CkksParams, server.BfvParams, server.EvalKeysCkks.EvalKey1, server.EvalKeysBfv.EvalKey1)
+
+	t.Run("ckks", func(t *testing.T) {
+		encrypted1, _ := server.EncryptCKKS(2.0)
+		encrypted2, _ := server.EncryptCKKS(3.0)
+
+		operationResultBytes, err := client.Ckks.Sum(encrypted1, encrypted2)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptCKKS(operationResultBytes)
+		assert.InDelta(5.0, decrypted, 1e-3, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("bfv", func(t *testing.T) {
+		encrypted1, _ := server.EncryptBFV(2)
+		encrypted2, _ := server.EncryptBFV(3)
+
+		operationResultBytes, err := client.Bfv.Mult(encrypted1, encrypted2)
+		assert.NoError(err, "Error performing operation")
+
+		decrypted, _ := server.DecryptBFV(operationResultBytes)
+		assert.Equal(int64(6), decrypted, "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("no keys", func(t *testing.T) {
+		_, err := client.EncryptCKKS(1.0)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = client.DecryptBFV([]byte{0x00, 0x00, 0x00})
+		assert.Error(err, "Didn't get expected error")
+	})
+}
+
+func TestGlobalContextFollowsKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	ckksParams, bfvParams := he.CkksParams, he.BfvParams
+	ckksKeys, bfvKeys := he.CkksKeys, he.BfvKeys
+	evalKeysCkks, evalKeysBfv := he.EvalKeysCkks, he.EvalKeysBfv
+	defer func() {
+		he.CkksParams, he.BfvParams = ckksParams, bfvParams
+		he.CkksKeys, he.BfvKeys = ckksKeys, bfvKeys
+		he.EvalKeysCkks, he.EvalKeysBfv = evalKeysCkks, evalKeysBfv
+	}()
+
+	encrypted, err := he.EncryptBFV(5)
+	assert.NoError(err, "Error encrypting")
+
+	dir := t.TempDir()
+	he.SetupServer(filepath.Join(dir, "ckksKeys.json"), filepath.Join(dir, "bfvKeys.json"))
+
+	reencrypted, err := he.EncryptBFV(6)
+	assert.NoError(err, "Error encrypting with new keys")
+	decrypted, err := he.DecryptBFV(reencrypted)
+	assert.NoError(err, "Error decrypting with new keys")
+	assert.Equal(int64(6), decrypted, "Package-level functions did not use new keys")
+
+	decrypted, _ = he.DecryptBFV(encrypted)
+	assert.NotEqual(int64(5), decrypted, "Package-level functions still use old keys")
+
+	// keys assigned directly take effect as well
+	he.BfvKeys = bfvKeys
+	decrypted, err = he.DecryptBFV(encrypted)
+	assert.NoError(err, "Error decrypting with assigned keys")
+	assert.Equal(int64(5), decrypted, "Package-level functions did not use assigned keys")
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/SamBridgess/homomorphicEncryption/bfvMath"
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDecryptionPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	policy := he.NewDecryptionPolicy()
+	policy.ApproveBfvAggregate("ArraySum", 3, (*bfvMath.Context).ArraySum)
+	policy.ApproveCkksAggregate("ArrayMean", 3, (*ckksMath.Context).ArrayMean)
+
+	ts := httptest.NewTLSServer(he.NewRouter(server, he.WithDecryptionPolicy(policy)))
+	defer ts.Close()
+
+	httpsServer := he.HttpsServer
+	he.HttpsServer = ts.Client()
+	defer func() { he.HttpsServer = httpsServer }()
+
+	// stored rows are registered by the data owner, computations are done by the client
+	rows := make([][]byte, 4)
+	for i := range rows {
+		rows[i], _ = server.EncryptBFV(int64(i + 1))
+	}
+	ckksRows := make([][]byte, 3)
+	for i := range ckksRows {
+		ckksRows[i], _ = server.EncryptCKKS(float64(i) + 0.5)
+	}
+	policy.RegisterInputs(rows...)
+	policy.RegisterInputs(ckksRows...)
+
+	client := he.NewClientContext(server.CkksParams, server.BfvParams, server.EvalKeysCkks.EvalKey1, server.EvalKeysBfv.EvalKey1)
+	sum, err := client.Bfv.ArraySum(rows[1:])
+	assert.NoError(err, "Error performing operation")
+
+	t.Run("approved bfv", func(t *testing.T) {
+		transcript := &he.ComputationTranscript{Operation: "ArraySum", Inputs: rows[1:]}
+		decrypted, err := he.SendVerifiableResultToServerBfv(ts.URL+"/decrypt_computations_bfv", sum, transcript)
+		assert.NoError(err, "Approved result was not decrypted")
+		assert.Equal(int64(9), decrypted, "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("approved ckks", func(t *testing.T) {
+		mean, err := client.Ckks.ArrayMean(ckksRows)
+		assert.NoError(err, "Error performing operation")
+
+		transcript := &he.ComputationTranscript{Operation: "ArrayMean", Inputs: ckksRows}
+		decrypted, err := he.SendVerifiableResultToServerCkks(ts.URL+"/decrypt_computations_ckks", mean, transcript)
+		assert.NoError(err, "Approved result was not decrypted")
+		assert.InDelta(1.5, decrypted, 1e-3, "Decrypted value is not within the allowed delta")
+	})
+
+	t.Run("stored row", func(t *testing.T) {
+		_, err := he.SendComputationResultToServerBfv(ts.URL+"/decrypt_computations_bfv", rows[0])
+		assert.Error(err, "Stored row was decrypted without transcript")
+
+		transcript := &he.ComputationTranscript{Operation: "ArraySum", Inputs: rows[:1]}
+		_, err = he.SendVerifiableResultToServerBfv(ts.URL+"/decrypt_computations_bfv", rows[0], transcript)
+		assert.Error(err, "Stored row was decrypted as a sum of a single row")
+	})
+
+	zero, _ := client.Bfv.Subtract(rows[1], rows[1])
+	padded, _ := client.Bfv.ArraySum([][]byte{rows[0], zero, zero})
+	repeated, _ := client.Bfv.ArraySum([][]byte{rows[0], rows[0], rows[0]})
+
+	deniedTests := []struct {
+		name       string
+		result     []byte
+		transcript *he.ComputationTranscript
+	}{
+		{"no transcript", sum, nil},
+		{"unknown operation", sum, &he.ComputationTranscript{Operation: "Mult", Inputs: rows[1:]}},
+		{"too few inputs", rows[0], &he.ComputationTranscript{Operation: "ArraySum", Inputs: rows[:1]}},
+		{"unregistered input", padded, &he.ComputationTranscript{Operation: "ArraySum", Inputs: [][]byte{rows[0], zero, zero}}},
+		{"repeated input", repeated, &he.ComputationTranscript{Operation: "ArraySum", Inputs: [][]byte{rows[0], rows[0], rows[0]}}},
+		{"result not matching transcript", rows[0], &he.ComputationTranscript{Operation: "ArraySum", Inputs: rows[1:]}},
+		{"wrong input", sum, &he.ComputationTranscript{Operation: "ArraySum", Inputs: [][]byte{rows[0], rows[1], {0x00, 0x00, 0x00}}}},
+	}
+
+	for _, currentTest := range deniedTests {
+		t.Run("denied "+currentTest.name, func(t *testing.T) {
+			err := policy.Authorize(server, currentTest.result, currentTest.transcript)
+			assert.ErrorIs(err, he.ErrNotApproved, "Result was approved")
+		})
+	}
+}
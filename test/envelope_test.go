@@ -0,0 +1,133 @@
+package test
+
+import (
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/SamBridgess/homomorphicEncryption/bfvMath"
+	"github.com/SamBridgess/homomorphicEncryption/ckksMath"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"github.com/ldsec/lattigo/v2/bfv"
+	"github.com/ldsec/lattigo/v2/ckks"
+	"github.com/ldsec/lattigo/v2/rlwe"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("round trip", func(t *testing.T) {
+		data, err := envelope.Wrap(envelope.SchemeBFV, 0x0102030405060708, "v1", []byte{0x01, 0x02})
+		assert.NoError(err)
+		assert.Equal(envelope.Magic, data[:len(envelope.Magic)], "Envelope doesn't start with magic bytes")
+
+		e, err := envelope.Unmarshal(data)
+		assert.NoError(err)
+		assert.Equal(envelope.Envelope{Scheme: envelope.SchemeBFV, ParamsHash: 0x0102030405060708, KeyID: "v1", Payload: []byte{0x01, 0x02}}, e)
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		e, err := envelope.Unmarshal([]byte{0x00, 0x00, 0x00})
+		assert.NoError(err)
+		assert.Equal(envelope.Envelope{Payload: []byte{0x00, 0x00, 0x00}}, e)
+
+		e, err = envelope.Unmarshal(append(append([]byte{}, envelope.Magic...), 1, 2, 'v', '1', 0x07))
+		assert.NoError(err)
+		assert.Equal(envelope.Envelope{KeyID: "v1", Payload: []byte{0x07}}, e)
+
+		_, err = envelope.Open(e.Payload, envelope.SchemeCKKS, 42)
+		assert.ErrorIs(err, envelope.ErrLegacy, "Legacy data was accepted without opt-in")
+
+		_, err = envelope.OpenLegacy(e.Payload, envelope.SchemeCKKS, 42)
+		assert.NoError(err, "Legacy data was rejected")
+
+		var schemeErr *envelope.SchemeMismatchError
+		data, _ := envelope.Wrap(envelope.SchemeBFV, 42, "", []byte{0x00})
+		_, err = envelope.OpenLegacy(data, envelope.SchemeCKKS, 42)
+		assert.ErrorAs(err, &schemeErr, "Scheme of enveloped data was not checked")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := envelope.Unmarshal(append(append([]byte{}, envelope.Magic...), 2, 1))
+		assert.ErrorIs(err, envelope.ErrMalformed, "Didn't get expected error")
+
+		_, err = envelope.Unmarshal(append(append([]byte{}, envelope.Magic...), 1, 5, 'v'))
+		assert.ErrorIs(err, envelope.ErrMalformed, "Didn't get expected error")
+
+		var versionErr *envelope.VersionError
+		_, err = envelope.Unmarshal(append(append([]byte{}, envelope.Magic...), 9))
+		assert.ErrorAs(err, &versionErr, "Didn't get expected error")
+
+		_, err = envelope.Wrap(envelope.SchemeCKKS, 0, string(make([]byte, 256)), nil)
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("open", func(t *testing.T) {
+		data, _ := envelope.Wrap(envelope.SchemeBFV, 1, "", []byte{0x00})
+
+		var schemeErr *envelope.SchemeMismatchError
+		_, err := envelope.Open(data, envelope.SchemeCKKS, 1)
+		assert.ErrorAs(err, &schemeErr, "Didn't get expected error")
+
+		var paramsErr *envelope.ParamsMismatchError
+		_, err = envelope.Open(data, envelope.SchemeBFV, 2)
+		assert.ErrorAs(err, &paramsErr, "Didn't get expected error")
+
+		_, err = envelope.Open(data, envelope.SchemeBFV, 1)
+		assert.NoError(err)
+	})
+}
+
+func TestEnvelopeMismatch(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContextWithRotations(t, 1)
+	encryptedCkks, _ := server.EncryptCKKS(1.0)
+	encryptedBfv, _ := server.EncryptBFV(1)
+
+	t.Run("scheme", func(t *testing.T) {
+		var schemeErr *envelope.SchemeMismatchError
+
+		_, err := server.Ckks.Sum(encryptedBfv, encryptedBfv)
+		assert.ErrorAs(err, &schemeErr, "BFV data was accepted by CKKS operation")
+
+		_, err = server.Bfv.Mult(encryptedCkks, encryptedCkks)
+		assert.ErrorAs(err, &schemeErr, "CKKS data was accepted by BFV operation")
+
+		_, err = server.DecryptCKKS(encryptedBfv)
+		assert.ErrorAs(err, &schemeErr, "BFV data was decrypted as CKKS")
+
+		_, err = server.DecryptBFV(encryptedCkks)
+		assert.ErrorAs(err, &schemeErr, "CKKS data was decrypted as BFV")
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		e, _ := envelope.Unmarshal(encryptedBfv)
+		_, err := server.DecryptBFV(e.Payload)
+		assert.ErrorIs(err, envelope.ErrLegacy, "Legacy data was decrypted without opt-in")
+		_, err = server.Bfv.Sum(e.Payload, e.Payload)
+		assert.ErrorIs(err, envelope.ErrLegacy, "Legacy data was accepted by BFV operation without opt-in")
+
+		config, _ := he.DefaultContextConfig()
+		config.AcceptLegacyCiphertexts = true
+		legacy, err := he.NewServerContextWithKeyStore(config, he.NewMemoryKeyStore())
+		assert.NoError(err, "Error creating context")
+
+		encrypted, _ := legacy.EncryptBFV(3)
+		e, _ = envelope.Unmarshal(encrypted)
+		decrypted, err := legacy.DecryptBFV(e.Payload)
+		assert.NoError(err, "Legacy data was rejected with opt-in")
+		assert.Equal(int64(3), decrypted, "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("params", func(t *testing.T) {
+		var paramsErr *envelope.ParamsMismatchError
+
+		ckksParams, _ := ckks.NewParametersFromLiteral(ckks.PN13QP218)
+		_, err := ckksMath.NewContext(ckksParams, rlwe.EvaluationKey{}).Sum(encryptedCkks, encryptedCkks)
+		assert.ErrorAs(err, &paramsErr, "Data of other params was accepted")
+
+		bfvParams, _ := bfv.NewParametersFromLiteral(bfv.PN13QP218)
+		_, err = bfvMath.NewContext(bfvParams, rlwe.EvaluationKey{}).Sum(encryptedBfv, encryptedBfv)
+		assert.ErrorAs(err, &paramsErr, "Data of other params was accepted")
+	})
+}
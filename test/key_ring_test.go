@@ -0,0 +1,218 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/SamBridgess/homomorphicEncryption/envelope"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestKeyRing(t *testing.T, store he.KeyStore, activeKeyID string, retiredKeyIDs ...string) *he.KeyRing {
+	config, err := he.DefaultContextConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.RotationSlots = 1
+
+	ring, err := he.NewKeyRing(config, store, activeKeyID, retiredKeyIDs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	assert := assert.New(t)
+
+	store := he.NewMemoryKeyStore()
+	ring := newTestKeyRing(t, store, "v1")
+
+	oldCkks, _ := ring.EncryptCKKSVector([]float64{1.5})
+	oldBfv, _ := ring.EncryptBFVVector([]int64{7})
+	keyID, _ := envelope.KeyID(oldCkks)
+	assert.Equal("v1", keyID, "Ciphertext doesn't carry key ID")
+
+	assert.NoError(ring.Rotate("v2"), "Error rotating keys")
+	assert.Equal([]he.KeyVersion{{ID: "v1", Status: he.KeyRetired}, {ID: "v2", Status: he.KeyActive}}, ring.Versions())
+
+	t.Run("decrypt retired", func(t *testing.T) {
+		decrypted, err := ring.DecryptCKKSVector(oldCkks)
+		assert.NoError(err, "Error decrypting original data")
+		assert.InDelta(1.5, decrypted[0], 1e-3, "Decrypted value is not within the allowed delta")
+
+		decryptedBfv, err := ring.DecryptBFVVector(oldBfv)
+		assert.NoError(err, "Error decrypting original data")
+		assert.Equal(int64(7), decryptedBfv[0], "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("re-encrypt", func(t *testing.T) {
+		newCkks, err := ring.ReEncryptCKKS(oldCkks)
+		assert.NoError(err, "Error re-encrypting data")
+		keyID, _ := envelope.KeyID(newCkks)
+		assert.Equal("v2", keyID, "Data is not re-encrypted with active key")
+
+		decrypted, _ := ring.Active().DecryptCKKS(newCkks)
+		assert.InDelta(1.5, decrypted, 1e-3, "Decrypted value is not within the allowed delta")
+
+		newBfv, err := ring.ReEncryptBFV(oldBfv)
+		assert.NoError(err, "Error re-encrypting data")
+		decryptedBfv, _ := ring.Active().DecryptBFV(newBfv)
+		assert.Equal(int64(7), decryptedBfv, "Decrypted value is not equal to expected value")
+
+		same, _ := ring.ReEncryptBFV(newBfv)
+		assert.Equal(newBfv, same, "Data encrypted with active key was re-encrypted")
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		reloaded := newTestKeyRing(t, store, "v2", "v1")
+		decrypted, err := reloaded.DecryptBFVVector(oldBfv)
+		assert.NoError(err, "Error decrypting original data")
+		assert.Equal(int64(7), decrypted[0], "Decrypted value is not equal to expected value")
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		assert.Error(ring.Remove("v2"), "Active key version was removed")
+		assert.NoError(ring.Remove("v1"), "Error removing key version")
+
+		_, err := ring.DecryptCKKSVector(oldCkks)
+		assert.ErrorIs(err, he.ErrUnknownKey, "Didn't get expected error")
+
+		names, _ := store.List()
+		assert.Equal([]string{"v2-bfv", "v2-ckks"}, names, "Keys of removed version are still stored")
+	})
+}
+
+func TestKeyRingLegacyKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	config, _ := he.DefaultContextConfig()
+	config.RotationSlots = 1
+	store := he.NewMemoryKeyStore()
+
+	legacy, err := he.NewServerContextWithKeyStore(config, store)
+	assert.NoError(err, "Error creating context")
+	legacyData, _ := legacy.EncryptBFV(3)
+
+	ring := newTestKeyRing(t, store, "v1", "")
+	job := he.ReEncryptionJob{Ring: ring, BfvColumns: []string{"value"}}
+
+	row := [][]byte{legacyData}
+	changed, err := job.ReEncryptRow(row)
+	assert.NoError(err, "Error re-encrypting row")
+	assert.Equal(1, changed, "Row was not re-encrypted")
+
+	decrypted, err := ring.Active().DecryptBFV(row[0])
+	assert.NoError(err, "Error decrypting re-encrypted data")
+	assert.Equal(int64(3), decrypted, "Decrypted value is not equal to expected value")
+
+	changed, _ = job.ReEncryptRow(row)
+	assert.Equal(0, changed, "Row encrypted with active key was re-encrypted")
+
+	changed, _ = job.ReEncryptRow([][]byte{nil})
+	assert.Equal(0, changed, "NULL value was re-encrypted")
+}
+
+func TestKeyMismatch(t *testing.T) {
+	assert := assert.New(t)
+
+	ring := newTestKeyRing(t, he.NewMemoryKeyStore(), "v1")
+	old, _ := ring.EncryptBFVVector([]int64{1})
+	assert.NoError(ring.Rotate("v2"))
+	current, _ := ring.EncryptBFVVector([]int64{2})
+
+	_, err := ring.Active().Bfv.Sum(old, current)
+	var mismatch *envelope.KeyMismatchError
+	assert.ErrorAs(err, &mismatch, "Ciphertexts of different key versions were combined")
+
+	_, err = ring.Active().DecryptBFV(old)
+	assert.ErrorAs(err, &mismatch, "Data was decrypted with another key version")
+
+	// computations of clients without key ID keep the key ID of operands
+	client := he.NewClientContext(ring.Active().CkksParams, ring.Active().BfvParams, ring.Active().EvalKeysCkks.EvalKey1, ring.Active().EvalKeysBfv.EvalKey1)
+	sum, err := client.Bfv.Sum(current, current)
+	assert.NoError(err, "Error performing operation")
+	keyID, _ := envelope.KeyID(sum)
+	assert.Equal("v2", keyID, "Result doesn't carry key ID of operands")
+}
+
+func TestKeyRingRouter(t *testing.T) {
+	assert := assert.New(t)
+
+	ring := newTestKeyRing(t, he.NewMemoryKeyStore(), "v1")
+	old, _ := ring.EncryptBFVVector([]int64{5})
+	assert.NoError(ring.Rotate("v2"))
+	current, _ := ring.EncryptBFVVector([]int64{6})
+
+	router := he.NewKeyRingRouter(ring)
+	decrypt := func(data []byte) (int, he.DecryptedResultResponseInt) {
+		body, _ := json.Marshal(map[string][]byte{"encrypted_result": data})
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/decrypt_computations_bfv", bytes.NewReader(body)))
+
+		var response he.DecryptedResultResponseInt
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response
+	}
+
+	code, response := decrypt(old)
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(int64(5), response.DecryptedResult, "Request is not routed to retired key")
+
+	code, response = decrypt(current)
+	assert.Equal(http.StatusOK, code)
+	assert.Equal(int64(6), response.DecryptedResult, "Request is not routed to active key")
+
+	unknown, _ := envelope.Wrap(envelope.SchemeBFV, 0, "v3", []byte{0x00, 0x00, 0x00})
+	code, _ = decrypt(unknown)
+	assert.Equal(http.StatusBadRequest, code, "Didn't get expected error")
+
+	for keyID, expectedCode := range map[string]int{"v1": http.StatusOK, "v3": http.StatusNotFound} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/get_bfv_eval_keys?key_id="+keyID, nil))
+		assert.Equal(expectedCode, recorder.Code, "Unexpected status for key ID "+keyID)
+	}
+}
+
+// blockingKeyStore blocks saving keys until release is closed
+type blockingKeyStore struct {
+	he.KeyStore
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (store *blockingKeyStore) Save(name string, keys he.KeyPair) error {
+	select {
+	case store.saving <- struct{}{}:
+	default:
+	}
+	<-store.release
+	return store.KeyStore.Save(name, keys)
+}
+
+func TestKeyRingRotateWithoutBlocking(t *testing.T) {
+	assert := assert.New(t)
+
+	memory := he.NewMemoryKeyStore()
+	ring := newTestKeyRing(t, memory, "v1")
+	encrypted, _ := ring.EncryptBFVVector([]int64{7})
+
+	store := &blockingKeyStore{KeyStore: memory, saving: make(chan struct{}, 1), release: make(chan struct{})}
+	blocked := newTestKeyRing(t, store, "v1")
+
+	rotated := make(chan error, 1)
+	go func() { rotated <- blocked.Rotate("v2") }()
+	<-store.saving
+
+	decrypted, err := blocked.DecryptBFVVector(encrypted)
+	assert.NoError(err, "Error decrypting during rotation")
+	assert.Equal(int64(7), decrypted[0], "Decrypted value is not equal to expected value")
+	assert.Equal("v1", blocked.Active().KeyID, "Key version activated before its keys were saved")
+
+	close(store.release)
+	assert.NoError(<-rotated, "Error rotating keys")
+	assert.Equal("v2", blocked.Active().KeyID, "Key version not activated after rotation")
+}
@@ -0,0 +1,165 @@
+package test
+
+import (
+	"encoding/json"
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestKeyStores(t *testing.T) map[string]he.KeyStore {
+	fileStore, err := he.NewFileKeyStore(filepath.Join(t.TempDir(), "keys"), []byte("passphrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]he.KeyStore{
+		"memory": he.NewMemoryKeyStore(),
+		"file":   fileStore,
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := newTestServerContextWithRotations(t, 1)
+
+	for storeName, store := range newTestKeyStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			names, err := store.List()
+			assert.NoError(err, "Error listing keys")
+			assert.Empty(names, "New key store is not empty")
+
+			_, err = store.Load("ckks")
+			assert.ErrorIs(err, he.ErrKeysNotFound, "Didn't get expected error")
+
+			assert.NoError(store.Save("ckks", ctx.CkksKeys), "Error saving keys")
+			assert.NoError(store.Save("bfv", ctx.BfvKeys), "Error saving keys")
+
+			loaded, err := store.Load("ckks")
+			assert.NoError(err, "Error loading keys")
+			assert.True(ctx.CkksKeys.Sk.Value.Equals(loaded.Sk.Value), "Loaded secret key differs from saved one")
+			assert.True(ctx.CkksKeys.Pk.Equals(loaded.Pk), "Loaded public key differs from saved one")
+
+			names, err = store.List()
+			assert.NoError(err, "Error listing keys")
+			assert.Equal([]string{"bfv", "ckks"}, names, "Listed names differ from saved ones")
+
+			assert.NoError(store.Delete("bfv"), "Error deleting keys")
+			assert.ErrorIs(store.Delete("bfv"), he.ErrKeysNotFound, "Didn't get expected error")
+			names, _ = store.List()
+			assert.Equal([]string{"ckks"}, names, "Deleted keys are still listed")
+
+			assert.Error(store.Save("../ckks", ctx.CkksKeys), "Didn't get expected error")
+			assert.Error(store.Save("", ctx.CkksKeys), "Didn't get expected error")
+		})
+	}
+}
+
+func TestFileKeyStore(t *testing.T) {
+	assert := assert.New(t)
+
+	ctx := newTestServerContextWithRotations(t, 1)
+	dir := filepath.Join(t.TempDir(), "keys")
+
+	store, err := he.NewFileKeyStore(dir, []byte("passphrase"))
+	assert.NoError(err, "Error creating key store")
+	assert.NoError(store.Save("ckks", ctx.CkksKeys), "Error saving keys")
+
+	t.Run("permissions", func(t *testing.T) {
+		info, err := os.Stat(dir)
+		assert.NoError(err)
+		assert.Equal(os.FileMode(0700), info.Mode().Perm(), "Key store directory is accessible by others")
+
+		info, err = os.Stat(filepath.Join(dir, "ckks.keys"))
+		assert.NoError(err)
+		assert.Equal(os.FileMode(0600), info.Mode().Perm(), "Key file is accessible by others")
+
+		shared := filepath.Join(t.TempDir(), "shared")
+		assert.NoError(os.Mkdir(shared, 0755))
+		assert.NoError(os.Chmod(shared, 0755))
+		_, err = he.NewFileKeyStore(shared, []byte("passphrase"))
+		assert.NoError(err, "Error creating key store")
+		info, err = os.Stat(shared)
+		assert.NoError(err)
+		assert.Equal(os.FileMode(0700), info.Mode().Perm(), "Existing key store directory left accessible by others")
+	})
+
+	t.Run("reopen", func(t *testing.T) {
+		reopened, err := he.NewFileKeyStore(dir, []byte("passphrase"))
+		assert.NoError(err, "Error creating key store")
+
+		loaded, err := reopened.Load("ckks")
+		assert.NoError(err, "Error loading keys")
+		assert.True(ctx.CkksKeys.Sk.Value.Equals(loaded.Sk.Value), "Loaded secret key differs from saved one")
+	})
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		wrong, err := he.NewFileKeyStore(dir, []byte("wrong passphrase"))
+		assert.NoError(err, "Error creating key store")
+
+		_, err = wrong.Load("ckks")
+		assert.ErrorIs(err, he.ErrWrongPassphrase, "Didn't get expected error")
+	})
+
+	t.Run("swapped file", func(t *testing.T) {
+		data, _ := os.ReadFile(filepath.Join(dir, "ckks.keys"))
+		assert.NoError(os.WriteFile(filepath.Join(dir, "bfv.keys"), data, 0600))
+
+		_, err := store.Load("bfv")
+		assert.ErrorIs(err, he.ErrWrongPassphrase, "Didn't get expected error")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		_, err := he.NewFileKeyStore(dir, nil)
+		assert.Error(err, "Didn't get expected error")
+
+		assert.NoError(os.WriteFile(filepath.Join(dir, "broken.keys"), []byte{0x00, 0x00, 0x00}, 0600))
+		_, err = store.Load("broken")
+		assert.Error(err, "Didn't get expected error")
+	})
+
+	t.Run("expensive scrypt parameters", func(t *testing.T) {
+		data, _ := os.ReadFile(filepath.Join(dir, "ckks.keys"))
+		for _, field := range []string{"n", "r", "p"} {
+			var file map[string]any
+			assert.NoError(json.Unmarshal(data, &file))
+			file[field] = 1 << 30
+			tampered, _ := json.Marshal(file)
+			assert.NoError(os.WriteFile(filepath.Join(dir, "tampered.keys"), tampered, 0600))
+
+			start := time.Now()
+			_, err := store.Load("tampered")
+			assert.ErrorContains(err, "unsupported scrypt parameters", "Didn't get expected error")
+			assert.Less(time.Since(start), time.Second, "Key derivation ran with tampered parameters")
+		}
+	})
+}
+
+func TestContextWithKeyStore(t *testing.T) {
+	assert := assert.New(t)
+
+	config, _ := he.DefaultContextConfig()
+	config.RotationSlots = 1
+	store := he.NewMemoryKeyStore()
+
+	ctx1, err := he.NewServerContextWithKeyStore(config, store)
+	assert.NoError(err, "Error creating context")
+	ctx2, err := he.NewServerContextWithKeyStore(config, store)
+	assert.NoError(err, "Error creating context")
+
+	names, _ := store.List()
+	assert.Equal([]string{"bfv", "ckks"}, names, "Generated keys are not saved")
+
+	encrypted, _ := ctx1.EncryptCKKS(42.0)
+	decrypted, err := ctx2.DecryptCKKS(encrypted)
+	assert.NoError(err, "Error decrypting original data")
+	assert.InDelta(42.0, decrypted, 1e-3, "Keys were not reused from key store")
+
+	encryptedBfv, _ := ctx1.EncryptBFV(42)
+	decryptedBfv, err := ctx2.DecryptBFV(encryptedBfv)
+	assert.NoError(err, "Error decrypting original data")
+	assert.Equal(int64(42), decryptedBfv, "Keys were not reused from key store")
+}
@@ -1,7 +1,9 @@
 package test
 
 import (
+	"encoding/json"
 	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/ldsec/lattigo/v2/rlwe"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"testing"
@@ -19,3 +21,25 @@ func TestKeysLoad(t *testing.T) {
 	err = os.Remove("bfvKeys.json")
 	assert.NoError(err, "Error deleting bfvKeys.json")
 }
+
+func TestEvalKeysLegacyJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	keys := he.EvalKeysBfv
+	legacy, err := json.Marshal(struct {
+		EvalKey1 rlwe.EvaluationKey
+	}{keys.EvalKey1})
+	assert.NoError(err, "Error encoding eval keys in legacy format")
+
+	var decoded he.EvalKeys
+	assert.NoError(json.Unmarshal(legacy, &decoded), "Error decoding eval keys in legacy format")
+	assert.True(keys.EvalKey1.Rlk.Equals(decoded.EvalKey1.Rlk), "Decoded relinearization key is not equal to encoded key")
+
+	current, err := json.Marshal(keys)
+	assert.NoError(err, "Error encoding eval keys")
+	decoded = he.EvalKeys{}
+	assert.NoError(json.Unmarshal(current, &decoded), "Error decoding eval keys")
+	assert.True(keys.EvalKey1.Rlk.Equals(decoded.EvalKey1.Rlk), "Decoded relinearization key is not equal to encoded key")
+
+	assert.Error(json.Unmarshal([]byte(`{"version": 99}`), &decoded), "Unknown format version accepted")
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerRoutePrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+
+	var requests int
+	countRequests := func(c *gin.Context) { requests++ }
+
+	mux := http.NewServeMux()
+	mux.Handle("/he/v1/", he.NewServer(server, he.WithRoutePrefix("/he/v1"), he.WithMiddleware(countRequests)))
+	ts := httptest.NewTLSServer(mux)
+	defer ts.Close()
+
+	httpsServer := he.HttpsServer
+	he.HttpsServer = ts.Client()
+	defer func() { he.HttpsServer = httpsServer }()
+
+	_, err := he.GetBFVParamsFromServer(ts.URL + "/he/v1/get_bfv_params")
+	assert.NoError(err, "Error requesting params under route prefix")
+
+	encrypted, _ := server.EncryptBFV(42)
+	decrypted, err := he.SendComputationResultToServerBfv(ts.URL+"/he/v1/decrypt_computations_bfv", encrypted)
+	assert.NoError(err, "Error decrypting under route prefix")
+	assert.Equal(int64(42), decrypted, "Decrypted value is not equal to expected value")
+	assert.Equal(2, requests, "Middleware did not run for every request")
+
+	_, err = he.GetBFVParamsFromServer(ts.URL + "/get_bfv_params")
+	assert.Error(err, "Route served without prefix")
+}
+
+func TestServerMaxBodyBytes(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	ts := httptest.NewTLSServer(he.NewServer(server, he.WithMaxBodyBytes(1024)))
+	defer ts.Close()
+
+	body := `{"encrypted_result": "` + string(bytes.Repeat([]byte("A"), 2048)) + `"}`
+	resp, err := ts.Client().Post(ts.URL+"/decrypt_computations_ckks", "application/json", bytes.NewBufferString(body))
+	assert.NoError(err, "Error sending request")
+	defer resp.Body.Close()
+	assert.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode, "Request exceeding body limit accepted")
+
+	resp, err = ts.Client().Post(ts.URL+"/decrypt_computations_ckks", "application/json", bytes.NewBufferString(`{"encrypted_result": "AAAA"}`))
+	assert.NoError(err, "Error sending request")
+	defer resp.Body.Close()
+	assert.NotEqual(http.StatusRequestEntityTooLarge, resp.StatusCode, "Request within body limit rejected")
+}
+
+func TestServerStartShutdown(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContext(t)
+	_, _, certFile, keyFile := writeTestCertificate(t, t.TempDir(), "127.0.0.1", nil, nil)
+
+	t.Run("no certificate", func(t *testing.T) {
+		listener, _ := net.Listen("tcp", "127.0.0.1:0")
+		assert.Error(he.NewServer(server).Serve(listener), "Server started without certificate")
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(err, "Error listening")
+
+		heServer := he.NewServer(server, he.WithCertificate(certFile, keyFile))
+		served := make(chan error, 1)
+		go func() { served <- heServer.Serve(listener) }()
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+		resp, err := client.Get("https://" + listener.Addr().String() + "/get_ckks_params")
+		assert.NoError(err, "Error requesting params")
+		resp.Body.Close()
+		assert.Equal(http.StatusOK, resp.StatusCode, "Params request failed")
+
+		assert.NoError(heServer.Shutdown(context.Background()), "Error shutting down")
+		assert.NoError(<-served, "Serve did not return nil after Shutdown")
+	})
+}
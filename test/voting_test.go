@@ -0,0 +1,152 @@
+package test
+
+import (
+	"context"
+	"errors"
+	he "github.com/SamBridgess/homomorphicEncryption"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVoting(t *testing.T) {
+	assert := assert.New(t)
+
+	server := newTestServerContextWithRotations(t, 4)
+	secret := []byte("0123456789abcdef")
+	ctx := context.Background()
+
+	poll, err := he.NewPoll(server, "board", 3, secret)
+	assert.NoError(err, "Error creating poll")
+
+	policy := he.NewDecryptionPolicy()
+	serverPoll, err := he.NewPoll(server, "board", 3, secret)
+	assert.NoError(err, "Error creating poll")
+	serverPoll.Approve(policy, 3)
+
+	// voters hold only the public key
+	voter := &he.Context{BfvParams: server.BfvParams, BfvKeys: he.NewKeyPair(nil, server.BfvKeys.Pk), KeyID: server.KeyID}
+	encryptor, err := he.NewBallotEncryptor(voter, 3)
+	assert.NoError(err, "Error creating ballot encryptor")
+
+	ts := httptest.NewTLSServer(he.NewRouter(server))
+	defer ts.Close()
+	policyTs := httptest.NewTLSServer(he.NewRouter(server, he.WithDecryptionPolicy(policy)))
+	defer policyTs.Close()
+
+	clients := []struct {
+		name   string
+		client *he.Client
+	}{
+		{"without policy", newTestClient(t, ts, "")},
+		{"with policy", newTestClient(t, policyTs, "")},
+	}
+
+	for _, currentClient := range clients {
+		t.Run("tally "+currentClient.name, func(t *testing.T) {
+			var ballots [][]byte
+			for _, choice := range []int{0, 2, 2, 1, 2} {
+				ballot, err := encryptor.EncryptBallot(choice)
+				assert.NoError(err, "Error encrypting ballot")
+				policy.RegisterInputs(ballot)
+				assert.NoError(poll.ValidateBallot(ctx, currentClient.client, ballot), "Valid ballot rejected")
+				ballots = append(ballots, ballot)
+			}
+
+			totals, err := poll.DecryptTotals(ctx, currentClient.client, ballots)
+			assert.NoError(err, "Error decrypting totals")
+			assert.Equal([]int64{1, 1, 3}, totals, "Decrypted totals are not equal to expected totals")
+		})
+	}
+
+	invalidBallots := []struct {
+		name   string
+		ballot []int64
+	}{
+		{"no vote", []int64{0, 0, 0}},
+		{"two votes", []int64{1, 1, 0}},
+		{"weighted vote", []int64{0, 2, 0}},
+		{"negative vote", []int64{2, -1, 0}},
+	}
+
+	for _, currentTest := range invalidBallots {
+		t.Run("invalid ballot "+currentTest.name, func(t *testing.T) {
+			ballot, _ := server.EncryptBFVVector(currentTest.ballot)
+			policy.RegisterInputs(ballot)
+			for _, currentClient := range clients {
+				assert.ErrorIs(poll.ValidateBallot(ctx, currentClient.client, ballot), he.ErrInvalidBallot, "Invalid ballot accepted")
+			}
+		})
+	}
+
+	t.Run("policy", func(t *testing.T) {
+		client := clients[1].client
+		var serverErr *he.ServerError
+
+		ballot, _ := poll.EncryptBallot(0)
+		err := poll.ValidateBallot(ctx, client, ballot)
+		assert.True(errors.As(err, &serverErr) && serverErr.StatusCode == http.StatusForbidden, "Unregistered ballot wasn't refused")
+
+		otherPoll, _ := he.NewPoll(server, "board", 3, []byte("fedcba9876543210"))
+		policy.RegisterInputs(ballot)
+		err = otherPoll.ValidateBallot(ctx, client, ballot)
+		assert.True(errors.As(err, &serverErr) && serverErr.StatusCode == http.StatusForbidden, "Check with other weights wasn't refused")
+
+		_, err = poll.DecryptTotals(ctx, client, [][]byte{ballot})
+		assert.True(errors.As(err, &serverErr) && serverErr.StatusCode == http.StatusForbidden, "Totals of too few ballots weren't refused")
+
+		// an invalid ballot registered but never validated must not be counted
+		ballots := [][]byte{ballot}
+		for _, choice := range []int{1, 2} {
+			valid, _ := encryptor.EncryptBallot(choice)
+			policy.RegisterInputs(valid)
+			ballots = append(ballots, valid)
+		}
+		invalid, _ := server.EncryptBFVVector([]int64{0, 5, 0})
+		policy.RegisterInputs(invalid)
+		_, err = poll.DecryptTotals(ctx, client, append(ballots, invalid))
+		assert.True(errors.As(err, &serverErr) && serverErr.StatusCode == http.StatusForbidden, "Totals over an invalid ballot weren't refused")
+
+		totals, err := poll.DecryptTotals(ctx, client, ballots)
+		assert.NoError(err, "Error decrypting totals")
+		assert.Equal([]int64{1, 1, 1}, totals, "Decrypted totals are not equal to expected totals")
+	})
+
+	t.Run("wrong input", func(t *testing.T) {
+		wrongInput := []byte{0x00, 0x00, 0x00}
+
+		_, err := he.NewPoll(server, "board", 0, secret)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = he.NewPoll(server, "", 3, secret)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = he.NewPoll(server, "board", 3, secret[:8])
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = poll.EncryptBallot(3)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = encryptor.EncryptBallot(-1)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = he.NewBallotEncryptor(voter, 0)
+		assert.Error(err, "Didn't get expected error")
+
+		ballot, _ := encryptor.EncryptBallot(0)
+		_, err = voter.DecryptBFV(ballot)
+		assert.Error(err, "Voter decrypted a ballot")
+
+		_, err = poll.CheckBallot(wrongInput)
+		assert.Error(err, "Didn't get expected error")
+
+		_, err = poll.Tally([][]byte{})
+		assert.Error(err, "Didn't get expected error")
+
+		ballot, _ = poll.EncryptBallot(0)
+		err = poll.ValidateBallot(ctx, newTestClient(t, ts, "/missing"), ballot)
+		assert.Error(err, "Didn't get expected error, server rejected the check")
+		assert.NotErrorIs(err, he.ErrInvalidBallot)
+	})
+}
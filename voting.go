@@ -0,0 +1,358 @@
+package homomorphicEncryption
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// Ballots of a Poll are one-hot vectors packed into a single BFV ciphertext: slot i holds 1
+// for the chosen option i and 0 for all other options. Ballots are checked homomorphically,
+// summed into an encrypted tally, and only totals of the tally are decrypted by the server
+// holding the secret key, through the same decryption endpoints as other BFV results.
+// Checks and totals are sent with transcripts, so a server with a DecryptionPolicy decrypts
+// them once Poll.Approve approved them. Voters encrypt ballots with a BallotEncryptor, which
+// needs only the public key
+
+// ErrInvalidBallot Returned for ballots that aren't votes for exactly one option of a Poll
+var ErrInvalidBallot = errors.New("ballot must be a vote for exactly one option")
+
+// minPollSecretLength Minimum length of the secret of a Poll in bytes
+const minPollSecretLength = 16
+
+// Poll Encrypted poll with Options options. Encrypting ballots requires ctx with the public key,
+// checking ballots requires the relinearization key, and checking ballots and extracting totals
+// require rotation keys for powers of two below Options
+type Poll struct {
+	ID      string
+	Options int
+
+	ctx    *Context
+	secret []byte
+
+	mu sync.Mutex
+	// valid digests of ballots whose checks the server decrypted to 0, see Approve
+	valid map[[sha256.Size]byte]struct{}
+}
+
+// BallotEncryptor Encrypts ballots of a poll for voters, who hold neither the secret key
+// nor the secret of the poll
+type BallotEncryptor struct {
+	Options int
+
+	ctx *Context
+}
+
+// NewPoll Creates a Poll with options options, whose ballots are encrypted and tallied with ctx.
+// id names the computations Approve approves, and secret of at least 16 bytes derives weights
+// of ballot checks. The server creates the same Poll, with the same id, options and secret, to
+// approve them, and voters must not know secret, otherwise they could forge invalid ballots
+// passing the check
+func NewPoll(ctx *Context, id string, options int, secret []byte) (*Poll, error) {
+	if id == "" {
+		return nil, errors.New("poll must have an ID")
+	}
+	if err := checkOptions(ctx, options); err != nil {
+		return nil, err
+	}
+	if len(secret) < minPollSecretLength {
+		return nil, fmt.Errorf("poll secret must be at least %d bytes", minPollSecretLength)
+	}
+	return &Poll{
+		ID:      id,
+		Options: options,
+		ctx:     ctx,
+		secret:  append([]byte{}, secret...),
+		valid:   make(map[[sha256.Size]byte]struct{}),
+	}, nil
+}
+
+// NewBallotEncryptor Creates a BallotEncryptor for a poll with options options. ctx needs only
+// the BFV parameters and public key of the server, e.g.
+// &Context{BfvParams: params, BfvKeys: NewKeyPair(nil, pk), KeyID: keyID}
+func NewBallotEncryptor(ctx *Context, options int) (*BallotEncryptor, error) {
+	if err := checkOptions(ctx, options); err != nil {
+		return nil, err
+	}
+	return &BallotEncryptor{Options: options, ctx: ctx}, nil
+}
+
+// EncryptBallot Encrypts a vote for option choice, counting from 0
+func (encryptor *BallotEncryptor) EncryptBallot(choice int) ([]byte, error) {
+	if choice < 0 || choice >= encryptor.Options {
+		return nil, fmt.Errorf("choice must be between 0 and %d", encryptor.Options-1)
+	}
+
+	ballot := make([]int64, encryptor.Options)
+	ballot[choice] = 1
+	return encryptor.ctx.EncryptBFVVector(ballot)
+}
+
+// Approve Approves decryption of ballot checks and totals of poll by policy, totals only over
+// at least minBallots ballots. Ballots must be registered with policy.RegisterInputs before they
+// are validated and tallied. Totals are only approved if every ballot is valid: the server
+// recomputes the check of every ballot and decrypts it itself, so poll must be created with
+// the Context holding the secret key
+func (poll *Poll) Approve(policy *DecryptionPolicy, minBallots int) {
+	policy.Approve(poll.checkOperation(), ApprovedComputation{
+		MinInputs: 1,
+		Compute: func(ctx *Context, inputs [][]byte) ([]byte, error) {
+			if len(inputs) != 1 {
+				return nil, errors.New("ballot check takes a single ballot")
+			}
+			return poll.checkBallot(ctx, inputs[0])
+		},
+	})
+
+	for option := 0; option < poll.Options; option++ {
+		policy.Approve(poll.totalOperation(option), ApprovedComputation{
+			MinInputs: minBallots,
+			Compute: func(ctx *Context, inputs [][]byte) ([]byte, error) {
+				if err := poll.verifyBallots(ctx, inputs); err != nil {
+					return nil, err
+				}
+
+				tally, err := ctx.Bfv.ArraySum(inputs)
+				if err != nil {
+					return nil, err
+				}
+				return poll.total(ctx, tally, option)
+			},
+		})
+	}
+}
+
+// EncryptBallot Encrypts a vote for option choice, counting from 0. Voters use
+// BallotEncryptor.EncryptBallot instead
+func (poll *Poll) EncryptBallot(choice int) ([]byte, error) {
+	encryptor := &BallotEncryptor{Options: poll.Options, ctx: poll.ctx}
+	return encryptor.EncryptBallot(choice)
+}
+
+// CheckBallot Returns encrypted data containing 0 in slot 0 when decrypted if ballot is a vote
+// for exactly one option, and a pseudorandom value otherwise. Every slot v of the ballot must
+// satisfy v^2 - v = 0, i.e. be 0 or 1, and the slots must sum up to 1. The check combines these
+// constraints with weights derived from ballot and the secret of poll, so violations don't cancel
+// out, except with probability 1/t for the plaintext modulus t, and decrypted checks of invalid
+// ballots reveal nothing about their content to anyone not knowing the secret
+func (poll *Poll) CheckBallot(ballot []byte) ([]byte, error) {
+	return poll.checkBallot(poll.ctx, ballot)
+}
+
+// ValidateBallot Checks ballot with CheckBallot and decrypts the check with client, sending the
+// transcript the server needs to approve it. Returns ErrInvalidBallot if ballot isn't a vote
+// for exactly one option
+func (poll *Poll) ValidateBallot(ctx context.Context, client *Client, ballot []byte) error {
+	check, err := poll.CheckBallot(ballot)
+	if err != nil {
+		return err
+	}
+
+	transcript := &ComputationTranscript{Operation: poll.checkOperation(), Inputs: [][]byte{ballot}}
+	decrypted, err := client.DecryptVerifiableBfv(ctx, check, transcript)
+	if err != nil {
+		return err
+	}
+	if decrypted != 0 {
+		return ErrInvalidBallot
+	}
+	return nil
+}
+
+// Tally Returns the encrypted tally of ballots, slot i holding the number of votes for option i.
+// Ballots must be validated before they are tallied
+func (poll *Poll) Tally(ballots [][]byte) ([]byte, error) {
+	return poll.ctx.Bfv.ArraySum(ballots)
+}
+
+// Totals Returns encrypted totals of tally, one per option, each in slot 0 of its ciphertext,
+// so that they can be decrypted without revealing anything else
+func (poll *Poll) Totals(tally []byte) ([][]byte, error) {
+	totals := make([][]byte, poll.Options)
+	for i := range totals {
+		var err error
+		totals[i], err = poll.total(poll.ctx, tally, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return totals, nil
+}
+
+// DecryptTotals Tallies validated ballots and decrypts totals of the tally with client in
+// a single batch request, sending the transcripts the server needs to approve them.
+// Returns the number of votes for every option
+func (poll *Poll) DecryptTotals(ctx context.Context, client *Client, ballots [][]byte) ([]int64, error) {
+	tally, err := poll.Tally(ballots)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := poll.Totals(tally)
+	if err != nil {
+		return nil, err
+	}
+
+	transcripts := make([]*ComputationTranscript, len(totals))
+	for i := range transcripts {
+		transcripts[i] = &ComputationTranscript{Operation: poll.totalOperation(i), Inputs: ballots}
+	}
+
+	results, errs, err := client.DecryptVerifiableBatchBfv(ctx, totals, transcripts)
+	if err != nil {
+		return nil, err
+	}
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("total of option %d: %w", i, err)
+		}
+	}
+	return results, nil
+}
+
+// checkBallot returns the check of ballot computed with keys of ctx, see CheckBallot
+func (poll *Poll) checkBallot(ctx *Context, ballot []byte) ([]byte, error) {
+	ct, err := ctx.Bfv.Unmarshal(ballot)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := poll.weights(ctx, ballot)
+	weight, squareWeights := weights[0], weights[1:]
+
+	// the sum of squareWeights[i] * (v[i]^2 - v[i]) and weight * (sum of v[i] - 1) is computed
+	// as the sum of squareWeights[i] * v[i]^2 + (weight - squareWeights[i]) * v[i] minus weight
+	linearWeights := make([]int64, poll.Options)
+	for i := range linearWeights {
+		linearWeights[i] = weight - squareWeights[i]
+	}
+
+	square, err := ct.Mult(ct)
+	if err != nil {
+		return nil, err
+	}
+
+	square, err = square.Relinearize()
+	if err != nil {
+		return nil, err
+	}
+
+	square, err = square.MultByVector(squareWeights)
+	if err != nil {
+		return nil, err
+	}
+
+	linear, err := ct.MultByVector(linearWeights)
+	if err != nil {
+		return nil, err
+	}
+
+	check, err := square.Sum(linear)
+	if err != nil {
+		return nil, err
+	}
+
+	check, err = check.SlotSum(poll.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	check, err = check.SubtractConst(weight)
+	if err != nil {
+		return nil, err
+	}
+	return check.Marshal()
+}
+
+// verifyBallots returns an error wrapping ErrInvalidBallot unless checks of all ballots, computed
+// with keys of ctx, decrypt to 0. Valid ballots are remembered, so totals of all options check
+// every ballot once
+func (poll *Poll) verifyBallots(ctx *Context, ballots [][]byte) error {
+	poll.mu.Lock()
+	defer poll.mu.Unlock()
+
+	for i, ballot := range ballots {
+		digest := sha256.Sum256(ballot)
+		if _, ok := poll.valid[digest]; ok {
+			continue
+		}
+
+		check, err := poll.checkBallot(ctx, ballot)
+		if err != nil {
+			return err
+		}
+
+		decrypted, err := ctx.DecryptBFV(check)
+		if err != nil {
+			return err
+		}
+		if decrypted != 0 {
+			return fmt.Errorf("ballot %d: %w", i, ErrInvalidBallot)
+		}
+		poll.valid[digest] = struct{}{}
+	}
+	return nil
+}
+
+// total returns the total of option of tally in slot 0, computed with keys of ctx
+func (poll *Poll) total(ctx *Context, tally []byte, option int) ([]byte, error) {
+	ct, err := ctx.Bfv.Unmarshal(tally)
+	if err != nil {
+		return nil, err
+	}
+
+	// the total of option is masked out and summed with zeros before it into slot 0
+	mask := make([]int64, option+1)
+	mask[option] = 1
+
+	total, err := ct.MultByVector(mask)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err = total.SlotSum(option + 1)
+	if err != nil {
+		return nil, err
+	}
+	return total.Marshal()
+}
+
+// weights returns Options + 1 nonzero residues modulo the plaintext modulus of ctx, derived from
+// ballot with HMAC-SHA256 keyed with the secret of poll
+func (poll *Poll) weights(ctx *Context, ballot []byte) []int64 {
+	t := new(big.Int).SetUint64(ctx.BfvParams.T() - 1)
+
+	weights := make([]int64, poll.Options+1)
+	for i := range weights {
+		mac := hmac.New(sha256.New, poll.secret)
+		mac.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+		mac.Write(ballot)
+
+		weight := new(big.Int).SetBytes(mac.Sum(nil))
+		weights[i] = weight.Mod(weight, t).Int64() + 1
+	}
+	return weights
+}
+
+// checkOptions returns an error unless ballots of ctx can hold options options
+func checkOptions(ctx *Context, options int) error {
+	if options < 1 || options > ctx.BfvSlots() {
+		return fmt.Errorf("number of options must be between 1 and %d", ctx.BfvSlots())
+	}
+	return nil
+}
+
+// checkOperation returns the name of the ballot check of poll in transcripts
+func (poll *Poll) checkOperation() string {
+	return fmt.Sprintf("poll %s check", poll.ID)
+}
+
+// totalOperation returns the name of the total of option of poll in transcripts
+func (poll *Poll) totalOperation(option int) string {
+	return fmt.Sprintf("poll %s total %d", poll.ID, option)
+}